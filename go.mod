@@ -1,3 +1,12 @@
 module github.com/talosprotocol/talos-sdk-go
 
 go 1.21
+
+require filippo.io/edwards25519 v1.1.0
+
+require gopkg.in/yaml.v3 v3.0.1
+
+require (
+	golang.org/x/crypto v0.33.0
+	golang.org/x/sys v0.30.0 // indirect
+)
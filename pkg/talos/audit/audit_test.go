@@ -0,0 +1,316 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/wallet"
+)
+
+func reason(err error) string {
+	if te, ok := err.(*errors.TalosError); ok {
+		r, _ := te.Details["reason"].(string)
+		return r
+	}
+	return ""
+}
+
+func newSigner(t *testing.T) *wallet.Wallet {
+	t.Helper()
+	w, err := wallet.FromSeed(bytes.Repeat([]byte{7}, 32), "auditor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+// writeLog creates a log with n tool-call entries and a final checkpoint.
+func writeLog(t *testing.T, n int) (string, *wallet.Wallet) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	w := newSigner(t)
+	l, err := Open(path, WithSigner(w), WithCheckpointEvery(3))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	for i := 0; i < n; i++ {
+		if _, err := l.Append(TypeToolCall, map[string]interface{}{"tool": "echo", "i": i}); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+	if err := l.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	return path, w
+}
+
+func readLines(t *testing.T, path string) []string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.SplitAfter(string(b), "\n")
+}
+
+func TestLogRoundTrip(t *testing.T) {
+	path, w := writeLog(t, 7)
+
+	report, err := VerifyFile(path, WithTrustedSigners(w.DID()), RequireSealed())
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	// 7 entries, checkpoints after 3 and 6, and a final one on Close.
+	if report.Entries != 10 || report.Checkpoints != 3 || report.Unsealed != 0 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+
+	// Reopening continues the chain.
+	l, err := Open(path, WithSigner(w))
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	e, err := l.Append(TypeToolCall, map[string]string{"tool": "later"})
+	if err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	if e.Seq != 10 || e.Prev != report.Head {
+		t.Errorf("entry does not continue the chain: %+v", e)
+	}
+	seq, head := l.Head()
+	l.Close()
+	if _, err := VerifyFile(path, WithExpectedHead(seq, head)); err != nil {
+		t.Errorf("Verify with expected head failed: %v", err)
+	}
+
+	// Entry data is stored canonically.
+	if !strings.Contains(readLines(t, path)[0], `"data":{"i":0,"tool":"echo"}`) {
+		t.Errorf("data not canonical: %s", readLines(t, path)[0])
+	}
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	path, w := writeLog(t, 5)
+	lines := readLines(t, path)
+	other, _ := wallet.Generate("mallory")
+
+	tests := []struct {
+		name   string
+		mutate func([]string) []string
+		opts   []VerifyOption
+		reason string
+	}{
+		{
+			name: "edited data",
+			mutate: func(l []string) []string {
+				l[1] = strings.Replace(l[1], `"echo"`, `"rm"`, 1)
+				return l
+			},
+			reason: ReasonHashMismatch,
+		},
+		{
+			name: "removed entry",
+			mutate: func(l []string) []string {
+				return append(l[:1], l[2:]...)
+			},
+			reason: ReasonSequence,
+		},
+		{
+			name: "torn write",
+			mutate: func(l []string) []string {
+				last := len(l) - 2
+				l[last] = l[last][:10]
+				return l[:last+1]
+			},
+			reason: ReasonTruncated,
+		},
+		{
+			name: "truncated tail",
+			mutate: func(l []string) []string {
+				return l[:5]
+			},
+			opts:   []VerifyOption{RequireSealed()},
+			reason: ReasonUnsealed,
+		},
+		{
+			name:   "untrusted signer",
+			mutate: func(l []string) []string { return l },
+			opts:   []VerifyOption{WithTrustedSigners(other.DID())},
+			reason: ReasonUntrusted,
+		},
+		{
+			name: "unknown field",
+			mutate: func(l []string) []string {
+				l[0] = strings.Replace(l[0], `{`, `{"extra":1,`, 1)
+				return l
+			},
+			reason: ReasonMalformed,
+		},
+		{
+			name:   "rewritten chain",
+			mutate: rewriteChain,
+			reason: ReasonBadSignature,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mutated := tt.mutate(append([]string(nil), lines...))
+			_, err := Verify(strings.NewReader(strings.Join(mutated, "")), tt.opts...)
+			if got := reason(err); got != tt.reason {
+				t.Errorf("expected reason %q, got %q (%v)", tt.reason, got, err)
+			}
+		})
+	}
+
+	// Without the head recorded elsewhere, dropping unsealed entries is only
+	// caught by WithExpectedHead.
+	l, _ := Open(path, WithSigner(w))
+	l.Append(TypeToolCall, map[string]int{"i": 99})
+	seq, head := l.Head()
+	l.f.Close()
+	sealed := strings.Join(lines, "")
+	if _, err := Verify(strings.NewReader(sealed), WithExpectedHead(seq, head)); reason(err) != ReasonTruncated {
+		t.Errorf("expected %s, got %v", ReasonTruncated, err)
+	}
+}
+
+// rewriteChain edits the first entry and recomputes every hash, as an
+// attacker without the signing key could.
+func rewriteChain(lines []string) []string {
+	prev := GenesisHash
+	out := make([]string, 0, len(lines))
+	for i, line := range lines {
+		if line == "" {
+			continue
+		}
+		var e Entry
+		json.Unmarshal([]byte(line), &e)
+		if i == 0 {
+			e.Data = json.RawMessage(`{"i":0,"tool":"rm"}`)
+		}
+		e.Prev = prev
+		e.Hash, _ = e.computeHash()
+		prev = e.Hash
+		b, _ := json.Marshal(e)
+		out = append(out, string(b)+"\n")
+	}
+	return out
+}
+
+func TestLogOptions(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := Open(filepath.Join(dir, "a.jsonl"), WithCheckpointEvery(5)); err == nil {
+		t.Error("expected error for checkpoints without signer")
+	}
+
+	l, err := Open(filepath.Join(dir, "b.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if _, err := l.Checkpoint(); err == nil {
+		t.Error("expected error for checkpoint without signer")
+	}
+	if _, err := l.Append(TypeCheckpoint, nil); err == nil {
+		t.Error("expected error for forged checkpoint type")
+	}
+
+	w := newSigner(t)
+	sig, err := l.Sign(w, []byte("payload"))
+	if err != nil || !wallet.Verify(w.PublicKey(), []byte("payload"), sig) {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	if seq, _ := l.Head(); seq != 0 {
+		t.Errorf("expected signature entry at 0, got %d", seq)
+	}
+}
+
+func TestRepair(t *testing.T) {
+	path, w := writeLog(t, 4)
+	if dropped, err := Repair(path); err != nil || dropped != nil {
+		t.Fatalf("expected intact log to need no repair, got %q (%v)", dropped, err)
+	}
+
+	// A crash mid-write leaves a partial last line.
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	_, _ = f.WriteString(`{"seq":5,"ts":"2024`)
+	f.Close()
+	if _, err := Open(path, WithSigner(w)); reason(err) != ReasonTruncated {
+		t.Fatalf("expected %s, got %v", ReasonTruncated, err)
+	}
+
+	dropped, err := Repair(path)
+	if err != nil || string(dropped) != `{"seq":5,"ts":"2024` {
+		t.Fatalf("unexpected repair result %q (%v)", dropped, err)
+	}
+	l, err := Open(path, WithSigner(w))
+	if err != nil {
+		t.Fatalf("Open after repair failed: %v", err)
+	}
+	if _, err := l.Append(TypeToolCall, map[string]interface{}{"tool": "echo"}); err != nil {
+		t.Fatalf("Append after repair failed: %v", err)
+	}
+	l.Close()
+	if _, err := VerifyFile(path, RequireSealed()); err != nil {
+		t.Errorf("repaired log does not verify: %v", err)
+	}
+
+	// Other damage is left alone.
+	lines := readLines(t, path)
+	lines[1] = strings.Replace(lines[1], `"echo"`, `"rm"`, 1)
+	_ = os.WriteFile(path, []byte(strings.Join(lines, "")+"{"), 0o600)
+	if _, err := Repair(path); reason(err) != ReasonHashMismatch {
+		t.Errorf("expected %s, got %v", ReasonHashMismatch, err)
+	}
+}
+
+func TestOpenRequiresOwnSigner(t *testing.T) {
+	path, _ := writeLog(t, 3)
+	other, _ := wallet.Generate("mallory")
+	if _, err := Open(path, WithSigner(other)); reason(err) != ReasonUntrusted {
+		t.Errorf("expected %s, got %v", ReasonUntrusted, err)
+	}
+}
+
+func TestWriteFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Append(TypeToolCall, map[string]interface{}{"i": 0}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Bytes left behind by a failed write are removed.
+	_, _ = l.f.Write([]byte(`{"seq":1,"partial`))
+	l.rollback(os.ErrClosed)
+	if l.broken != nil {
+		t.Fatalf("rollback failed: %v", l.broken)
+	}
+	if _, err := l.Append(TypeToolCall, map[string]interface{}{"i": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if report, err := VerifyFile(path); err != nil || report.Entries != 2 {
+		t.Fatalf("expected 2 entries, got %+v (%v)", report, err)
+	}
+
+	// A write that cannot be rolled back poisons the log.
+	l.f.Close()
+	if _, err := l.Append(TypeToolCall, nil); err == nil {
+		t.Fatal("expected write error")
+	}
+	if l.broken == nil {
+		t.Fatal("expected the log to be marked broken")
+	}
+	_, err = l.Append(TypeToolCall, nil)
+	if te, ok := err.(*errors.TalosError); !ok || te.Code != errors.CodeIOError {
+		t.Errorf("expected %s, got %v", errors.CodeIOError, err)
+	}
+	l.closed = true
+}
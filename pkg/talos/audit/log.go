@@ -0,0 +1,343 @@
+// Package audit keeps a tamper-evident, append-only local record of SDK
+// operations such as tool calls and signatures.
+//
+// The log is a JSON lines file. Every entry is hashed over its canonical
+// JSON form (canonical.Marshal) and links to the hash of the entry before
+// it. Checkpoint entries additionally carry an Ed25519ctx signature from a
+// wallet over the chain head, so that a rewritten chain is detectable even
+// if every hash was recomputed, provided the verifier knows which key to
+// trust (WithTrustedSigners): anyone can re-sign a rewritten log with a key
+// of their own. Verify checks a log end to end.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/canonical"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/wallet"
+)
+
+// Entry types written by the SDK.
+const (
+	TypeToolCall   = "tool_call"
+	TypeSignature  = "signature"
+	TypeCheckpoint = "checkpoint"
+)
+
+// checkpointContext is the Ed25519ctx context for checkpoint signatures.
+const checkpointContext = "talos-audit-checkpoint-v1"
+
+// GenesisHash is the Prev value of the first entry.
+var GenesisHash = strings.Repeat("0", 2*sha256.Size)
+
+// Entry is one line of the log.
+type Entry struct {
+	Seq       uint64          `json:"seq"`
+	Timestamp string          `json:"ts"` // RFC 3339, UTC
+	Type      string          `json:"type"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	Prev      string          `json:"prev"`
+	Signer    string          `json:"signer,omitempty"` // checkpoints only
+	Hash      string          `json:"hash,omitempty"`
+	Signature string          `json:"sig,omitempty"` // checkpoints only
+}
+
+// computeHash returns the hex SHA-256 of the canonical entry without its
+// hash and signature.
+func (e *Entry) computeHash() (string, error) {
+	unsigned := *e
+	unsigned.Hash, unsigned.Signature = "", ""
+	b, err := canonical.Marshal(&unsigned)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Checkpoint is the payload of a checkpoint entry.
+type Checkpoint struct {
+	// Entries is the number of entries before the checkpoint.
+	Entries uint64 `json:"entries"`
+}
+
+// Log appends entries to a file. It is safe for concurrent use.
+type Log struct {
+	mu       sync.Mutex
+	f        *os.File
+	signer   *wallet.Wallet
+	every    int
+	now      func() time.Time
+	next     uint64
+	head     string
+	unsealed int
+	size     int64 // file size after the last entry
+	closed   bool
+	// broken is set when a failed write could not be rolled back; the
+	// file may then hold an entry the chain state does not reflect.
+	broken error
+}
+
+// Option configures a Log.
+type Option func(*Log)
+
+// WithSigner signs checkpoints with w. Without a signer the log is only
+// hash-chained and Checkpoint fails.
+func WithSigner(w *wallet.Wallet) Option {
+	return func(l *Log) {
+		l.signer = w
+	}
+}
+
+// WithCheckpointEvery writes a signed checkpoint automatically after every n
+// entries. It requires WithSigner.
+func WithCheckpointEvery(n int) Option {
+	return func(l *Log) {
+		l.every = n
+	}
+}
+
+// WithClock overrides the time source.
+func WithClock(now func() time.Time) Option {
+	return func(l *Log) {
+		l.now = now
+	}
+}
+
+// Open opens or creates the log at path. An existing log is verified first
+// and appending continues from its head; a log that fails verification is
+// not written to. With WithSigner, checkpoints must be signed by that
+// wallet. A log whose last line was cut short by a crash fails with reason
+// truncated; see Repair.
+func Open(path string, opts ...Option) (*Log, error) {
+	l := &Log{now: time.Now, head: GenesisHash}
+	for _, opt := range opts {
+		opt(l)
+	}
+	if l.every > 0 && l.signer == nil {
+		return nil, errors.New(errors.CodeInvalidInput, "automatic checkpoints require a signer")
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, errors.New(errors.CodeIOError, "failed to open audit log", errors.WithCause(err))
+	}
+	var vopts []VerifyOption
+	if l.signer != nil {
+		vopts = append(vopts, WithTrustedSigners(l.signer.DID()))
+	}
+	report, err := Verify(bufio.NewReader(f), vopts...)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, errors.New(errors.CodeIOError, "failed to stat audit log", errors.WithCause(err))
+	}
+	l.f = f
+	l.size = info.Size()
+	l.next = report.Entries
+	if report.Entries > 0 {
+		l.head = report.Head
+	}
+	l.unsealed = int(report.Unsealed)
+	return l, nil
+}
+
+// Append records an event of the given type. data is stored in canonical
+// JSON form. If the entry is written but the automatic checkpoint after it
+// fails, the entry is returned together with the error.
+func (l *Log) Append(eventType string, data interface{}) (*Entry, error) {
+	if eventType == "" || eventType == TypeCheckpoint {
+		return nil, errors.New(errors.CodeInvalidInput, fmt.Sprintf("invalid entry type %q", eventType))
+	}
+	raw, err := canonical.Marshal(data)
+	if err != nil {
+		return nil, errors.New(errors.CodeInvalidInput, "failed to canonicalize entry data", errors.WithCause(err))
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	e, err := l.write(&Entry{Type: eventType, Data: raw})
+	if err != nil {
+		return nil, err
+	}
+	l.unsealed++
+	if l.every > 0 && l.unsealed >= l.every {
+		if _, err := l.checkpoint(); err != nil {
+			return e, err
+		}
+	}
+	return e, nil
+}
+
+// Sign signs message with w and records the signature.
+func (l *Log) Sign(w *wallet.Wallet, message []byte) ([]byte, error) {
+	sig := w.Sign(message)
+	digest := sha256.Sum256(message)
+	_, err := l.Append(TypeSignature, map[string]interface{}{
+		"signer":         w.DID(),
+		"message_sha256": hex.EncodeToString(digest[:]),
+		"signature":      base64.RawURLEncoding.EncodeToString(sig),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sig, nil
+}
+
+// Checkpoint writes a signed checkpoint over the current head.
+func (l *Log) Checkpoint() (*Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.checkpoint()
+}
+
+// Head returns the sequence number and hash of the last entry. Storing
+// these outside the log (see WithExpectedHead) lets Verify detect
+// truncation of entries written after the last checkpoint.
+func (l *Log) Head() (uint64, string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.next == 0 {
+		return 0, GenesisHash
+	}
+	return l.next - 1, l.head
+}
+
+// Close writes a final checkpoint if there are unsealed entries and a
+// signer is configured, then closes the file.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return nil
+	}
+	var err error
+	if l.signer != nil && l.unsealed > 0 {
+		_, err = l.checkpoint()
+	}
+	l.closed = true
+	if cerr := l.f.Close(); err == nil && cerr != nil {
+		err = errors.New(errors.CodeIOError, "failed to close audit log", errors.WithCause(cerr))
+	}
+	return err
+}
+
+func (l *Log) checkpoint() (*Entry, error) {
+	if l.signer == nil {
+		return nil, errors.New(errors.CodeInvalidInput, "checkpoints require a signer")
+	}
+	raw, _ := canonical.Marshal(Checkpoint{Entries: l.next})
+	e := &Entry{Type: TypeCheckpoint, Data: raw, Signer: l.signer.DID()}
+	e, err := l.write(e)
+	if err != nil {
+		return nil, err
+	}
+	l.unsealed = 0
+	return e, nil
+}
+
+// write fills in the chain fields, signs checkpoints and appends the entry.
+// The caller holds l.mu.
+func (l *Log) write(e *Entry) (*Entry, error) {
+	if l.closed {
+		return nil, errors.New(errors.CodeInvalidInput, "audit log is closed")
+	}
+	if l.broken != nil {
+		return nil, errors.New(errors.CodeIOError, "audit log is unusable after a failed write", errors.WithCause(l.broken))
+	}
+	e.Seq = l.next
+	e.Timestamp = l.now().UTC().Format(time.RFC3339Nano)
+	e.Prev = l.head
+	hash, err := e.computeHash()
+	if err != nil {
+		return nil, errors.New(errors.CodeInvalidInput, "failed to hash entry", errors.WithCause(err))
+	}
+	e.Hash = hash
+	if e.Type == TypeCheckpoint {
+		sig, err := l.signer.SignWithOptions([]byte(hash), wallet.SignOptions{
+			Variant: wallet.VariantEd25519ctx,
+			Context: checkpointContext,
+		})
+		if err != nil {
+			return nil, err
+		}
+		e.Signature = base64.RawURLEncoding.EncodeToString(sig)
+	}
+
+	line, err := canonical.Marshal(e)
+	if err != nil {
+		return nil, errors.New(errors.CodeInvalidInput, "failed to encode entry", errors.WithCause(err))
+	}
+	line = append(line, '\n')
+	if _, err := l.f.Write(line); err != nil {
+		l.rollback(err)
+		return nil, errors.New(errors.CodeIOError, "failed to write audit entry", errors.WithCause(err))
+	}
+	if err := l.f.Sync(); err != nil {
+		l.rollback(err)
+		return nil, errors.New(errors.CodeIOError, "failed to sync audit log", errors.WithCause(err))
+	}
+	l.size += int64(len(line))
+	l.next++
+	l.head = hash
+	return e, nil
+}
+
+// rollback removes whatever part of a failed write reached the file, so that
+// the next entry does not reuse its sequence number. If that fails too the
+// log is marked broken and refuses further writes. The caller holds l.mu.
+func (l *Log) rollback(cause error) {
+	if err := l.f.Truncate(l.size); err != nil {
+		l.broken = cause
+		return
+	}
+	if err := l.f.Sync(); err != nil {
+		l.broken = cause
+	}
+}
+
+// Repair truncates the log at path after its last complete line if the
+// final line was left incomplete, e.g. by a crash during a write, so that
+// Open accepts the log again. Every complete entry must still verify; other
+// damage is not repaired. It returns the discarded bytes, or nil if the log
+// was intact. The log must not be open while it is repaired.
+func Repair(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.New(errors.CodeIOError, "failed to read audit log", errors.WithCause(err))
+	}
+	keep := bytes.LastIndexByte(data, '\n') + 1
+	if _, err := Verify(bytes.NewReader(data[:keep])); err != nil {
+		return nil, err
+	}
+	if keep == len(data) {
+		return nil, nil
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return nil, errors.New(errors.CodeIOError, "failed to open audit log", errors.WithCause(err))
+	}
+	defer f.Close()
+	if err := f.Truncate(int64(keep)); err != nil {
+		return nil, errors.New(errors.CodeIOError, "failed to truncate audit log", errors.WithCause(err))
+	}
+	if err := f.Sync(); err != nil {
+		return nil, errors.New(errors.CodeIOError, "failed to sync audit log", errors.WithCause(err))
+	}
+	return data[keep:], nil
+}
@@ -0,0 +1,200 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/wallet"
+)
+
+// Reasons reported in Details["reason"] when verification fails.
+const (
+	ReasonMalformed    = "malformed_entry"
+	ReasonSequence     = "sequence_gap"
+	ReasonBrokenChain  = "broken_chain"
+	ReasonHashMismatch = "hash_mismatch"
+	ReasonBadSignature = "bad_signature"
+	ReasonUntrusted    = "untrusted_signer"
+	ReasonTruncated    = "truncated"
+	ReasonUnsealed     = "unsealed_entries"
+)
+
+// Report summarizes a verified log.
+type Report struct {
+	// Entries is the number of entries, including checkpoints.
+	Entries uint64
+	// Checkpoints is the number of valid signed checkpoints.
+	Checkpoints int
+	// Head is the hash of the last entry.
+	Head string
+	// LastCheckpoint is the sequence number of the last checkpoint, or -1.
+	LastCheckpoint int64
+	// Unsealed counts entries after the last checkpoint. They are
+	// hash-chained but can be truncated undetectably unless the head was
+	// recorded elsewhere.
+	Unsealed uint64
+}
+
+// VerifyOption configures Verify.
+type VerifyOption func(*verifier)
+
+type verifier struct {
+	trusted     map[string]bool
+	expectSeq   *uint64
+	expectHash  string
+	requireSeal bool
+}
+
+// WithTrustedSigners requires every checkpoint to be signed by one of dids.
+func WithTrustedSigners(dids ...string) VerifyOption {
+	return func(v *verifier) {
+		if v.trusted == nil {
+			v.trusted = make(map[string]bool)
+		}
+		for _, d := range dids {
+			v.trusted[d] = true
+		}
+	}
+}
+
+// WithExpectedHead requires the log to contain the entry seq with the given
+// hash, as previously returned by Log.Head. Entries after it are allowed.
+func WithExpectedHead(seq uint64, hash string) VerifyOption {
+	return func(v *verifier) {
+		v.expectSeq = &seq
+		v.expectHash = hash
+	}
+}
+
+// RequireSealed fails verification if any entry follows the last
+// checkpoint, i.e. the log was not closed cleanly.
+func RequireSealed() VerifyOption {
+	return func(v *verifier) {
+		v.requireSeal = true
+	}
+}
+
+// VerifyFile verifies the log at path.
+func VerifyFile(path string, opts ...VerifyOption) (*Report, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.New(errors.CodeIOError, "failed to open audit log", errors.WithCause(err))
+	}
+	defer f.Close()
+	return Verify(f, opts...)
+}
+
+// Verify reads a log and checks sequence numbers, hash links, entry hashes
+// and checkpoint signatures. It reports the first problem found with its
+// reason and sequence number in Details.
+func Verify(r io.Reader, opts ...VerifyOption) (*Report, error) {
+	v := &verifier{}
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	report := &Report{Head: GenesisHash, LastCheckpoint: -1}
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) > 0 {
+				return nil, fail(ReasonTruncated, "last entry is incomplete", report.Entries)
+			}
+			break
+		}
+		if err != nil {
+			return nil, errors.New(errors.CodeIOError, "failed to read audit log", errors.WithCause(err))
+		}
+
+		e, err := decodeEntry(line)
+		if err != nil {
+			return nil, fail(ReasonMalformed, "entry is not valid JSON", report.Entries)
+		}
+		if err := v.check(e, report); err != nil {
+			return nil, err
+		}
+		report.Entries++
+		report.Head = e.Hash
+		if e.Type == TypeCheckpoint {
+			report.Checkpoints++
+			report.LastCheckpoint = int64(e.Seq)
+			report.Unsealed = 0
+		} else {
+			report.Unsealed++
+		}
+	}
+
+	if v.expectSeq != nil && *v.expectSeq >= report.Entries {
+		return nil, fail(ReasonTruncated,
+			fmt.Sprintf("log ends before expected head %d", *v.expectSeq), report.Entries)
+	}
+	if v.requireSeal && report.Unsealed > 0 {
+		return nil, fail(ReasonUnsealed,
+			fmt.Sprintf("%d entries after the last checkpoint", report.Unsealed), report.Entries)
+	}
+	return report, nil
+}
+
+func (v *verifier) check(e *Entry, report *Report) error {
+	if e.Seq != report.Entries {
+		return fail(ReasonSequence, fmt.Sprintf("expected sequence %d, got %d", report.Entries, e.Seq), report.Entries)
+	}
+	if e.Prev != report.Head {
+		return fail(ReasonBrokenChain, "entry does not link to the previous entry", e.Seq)
+	}
+	hash, err := e.computeHash()
+	if err != nil || hash != e.Hash {
+		return fail(ReasonHashMismatch, "entry hash does not match its contents", e.Seq)
+	}
+	if v.expectSeq != nil && e.Seq == *v.expectSeq && e.Hash != v.expectHash {
+		return fail(ReasonHashMismatch, "entry does not match the expected head", e.Seq)
+	}
+
+	if e.Type != TypeCheckpoint {
+		if e.Signer != "" || e.Signature != "" {
+			return fail(ReasonMalformed, "only checkpoints carry signatures", e.Seq)
+		}
+		return nil
+	}
+	var cp Checkpoint
+	if err := json.Unmarshal(e.Data, &cp); err != nil || cp.Entries != e.Seq {
+		return fail(ReasonMalformed, "checkpoint does not match its position", e.Seq)
+	}
+	pub, err := wallet.PublicKeyFromDID(e.Signer)
+	if err != nil {
+		return fail(ReasonBadSignature, "checkpoint signer is not a valid DID", e.Seq)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(e.Signature)
+	if err != nil || !wallet.VerifyWithOptions(pub, []byte(e.Hash), sig, wallet.SignOptions{
+		Variant: wallet.VariantEd25519ctx,
+		Context: checkpointContext,
+	}) {
+		return fail(ReasonBadSignature, "checkpoint signature does not verify", e.Seq)
+	}
+	if v.trusted != nil && !v.trusted[e.Signer] {
+		return fail(ReasonUntrusted, "checkpoint signed by an untrusted key", e.Seq)
+	}
+	return nil
+}
+
+func decodeEntry(line []byte) (*Entry, error) {
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.DisallowUnknownFields()
+	var e Entry
+	if err := dec.Decode(&e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func fail(reason, message string, seq uint64) error {
+	return errors.New(errors.CodeCryptoError, message,
+		errors.WithDetails(map[string]interface{}{"reason": reason, "seq": seq}))
+}
@@ -1,6 +1,7 @@
 package canonical_test
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/talosprotocol/talos-sdk-go/pkg/talos/canonical"
@@ -92,3 +93,43 @@ func TestPermutation(t *testing.T) {
 		t.Errorf("Nested permutation failed: %s != %s", string(nb1), string(nb2))
 	}
 }
+
+func TestMarshalJCS(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "rfc8785_numbers",
+			input:    `{"numbers":[333333333.33333329,1E30,4.50,2e-3,0.000000000000000000000000001]}`,
+			expected: `{"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27]}`,
+		},
+		{
+			name:     "rfc8785_strings",
+			input:    `{"string":"\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/<&>"}`,
+			expected: "{\"string\":\"€$\\u000f\\nA'B\\\"\\\\\\\\\\\"/<&>\"}",
+		},
+		{
+			name:     "rfc8785_sorting",
+			input:    `{"\u20ac":"Euro Sign","\r":"Carriage Return","\ufb33":"Hebrew Letter Dalet With Dagesh","1":"One","\ud83d\ude00":"Emoji: Grinning Face","\u0080":"Control","\u00f6":"Latin Small Letter O With Diaeresis"}`,
+			expected: "{\"\\r\":\"Carriage Return\",\"1\":\"One\",\"\u0080\":\"Control\",\"ö\":\"Latin Small Letter O With Diaeresis\",\"€\":\"Euro Sign\",\"😀\":\"Emoji: Grinning Face\",\"\ufb33\":\"Hebrew Letter Dalet With Dagesh\"}",
+		},
+		{
+			name:     "literals",
+			input:    `{"b":[true,false,null,{}],"a":[]}`,
+			expected: `{"a":[],"b":[true,false,null,{}]}`,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := canonical.MarshalJCS(json.RawMessage(tc.input))
+			if err != nil {
+				t.Fatalf("MarshalJCS failed: %v", err)
+			}
+			if string(got) != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,280 @@
+// Package capability mints and verifies capability tokens: signed grants
+// from an issuer DID allowing a subject DID to call specific tools on
+// specific MCP servers until an expiry time, optionally restricted further
+// by constraints on the tool call arguments.
+//
+// A token is signed by the issuer's wallet over its canonical JSON form
+// (canonical.Marshal) with the signature field omitted. A holder may
+// delegate a narrower capability to another DID; the child embeds its parent,
+// and the chain is verified back to a trusted root issuer.
+package capability
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/canonical"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/wallet"
+)
+
+// Version is the capability format version.
+const Version = 1
+
+// Wildcard in Servers or Tools matches any server or tool.
+const Wildcard = "*"
+
+// Reasons reported in Details["reason"].
+const (
+	ReasonMalformed        = "malformed"
+	ReasonBadSignature     = "bad_signature"
+	ReasonUntrustedIssuer  = "untrusted_issuer"
+	ReasonExpired          = "expired"
+	ReasonNotYetValid      = "not_yet_valid"
+	ReasonWrongSubject     = "wrong_subject"
+	ReasonServerNotAllowed = "server_not_allowed"
+	ReasonToolNotAllowed   = "tool_not_allowed"
+	ReasonBrokenChain      = "broken_chain"
+	ReasonEscalation       = "escalation"
+	ReasonChainTooDeep     = "chain_too_deep"
+)
+
+// MaxChainDepth bounds the number of delegations above a capability.
+const MaxChainDepth = 8
+
+// Capability grants Subject the right to call Tools on Servers until
+// ExpiresAt. Times are Unix seconds.
+type Capability struct {
+	Version   int      `json:"v"`
+	Issuer    string   `json:"iss"`
+	Subject   string   `json:"sub"`
+	Servers   []string `json:"servers"`
+	Tools     []string `json:"tools"`
+	IssuedAt  int64    `json:"iat"`
+	ExpiresAt int64    `json:"exp"`
+	Nonce     string   `json:"nonce"`
+	// Constraints restrict the tool call input; all must hold.
+	Constraints []Constraint `json:"constraints,omitempty"`
+	// Parent is the capability this one was delegated from, or nil for a
+	// root capability.
+	Parent    *Capability `json:"parent,omitempty"`
+	Signature string      `json:"sig,omitempty"`
+}
+
+// Mint fills in the issuer, version, nonce and issue time of c (unless
+// already set) and signs it with issuer, producing a root capability.
+func Mint(issuer *wallet.Wallet, c Capability) (*Capability, error) {
+	c.Parent = nil
+	return mint(issuer, c)
+}
+
+// Delegate issues a child of parent to c.Subject, signed by holder, who must
+// be the parent's subject. The child may only attenuate: its servers and
+// tools must be covered by the parent's and it may not outlive it. The
+// parent's constraints keep applying, so c.Constraints can only tighten
+// them.
+func Delegate(holder *wallet.Wallet, parent *Capability, c Capability) (*Capability, error) {
+	if parent == nil {
+		return nil, errors.New(errors.CodeInvalidInput, "parent capability is required")
+	}
+	if holder.DID() != parent.Subject {
+		return nil, invalid(ReasonBrokenChain, "only the parent's subject can delegate it")
+	}
+	if c.IssuedAt == 0 {
+		c.IssuedAt = max(time.Now().Unix(), parent.IssuedAt)
+	}
+	c.Parent = parent
+	if err := attenuates(&c, parent); err != nil {
+		return nil, err
+	}
+	if depth(&c) > MaxChainDepth {
+		return nil, invalid(ReasonChainTooDeep, fmt.Sprintf("delegation chain exceeds %d levels", MaxChainDepth))
+	}
+	return mint(holder, c)
+}
+
+func mint(issuer *wallet.Wallet, c Capability) (*Capability, error) {
+	out := c
+	out.Version = Version
+	out.Issuer = issuer.DID()
+	out.Servers = append([]string(nil), c.Servers...)
+	out.Tools = append([]string(nil), c.Tools...)
+	out.Constraints = append([]Constraint(nil), c.Constraints...)
+	if out.IssuedAt == 0 {
+		out.IssuedAt = time.Now().Unix()
+	}
+	if out.Nonce == "" {
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			return nil, errors.New(errors.CodeCryptoError, "failed to generate nonce", errors.WithCause(err))
+		}
+		out.Nonce = base64.RawURLEncoding.EncodeToString(b)
+	}
+	if err := out.validate(); err != nil {
+		return nil, err
+	}
+
+	msg, err := out.signingBytes()
+	if err != nil {
+		return nil, err
+	}
+	out.Signature = base64.RawURLEncoding.EncodeToString(issuer.Sign(msg))
+	return &out, nil
+}
+
+// Encode returns the token form: base64url of the canonical JSON.
+func (c *Capability) Encode() (string, error) {
+	b, err := canonical.Marshal(c)
+	if err != nil {
+		return "", errors.New(errors.CodeInvalidInput, "failed to encode capability", errors.WithCause(err))
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Parse decodes a token produced by Encode. It does not verify it.
+func Parse(token string) (*Capability, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, invalid(ReasonMalformed, "capability is not valid base64url")
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	var c Capability
+	if err := dec.Decode(&c); err != nil {
+		return nil, invalid(ReasonMalformed, "capability is not valid JSON")
+	}
+	return &c, nil
+}
+
+// Allows reports whether the capability's scope covers the server and
+// tool. It does not check signature or expiry.
+func (c *Capability) Allows(serverID, toolName string) bool {
+	return contains(c.Servers, serverID) && contains(c.Tools, toolName)
+}
+
+// signingBytes returns the canonical JSON of c without its signature.
+func (c *Capability) signingBytes() ([]byte, error) {
+	unsigned := *c
+	unsigned.Signature = ""
+	b, err := canonical.Marshal(&unsigned)
+	if err != nil {
+		return nil, errors.New(errors.CodeInvalidInput, "failed to canonicalize capability", errors.WithCause(err))
+	}
+	return b, nil
+}
+
+func (c *Capability) validate() error {
+	switch {
+	case c.Version != Version:
+		return invalid(ReasonMalformed, fmt.Sprintf("unsupported capability version %d", c.Version))
+	case c.Subject == "":
+		return invalid(ReasonMalformed, "capability has no subject")
+	case len(c.Servers) == 0 || len(c.Tools) == 0:
+		return invalid(ReasonMalformed, "capability must name at least one server and tool")
+	case c.ExpiresAt <= c.IssuedAt:
+		return invalid(ReasonMalformed, "capability must expire after it is issued")
+	case c.Nonce == "":
+		return invalid(ReasonMalformed, "capability has no nonce")
+	}
+	if _, err := wallet.PublicKeyFromDID(c.Subject); err != nil {
+		return invalid(ReasonMalformed, "capability subject is not a valid DID")
+	}
+	for i := range c.Constraints {
+		if err := c.Constraints[i].validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verifySignature checks that c is signed by its issuer.
+func (c *Capability) verifySignature() error {
+	pub, err := wallet.PublicKeyFromDID(c.Issuer)
+	if err != nil {
+		return invalid(ReasonMalformed, "capability issuer is not a valid DID")
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(c.Signature)
+	if err != nil {
+		return invalid(ReasonBadSignature, "capability signature is not valid base64url")
+	}
+	msg, err := c.signingBytes()
+	if err != nil {
+		return err
+	}
+	if !wallet.Verify(pub, msg, sig) {
+		return invalid(ReasonBadSignature, "capability signature does not verify")
+	}
+	return nil
+}
+
+// Root returns the root of the delegation chain.
+func (c *Capability) Root() *Capability {
+	for c.Parent != nil {
+		c = c.Parent
+	}
+	return c
+}
+
+func depth(c *Capability) int {
+	n := 0
+	for ; c.Parent != nil; c = c.Parent {
+		n++
+	}
+	return n
+}
+
+// attenuates checks that child grants no more than parent.
+func attenuates(child, parent *Capability) error {
+	for _, s := range child.Servers {
+		if !covers(parent.Servers, s) {
+			return escalation(fmt.Sprintf("server %q is not granted by the parent", s))
+		}
+	}
+	for _, t := range child.Tools {
+		if !covers(parent.Tools, t) {
+			return escalation(fmt.Sprintf("tool %q is not granted by the parent", t))
+		}
+	}
+	if child.ExpiresAt > parent.ExpiresAt {
+		return escalation("capability outlives its parent")
+	}
+	if child.IssuedAt < parent.IssuedAt {
+		return escalation("capability is issued before its parent")
+	}
+	return nil
+}
+
+// covers reports whether set grants v, where v may itself be a wildcard.
+func covers(set []string, v string) bool {
+	if v == Wildcard {
+		for _, s := range set {
+			if s == Wildcard {
+				return true
+			}
+		}
+		return false
+	}
+	return contains(set, v)
+}
+
+func escalation(message string) error {
+	return invalid(ReasonEscalation, message)
+}
+
+func contains(set []string, v string) bool {
+	for _, s := range set {
+		if s == Wildcard || s == v {
+			return true
+		}
+	}
+	return false
+}
+
+func invalid(reason, message string) error {
+	return errors.New(errors.CodeInvalidCapability, message,
+		errors.WithDetails(map[string]interface{}{"reason": reason}))
+}
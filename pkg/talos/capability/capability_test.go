@@ -0,0 +1,202 @@
+package capability
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/wallet"
+)
+
+func newWallet(t *testing.T, b byte) *wallet.Wallet {
+	t.Helper()
+	w, err := wallet.FromSeed(bytes.Repeat([]byte{b}, 32), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func expectError(t *testing.T, err error, code errors.TalosErrorCode, reason string) {
+	t.Helper()
+	te, ok := err.(*errors.TalosError)
+	if !ok {
+		t.Fatalf("expected *TalosError, got %v", err)
+	}
+	if te.Code != code {
+		t.Errorf("expected code %s, got %s (%v)", code, te.Code, err)
+	}
+	if got, _ := te.Details["reason"].(string); got != reason {
+		t.Errorf("expected reason %q, got %q", reason, got)
+	}
+}
+
+var now = time.Unix(1_700_000_000, 0)
+
+func TestMintAndVerify(t *testing.T) {
+	root, agent := newWallet(t, 1), newWallet(t, 2)
+	c, err := Mint(root, Capability{
+		Subject:   agent.DID(),
+		Servers:   []string{"fs"},
+		Tools:     []string{"read", "list"},
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		t.Fatalf("Mint failed: %v", err)
+	}
+	if c.Issuer != root.DID() || c.Nonce == "" || c.Signature == "" {
+		t.Errorf("Mint did not fill in fields: %+v", c)
+	}
+
+	v := NewVerifier(WithTrustedIssuers(root.DID()), WithClock(func() time.Time { return now }))
+	ok := Request{Caller: agent.DID(), ServerID: "fs", ToolName: "read"}
+	if err := v.Verify(c, ok); err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+
+	token, _ := c.Encode()
+	parsed, err := v.VerifyToken(token, ok)
+	if err != nil {
+		t.Fatalf("VerifyToken failed: %v", err)
+	}
+	if parsed.Nonce != c.Nonce {
+		t.Error("token round trip lost fields")
+	}
+}
+
+func TestVerify_Errors(t *testing.T) {
+	root, agent, other := newWallet(t, 1), newWallet(t, 2), newWallet(t, 3)
+	base := Capability{
+		Subject:   agent.DID(),
+		Servers:   []string{"fs"},
+		Tools:     []string{"read"},
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(time.Hour).Unix(),
+	}
+	good, _ := Mint(root, base)
+	untrusted, _ := Mint(other, base)
+	tampered := *good
+	tampered.Tools = []string{"*"}
+	req := Request{Caller: agent.DID(), ServerID: "fs", ToolName: "read"}
+
+	at := func(t time.Time) *Verifier {
+		return NewVerifier(WithTrustedIssuers(root.DID()), WithClock(func() time.Time { return t }))
+	}
+
+	tests := []struct {
+		name   string
+		v      *Verifier
+		c      *Capability
+		req    Request
+		code   errors.TalosErrorCode
+		reason string
+	}{
+		{"tampered", at(now), &tampered, req, errors.CodeInvalidCapability, ReasonBadSignature},
+		{"untrusted issuer", at(now), untrusted, req, errors.CodeInvalidCapability, ReasonUntrustedIssuer},
+		{"expired", at(now.Add(2 * time.Hour)), good, req, errors.CodeCapabilityExpired, ReasonExpired},
+		{"not yet valid", at(now.Add(-time.Hour)), good, req, errors.CodeCapabilityExpired, ReasonNotYetValid},
+		{"wrong caller", at(now), good, Request{Caller: other.DID(), ServerID: "fs", ToolName: "read"}, errors.CodeDenied, ReasonWrongSubject},
+		{"wrong server", at(now), good, Request{ServerID: "db", ToolName: "read"}, errors.CodeDenied, ReasonServerNotAllowed},
+		{"wrong tool", at(now), good, Request{ServerID: "fs", ToolName: "write"}, errors.CodeDenied, ReasonToolNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectError(t, tt.v.Verify(tt.c, tt.req), tt.code, tt.reason)
+		})
+	}
+
+	// Expiry within the leeway is tolerated.
+	if err := at(now.Add(time.Hour+10*time.Second)).Verify(good, req); err != nil {
+		t.Errorf("expected leeway to apply: %v", err)
+	}
+}
+
+func TestMint_Validation(t *testing.T) {
+	root := newWallet(t, 1)
+	if _, err := Mint(root, Capability{Subject: "did:key:zbad", Servers: []string{"a"}, Tools: []string{"b"}, ExpiresAt: time.Now().Add(time.Hour).Unix()}); err == nil {
+		t.Error("expected error for invalid subject")
+	}
+	if _, err := Mint(root, Capability{Subject: root.DID(), Servers: []string{"a"}, ExpiresAt: time.Now().Add(time.Hour).Unix()}); err == nil {
+		t.Error("expected error for missing tools")
+	}
+	if _, err := Mint(root, Capability{Subject: root.DID(), Servers: []string{"a"}, Tools: []string{"b"}}); err == nil {
+		t.Error("expected error for missing expiry")
+	}
+	if _, err := Parse("!!"); err == nil {
+		t.Error("expected error for malformed token")
+	}
+}
+
+func TestDelegation(t *testing.T) {
+	root, orchestrator, worker, other := newWallet(t, 1), newWallet(t, 2), newWallet(t, 3), newWallet(t, 4)
+	parent, _ := Mint(root, Capability{
+		Subject:   orchestrator.DID(),
+		Servers:   []string{"fs", "db"},
+		Tools:     []string{Wildcard},
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(time.Hour).Unix(),
+	})
+	child, err := Delegate(orchestrator, parent, Capability{
+		Subject:   worker.DID(),
+		Servers:   []string{"fs"},
+		Tools:     []string{"read"},
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(30 * time.Minute).Unix(),
+	})
+	if err != nil {
+		t.Fatalf("Delegate failed: %v", err)
+	}
+	if child.Root() != parent || child.Issuer != orchestrator.DID() {
+		t.Error("child does not chain to parent")
+	}
+
+	v := NewVerifier(WithTrustedIssuers(root.DID()), WithClock(func() time.Time { return now }))
+	if err := v.Verify(child, Request{Caller: worker.DID(), ServerID: "fs", ToolName: "read"}); err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	expectError(t, v.Verify(child, Request{ServerID: "db", ToolName: "read"}), errors.CodeDenied, ReasonServerNotAllowed)
+
+	// The chain survives encoding.
+	token, _ := child.Encode()
+	if _, err := v.VerifyToken(token, Request{Caller: worker.DID(), ServerID: "fs", ToolName: "read"}); err != nil {
+		t.Errorf("VerifyToken failed: %v", err)
+	}
+
+	// Delegate refuses to escalate.
+	escalations := []Capability{
+		{Subject: worker.DID(), Servers: []string{"mail"}, Tools: []string{"read"}, IssuedAt: now.Unix(), ExpiresAt: now.Add(time.Minute).Unix()},
+		{Subject: worker.DID(), Servers: []string{Wildcard}, Tools: []string{"read"}, IssuedAt: now.Unix(), ExpiresAt: now.Add(time.Minute).Unix()},
+		{Subject: worker.DID(), Servers: []string{"fs"}, Tools: []string{"read"}, IssuedAt: now.Unix(), ExpiresAt: now.Add(2 * time.Hour).Unix()},
+	}
+	for _, c := range escalations {
+		_, err := Delegate(orchestrator, parent, c)
+		expectError(t, err, errors.CodeInvalidCapability, ReasonEscalation)
+	}
+	if _, err := Delegate(other, parent, escalations[0]); err == nil {
+		t.Error("expected error when a non-holder delegates")
+	}
+
+	// A hand-built escalating child is rejected by the verifier even though
+	// its signature is valid.
+	forged := Capability{
+		Subject: worker.DID(), Servers: []string{"fs", "mail"}, Tools: []string{"send"},
+		IssuedAt: now.Unix(), ExpiresAt: now.Add(time.Minute).Unix(), Parent: parent,
+	}
+	bad, _ := mint(orchestrator, forged)
+	expectError(t, v.Verify(bad, Request{ServerID: "mail", ToolName: "send"}), errors.CodeInvalidCapability, ReasonEscalation)
+
+	// A child signed by someone other than the parent's subject breaks the chain.
+	forged.Servers = []string{"fs"}
+	hijacked, _ := mint(other, forged)
+	expectError(t, v.Verify(hijacked, Request{ServerID: "fs", ToolName: "send"}), errors.CodeInvalidCapability, ReasonBrokenChain)
+
+	// The child expires before its parent.
+	expectError(t, NewVerifier(WithTrustedIssuers(root.DID()), WithClock(func() time.Time { return now.Add(45 * time.Minute) })).
+		Verify(child, Request{ServerID: "fs", ToolName: "read"}), errors.CodeCapabilityExpired, ReasonExpired)
+
+	// Only the root issuer needs to be trusted.
+	expectError(t, NewVerifier(WithTrustedIssuers(orchestrator.DID()), WithClock(func() time.Time { return now })).
+		Verify(child, Request{ServerID: "fs", ToolName: "read"}), errors.CodeInvalidCapability, ReasonUntrustedIssuer)
+}
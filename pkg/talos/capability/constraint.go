@@ -0,0 +1,302 @@
+package capability
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+)
+
+// Constraint operators.
+const (
+	// OpEq requires the selected value to equal Value.
+	OpEq = "eq"
+	// OpPrefix requires a string starting with Value.
+	OpPrefix = "prefix"
+	// OpPathPrefix requires a slash-separated path that, once cleaned, is
+	// Value or lies below it. Unlike OpPrefix it cannot be escaped with "..".
+	OpPathPrefix = "path_prefix"
+	// OpEnum requires the value to equal one of Values.
+	OpEnum = "enum"
+	// OpRange requires a number within [Min, Max]; either bound may be
+	// omitted.
+	OpRange = "range"
+)
+
+// Reasons reported when a tool call input violates a constraint.
+const (
+	ReasonConstraintViolated = "constraint_violated"
+	ReasonArgumentMissing    = "argument_missing"
+)
+
+// Constraint restricts an argument of the tool call input. Path is a
+// JSON-path selector: "$" followed by ".name", "['name']", "[index]" or
+// "[*]" steps. When a selector matches several values (via "[*]"), every
+// one of them must satisfy the constraint.
+type Constraint struct {
+	Path   string        `json:"path"`
+	Op     string        `json:"op"`
+	Value  interface{}   `json:"value,omitempty"`
+	Values []interface{} `json:"values,omitempty"`
+	Min    *float64      `json:"min,omitempty"`
+	Max    *float64      `json:"max,omitempty"`
+}
+
+// Eq returns a constraint requiring the argument at p to equal v.
+func Eq(p string, v interface{}) Constraint {
+	return Constraint{Path: p, Op: OpEq, Value: v}
+}
+
+// Prefix returns a constraint requiring the string at p to start with s.
+func Prefix(p, s string) Constraint {
+	return Constraint{Path: p, Op: OpPrefix, Value: s}
+}
+
+// PathPrefix returns a constraint requiring the file path at p to be dir or
+// lie below it.
+func PathPrefix(p, dir string) Constraint {
+	return Constraint{Path: p, Op: OpPathPrefix, Value: dir}
+}
+
+// Enum returns a constraint requiring the argument at p to be one of vs.
+func Enum(p string, vs ...interface{}) Constraint {
+	return Constraint{Path: p, Op: OpEnum, Values: vs}
+}
+
+// Range returns a constraint requiring the number at p to lie in
+// [min, max]. Pass nil for an open bound.
+func Range(p string, min, max *float64) Constraint {
+	return Constraint{Path: p, Op: OpRange, Min: min, Max: max}
+}
+
+func (c *Constraint) validate() error {
+	if _, err := parsePath(c.Path); err != nil {
+		return err
+	}
+	switch c.Op {
+	case OpEq:
+		return nil
+	case OpPrefix, OpPathPrefix:
+		if _, ok := c.Value.(string); !ok {
+			return invalid(ReasonMalformed, fmt.Sprintf("%s constraint on %s needs a string value", c.Op, c.Path))
+		}
+	case OpEnum:
+		if len(c.Values) == 0 {
+			return invalid(ReasonMalformed, fmt.Sprintf("enum constraint on %s has no values", c.Path))
+		}
+	case OpRange:
+		if c.Min == nil && c.Max == nil {
+			return invalid(ReasonMalformed, fmt.Sprintf("range constraint on %s has no bounds", c.Path))
+		}
+		if c.Min != nil && c.Max != nil && *c.Min > *c.Max {
+			return invalid(ReasonMalformed, fmt.Sprintf("range constraint on %s is empty", c.Path))
+		}
+	default:
+		return invalid(ReasonMalformed, fmt.Sprintf("unknown constraint operator %q", c.Op))
+	}
+	return nil
+}
+
+// check evaluates the constraint against normalized JSON input.
+func (c *Constraint) check(input interface{}) error {
+	steps, err := parsePath(c.Path)
+	if err != nil {
+		return err
+	}
+	values, ok := selectPath(input, steps)
+	if !ok {
+		return c.deny(ReasonArgumentMissing, fmt.Sprintf("argument %s is missing", c.Path), nil)
+	}
+	for _, v := range values {
+		if msg := c.test(v); msg != "" {
+			return c.deny(ReasonConstraintViolated, fmt.Sprintf("argument %s %s", c.Path, msg), v)
+		}
+	}
+	return nil
+}
+
+// test returns why v violates the constraint, or "".
+func (c *Constraint) test(v interface{}) string {
+	switch c.Op {
+	case OpEq:
+		if !jsonEqual(v, c.Value) {
+			return "does not equal the permitted value"
+		}
+	case OpPrefix:
+		s, ok := v.(string)
+		if !ok || !strings.HasPrefix(s, c.Value.(string)) {
+			return fmt.Sprintf("does not start with %q", c.Value)
+		}
+	case OpPathPrefix:
+		s, ok := v.(string)
+		if !ok || !underDir(s, c.Value.(string)) {
+			return fmt.Sprintf("is not within %q", c.Value)
+		}
+	case OpEnum:
+		for _, allowed := range c.Values {
+			if jsonEqual(v, allowed) {
+				return ""
+			}
+		}
+		return "is not one of the permitted values"
+	case OpRange:
+		n, ok := v.(float64)
+		if !ok {
+			return "is not a number"
+		}
+		if c.Min != nil && n < *c.Min {
+			return fmt.Sprintf("is below %v", *c.Min)
+		}
+		if c.Max != nil && n > *c.Max {
+			return fmt.Sprintf("is above %v", *c.Max)
+		}
+	}
+	return ""
+}
+
+func (c *Constraint) deny(reason, message string, actual interface{}) error {
+	details := map[string]interface{}{"reason": reason, "path": c.Path, "op": c.Op}
+	if actual != nil {
+		details["actual"] = actual
+	}
+	return errors.New(errors.CodeDenied, message, errors.WithDetails(details))
+}
+
+// underDir reports whether p, cleaned, is dir or below it. Relative paths
+// are compared as given, so a relative p never matches an absolute dir.
+func underDir(p, dir string) bool {
+	p, dir = path.Clean(p), path.Clean(dir)
+	if p == dir || dir == "/" && strings.HasPrefix(p, "/") {
+		return true
+	}
+	return strings.HasPrefix(p, dir+"/")
+}
+
+// normalizeInput converts a tool call input to its generic JSON form so
+// that it can be compared with constraint values decoded from JSON.
+func normalizeInput(input interface{}) (interface{}, error) {
+	b, err := json.Marshal(input)
+	if err != nil {
+		return nil, errors.New(errors.CodeInvalidInput, "tool call input is not JSON-encodable", errors.WithCause(err))
+	}
+	var out interface{}
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil, errors.New(errors.CodeInvalidInput, "tool call input is not JSON-encodable", errors.WithCause(err))
+	}
+	return out, nil
+}
+
+func jsonEqual(a, b interface{}) bool {
+	na, err := normalizeInput(a)
+	if err != nil {
+		return false
+	}
+	nb, err := normalizeInput(b)
+	if err != nil {
+		return false
+	}
+	return reflect.DeepEqual(na, nb)
+}
+
+// step is one selector of a JSON path: an object key, an array index, or
+// "[*]" (index -1).
+type step struct {
+	key   string
+	index int
+	isKey bool
+}
+
+func parsePath(p string) ([]step, error) {
+	bad := func() error {
+		return invalid(ReasonMalformed, fmt.Sprintf("invalid constraint path %q", p))
+	}
+	if !strings.HasPrefix(p, "$") {
+		return nil, bad()
+	}
+	var steps []step
+	rest := p[1:]
+	for rest != "" {
+		switch {
+		case rest[0] == '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			name := rest[1 : end+1]
+			if name == "" {
+				return nil, bad()
+			}
+			steps = append(steps, step{key: name, isKey: true})
+			rest = rest[end+1:]
+		case strings.HasPrefix(rest, "['"):
+			end := strings.Index(rest, "']")
+			if end < 0 {
+				return nil, bad()
+			}
+			steps = append(steps, step{key: rest[2:end], isKey: true})
+			rest = rest[end+2:]
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, bad()
+			}
+			sel := rest[1:end]
+			if sel == "*" {
+				steps = append(steps, step{index: -1})
+			} else {
+				i, err := strconv.Atoi(sel)
+				if err != nil || i < 0 {
+					return nil, bad()
+				}
+				steps = append(steps, step{index: i})
+			}
+			rest = rest[end+1:]
+		default:
+			return nil, bad()
+		}
+	}
+	return steps, nil
+}
+
+// selectPath returns the values matched by steps. ok is false if a key or
+// index along the path does not exist.
+func selectPath(v interface{}, steps []step) ([]interface{}, bool) {
+	if len(steps) == 0 {
+		return []interface{}{v}, true
+	}
+	s, rest := steps[0], steps[1:]
+	if s.isKey {
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		child, ok := obj[s.key]
+		if !ok {
+			return nil, false
+		}
+		return selectPath(child, rest)
+	}
+	arr, ok := v.([]interface{})
+	if !ok {
+		return nil, false
+	}
+	if s.index >= 0 {
+		if s.index >= len(arr) {
+			return nil, false
+		}
+		return selectPath(arr[s.index], rest)
+	}
+	var out []interface{}
+	for _, elem := range arr {
+		vs, ok := selectPath(elem, rest)
+		if !ok {
+			return nil, false
+		}
+		out = append(out, vs...)
+	}
+	return out, true
+}
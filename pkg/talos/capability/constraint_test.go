@@ -0,0 +1,139 @@
+package capability
+
+import (
+	"testing"
+	"time"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+)
+
+func float(f float64) *float64 { return &f }
+
+func TestConstraints(t *testing.T) {
+	root, agent := newWallet(t, 1), newWallet(t, 2)
+	c, err := Mint(root, Capability{
+		Subject:   agent.DID(),
+		Servers:   []string{"fs"},
+		Tools:     []string{"read"},
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(time.Hour).Unix(),
+		Constraints: []Constraint{
+			PathPrefix("$.path", "/data/reports"),
+			Enum("$.encoding", "utf-8", "base64"),
+			Range("$.limit", float(1), float(1000)),
+			Eq("$.options['follow_links']", false),
+			Prefix("$.tags[*]", "team-"),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Mint failed: %v", err)
+	}
+	v := NewVerifier(WithTrustedIssuers(root.DID()), WithClock(func() time.Time { return now }))
+
+	valid := func() map[string]interface{} {
+		return map[string]interface{}{
+			"path":     "/data/reports/q3.csv",
+			"encoding": "utf-8",
+			"limit":    100,
+			"options":  map[string]bool{"follow_links": false},
+			"tags":     []string{"team-a", "team-b"},
+		}
+	}
+	call := func(input map[string]interface{}) error {
+		return v.Verify(c, Request{ServerID: "fs", ToolName: "read", Input: input})
+	}
+	if err := call(valid()); err != nil {
+		t.Fatalf("Verify failed for valid input: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		key    string
+		value  interface{}
+		reason string
+		path   string
+	}{
+		{"path outside prefix", "path", "/data/other.csv", ReasonConstraintViolated, "$.path"},
+		{"path traversal", "path", "/data/reports/../../etc/passwd", ReasonConstraintViolated, "$.path"},
+		{"sibling directory", "path", "/data/reports-old/x", ReasonConstraintViolated, "$.path"},
+		{"enum", "encoding", "latin1", ReasonConstraintViolated, "$.encoding"},
+		{"range high", "limit", 5000, ReasonConstraintViolated, "$.limit"},
+		{"range type", "limit", "10", ReasonConstraintViolated, "$.limit"},
+		{"eq", "options", map[string]bool{"follow_links": true}, ReasonConstraintViolated, "$.options['follow_links']"},
+		{"wildcard element", "tags", []string{"team-a", "admin"}, ReasonConstraintViolated, "$.tags[*]"},
+		{"missing nested", "options", map[string]bool{}, ReasonArgumentMissing, "$.options['follow_links']"},
+		{"missing", "path", nil, ReasonArgumentMissing, "$.path"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := valid()
+			if tt.value == nil {
+				delete(input, tt.key)
+			} else {
+				input[tt.key] = tt.value
+			}
+			err := call(input)
+			expectError(t, err, errors.CodeDenied, tt.reason)
+			if te, ok := err.(*errors.TalosError); ok && te.Details["path"] != tt.path {
+				t.Errorf("expected path %s in details, got %v", tt.path, te.Details["path"])
+			}
+		})
+	}
+}
+
+func TestConstraints_Delegation(t *testing.T) {
+	root, orchestrator, worker := newWallet(t, 1), newWallet(t, 2), newWallet(t, 3)
+	parent, _ := Mint(root, Capability{
+		Subject: orchestrator.DID(), Servers: []string{"fs"}, Tools: []string{"read"},
+		IssuedAt: now.Unix(), ExpiresAt: now.Add(time.Hour).Unix(),
+		Constraints: []Constraint{PathPrefix("$.path", "/data")},
+	})
+	// The child tightens the prefix; the parent's constraint still applies.
+	child, err := Delegate(orchestrator, parent, Capability{
+		Subject: worker.DID(), Servers: []string{"fs"}, Tools: []string{"read"},
+		IssuedAt: now.Unix(), ExpiresAt: now.Add(time.Minute).Unix(),
+		Constraints: []Constraint{PathPrefix("$.path", "/data/public")},
+	})
+	if err != nil {
+		t.Fatalf("Delegate failed: %v", err)
+	}
+	// A child that tries to loosen the constraint gains nothing.
+	loose, _ := Delegate(orchestrator, parent, Capability{
+		Subject: worker.DID(), Servers: []string{"fs"}, Tools: []string{"read"},
+		IssuedAt: now.Unix(), ExpiresAt: now.Add(time.Minute).Unix(),
+		Constraints: []Constraint{PathPrefix("$.path", "/")},
+	})
+
+	v := NewVerifier(WithTrustedIssuers(root.DID()), WithClock(func() time.Time { return now }))
+	req := func(p string) Request {
+		return Request{ServerID: "fs", ToolName: "read", Input: map[string]string{"path": p}}
+	}
+	if err := v.Verify(child, req("/data/public/a")); err != nil {
+		t.Errorf("Verify failed: %v", err)
+	}
+	expectError(t, v.Verify(child, req("/data/private/a")), errors.CodeDenied, ReasonConstraintViolated)
+	expectError(t, v.Verify(loose, req("/etc/passwd")), errors.CodeDenied, ReasonConstraintViolated)
+}
+
+func TestConstraint_Validation(t *testing.T) {
+	bad := []Constraint{
+		{Path: "path", Op: OpEq},
+		{Path: "$.a[", Op: OpEq},
+		{Path: "$..a", Op: OpEq},
+		{Path: "$.a", Op: "regex", Value: ".*"},
+		{Path: "$.a", Op: OpPrefix, Value: 3},
+		{Path: "$.a", Op: OpEnum},
+		{Path: "$.a", Op: OpRange},
+		{Path: "$.a", Op: OpRange, Min: float(5), Max: float(1)},
+	}
+	root := newWallet(t, 1)
+	for _, c := range bad {
+		_, err := Mint(root, Capability{
+			Subject: root.DID(), Servers: []string{"s"}, Tools: []string{"t"},
+			ExpiresAt: time.Now().Add(time.Hour).Unix(), Constraints: []Constraint{c},
+		})
+		if err == nil {
+			t.Errorf("expected error for constraint %+v", c)
+		}
+	}
+}
@@ -0,0 +1,385 @@
+package capability
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/canonical"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/wallet"
+)
+
+// Reasons reported for revocation failures.
+const (
+	ReasonRevoked       = "revoked"
+	ReasonStatusUnknown = "revocation_status_unknown"
+	ReasonRollback      = "revocation_rollback"
+)
+
+// DefaultRevocationTTL is how long HTTPRevocations caches a fetched list.
+const DefaultRevocationTTL = 5 * time.Minute
+
+// maxRevocationListBytes bounds fetched revocation lists.
+const maxRevocationListBytes = 4 * 1024 * 1024
+
+// ID returns the capability's identifier: the hex SHA-256 of its canonical,
+// signed JSON form, including any parents.
+func (c *Capability) ID() (string, error) {
+	b, err := canonical.Marshal(c)
+	if err != nil {
+		return "", errors.New(errors.CodeInvalidInput, "failed to canonicalize capability", errors.WithCause(err))
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// RevocationList is a signed set of capability IDs withdrawn by Issuer. An
+// issuer may revoke capabilities it issued and, transitively, everything
+// delegated from them. Times are Unix seconds.
+type RevocationList struct {
+	Issuer     string   `json:"iss"`
+	IssuedAt   int64    `json:"iat"`
+	NextUpdate int64    `json:"next_update"`
+	Revoked    []string `json:"revoked"`
+	Signature  string   `json:"sig,omitempty"`
+}
+
+// SignRevocationList signs a list of revoked capability IDs valid until
+// nextUpdate.
+func SignRevocationList(issuer *wallet.Wallet, revoked []string, nextUpdate time.Time) (*RevocationList, error) {
+	l := &RevocationList{
+		Issuer:     issuer.DID(),
+		IssuedAt:   time.Now().Unix(),
+		NextUpdate: nextUpdate.Unix(),
+		Revoked:    append([]string{}, revoked...),
+	}
+	msg, err := l.signingBytes()
+	if err != nil {
+		return nil, err
+	}
+	l.Signature = base64.RawURLEncoding.EncodeToString(issuer.Sign(msg))
+	return l, nil
+}
+
+// Verify checks the list's signature.
+func (l *RevocationList) Verify() error {
+	pub, err := wallet.PublicKeyFromDID(l.Issuer)
+	if err != nil {
+		return invalid(ReasonMalformed, "revocation list issuer is not a valid DID")
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(l.Signature)
+	if err != nil {
+		return invalid(ReasonBadSignature, "revocation list signature is not valid base64url")
+	}
+	msg, err := l.signingBytes()
+	if err != nil {
+		return err
+	}
+	if !wallet.Verify(pub, msg, sig) {
+		return invalid(ReasonBadSignature, "revocation list signature does not verify")
+	}
+	return nil
+}
+
+func (l *RevocationList) signingBytes() ([]byte, error) {
+	unsigned := *l
+	unsigned.Signature = ""
+	b, err := canonical.Marshal(&unsigned)
+	if err != nil {
+		return nil, errors.New(errors.CodeInvalidInput, "failed to canonicalize revocation list", errors.WithCause(err))
+	}
+	return b, nil
+}
+
+// RevocationChecker reports whether issuer has revoked the capability with
+// the given ID. An error means the status could not be determined.
+type RevocationChecker interface {
+	IsRevoked(ctx context.Context, issuer, id string) (bool, error)
+}
+
+// RevocationPolicy decides what happens when revocation status is unknown.
+type RevocationPolicy int
+
+const (
+	// FailClosed rejects capabilities whose status cannot be determined.
+	FailClosed RevocationPolicy = iota
+	// FailOpen accepts them.
+	FailOpen
+)
+
+// WithRevocationChecker checks every link of a capability chain against rc.
+// A capability is revoked if its issuer, or the issuer of any capability
+// above it, has revoked it or one of its ancestors.
+func WithRevocationChecker(rc RevocationChecker, policy RevocationPolicy) VerifierOption {
+	return func(v *Verifier) {
+		v.revocations = rc
+		v.revocationPolicy = policy
+	}
+}
+
+// checkRevocation asks, for every link, whether that link's issuer or any
+// issuer above it has revoked it.
+func (v *Verifier) checkRevocation(ctx context.Context, c *Capability) error {
+	if v.revocations == nil {
+		return nil
+	}
+	for d, link := 0, c; link != nil; d, link = d+1, link.Parent {
+		id, err := link.ID()
+		if err != nil {
+			return err
+		}
+		for above := link; above != nil; above = above.Parent {
+			revoked, err := v.revocations.IsRevoked(ctx, above.Issuer, id)
+			if err != nil {
+				if v.revocationPolicy == FailOpen {
+					continue
+				}
+				return errors.New(errors.CodeInvalidCapability, "capability revocation status is unknown",
+					errors.WithCause(err),
+					errors.WithDetails(map[string]interface{}{"reason": ReasonStatusUnknown, "depth": d}))
+			}
+			if revoked {
+				return errors.New(errors.CodeInvalidCapability, "capability has been revoked",
+					errors.WithDetails(map[string]interface{}{
+						"reason": ReasonRevoked, "depth": d, "id": id, "revoked_by": above.Issuer,
+					}))
+			}
+		}
+	}
+	return nil
+}
+
+// MemoryRevocations holds verified revocation lists in memory. It is safe
+// for concurrent use.
+type MemoryRevocations struct {
+	mu    sync.RWMutex
+	lists map[string]*revocationSet
+	now   func() time.Time
+}
+
+type revocationSet struct {
+	issuedAt   int64
+	nextUpdate int64
+	ids        map[string]bool
+}
+
+// NewMemoryRevocations returns an empty store.
+func NewMemoryRevocations() *MemoryRevocations {
+	return &MemoryRevocations{lists: make(map[string]*revocationSet), now: time.Now}
+}
+
+// Add verifies l and makes it the current list for its issuer, unless a
+// newer list is already held.
+func (m *MemoryRevocations) Add(l *RevocationList) error {
+	if err := l.Verify(); err != nil {
+		return err
+	}
+	set := &revocationSet{issuedAt: l.IssuedAt, nextUpdate: l.NextUpdate, ids: make(map[string]bool, len(l.Revoked))}
+	for _, id := range l.Revoked {
+		set.ids[id] = true
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if cur := m.lists[l.Issuer]; cur != nil && cur.issuedAt > l.IssuedAt {
+		return nil
+	}
+	m.lists[l.Issuer] = set
+	return nil
+}
+
+// IsRevoked implements RevocationChecker. Issuers without a list have
+// revoked nothing; a list past its NextUpdate time is an error.
+func (m *MemoryRevocations) IsRevoked(_ context.Context, issuer, id string) (bool, error) {
+	m.mu.RLock()
+	set := m.lists[issuer]
+	m.mu.RUnlock()
+	if set == nil {
+		return false, nil
+	}
+	if m.now().Unix() > set.nextUpdate {
+		return false, errors.New(errors.CodeInvalidCapability,
+			fmt.Sprintf("revocation list for %s is stale", issuer))
+	}
+	return set.ids[id], nil
+}
+
+// Doer matches http.Client.Do and mcp.Doer.
+type Doer interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+// HTTPRevocations fetches each issuer's revocation list from
+// BaseURL + "/" + url.PathEscape(issuer) and caches it. A 404 means the
+// issuer has revoked nothing. Once a list has been seen for an issuer, a
+// refetch that returns an older list, or a 404, is rejected as a rollback.
+type HTTPRevocations struct {
+	baseURL string
+	client  Doer
+	ttl     time.Duration
+	now     func() time.Time
+
+	mu      sync.Mutex
+	cache   map[string]*cachedList
+	pending map[string]*fetch
+}
+
+type cachedList struct {
+	ids      map[string]bool
+	issuedAt int64
+	expires  time.Time
+}
+
+type fetch struct {
+	done chan struct{}
+	list *cachedList
+	err  error
+}
+
+// HTTPOption configures HTTPRevocations.
+type HTTPOption func(*HTTPRevocations)
+
+// WithHTTPClient sets the HTTP client.
+func WithHTTPClient(client Doer) HTTPOption {
+	return func(h *HTTPRevocations) {
+		h.client = client
+	}
+}
+
+// WithCacheTTL sets how long a fetched list is used before refetching. A
+// list is never used past its own NextUpdate time.
+func WithCacheTTL(ttl time.Duration) HTTPOption {
+	return func(h *HTTPRevocations) {
+		h.ttl = ttl
+	}
+}
+
+// NewHTTPRevocations creates a checker fetching lists under baseURL.
+func NewHTTPRevocations(baseURL string, opts ...HTTPOption) *HTTPRevocations {
+	h := &HTTPRevocations{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{Timeout: 10 * time.Second},
+		ttl:     DefaultRevocationTTL,
+		now:     time.Now,
+		cache:   make(map[string]*cachedList),
+		pending: make(map[string]*fetch),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// IsRevoked implements RevocationChecker. Concurrent lookups for the same
+// issuer share one fetch.
+func (h *HTTPRevocations) IsRevoked(ctx context.Context, issuer, id string) (bool, error) {
+	list, err := h.list(ctx, issuer)
+	if err != nil {
+		return false, err
+	}
+	return list.ids[id], nil
+}
+
+func (h *HTTPRevocations) list(ctx context.Context, issuer string) (*cachedList, error) {
+	h.mu.Lock()
+	if c := h.cache[issuer]; c != nil && h.now().Before(c.expires) {
+		h.mu.Unlock()
+		return c, nil
+	}
+	f := h.pending[issuer]
+	if f == nil {
+		f = &fetch{done: make(chan struct{})}
+		h.pending[issuer] = f
+		prev := h.cache[issuer]
+		go func() {
+			// Detached from any one caller's context so that a cancelled
+			// caller does not fail the others.
+			f.list, f.err = h.fetch(context.Background(), issuer, prev)
+			h.mu.Lock()
+			delete(h.pending, issuer)
+			if f.err == nil {
+				h.cache[issuer] = f.list
+			}
+			h.mu.Unlock()
+			close(f.done)
+		}()
+	}
+	h.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.list, f.err
+	case <-ctx.Done():
+		return nil, errors.New(errors.CodeTransportTimeout, "revocation lookup cancelled", errors.WithCause(ctx.Err()))
+	}
+}
+
+// fetch retrieves issuer's list. prev is the last list accepted for issuer,
+// possibly expired, or nil.
+func (h *HTTPRevocations) fetch(ctx context.Context, issuer string, prev *cachedList) (*cachedList, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.baseURL+"/"+url.PathEscape(issuer), nil)
+	if err != nil {
+		return nil, errors.New(errors.CodeInvalidInput, "invalid revocation URL", errors.WithCause(err))
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, errors.New(errors.CodeTransportError, "failed to fetch revocation list", errors.WithCause(err))
+	}
+	defer resp.Body.Close()
+
+	now := h.now()
+	if resp.StatusCode == http.StatusNotFound {
+		if prev != nil && prev.issuedAt != 0 {
+			return nil, invalid(ReasonRollback, fmt.Sprintf("revocation list for %s disappeared", issuer))
+		}
+		return &cachedList{expires: now.Add(h.ttl)}, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(errors.CodeTransportError,
+			fmt.Sprintf("revocation list fetch returned status %d", resp.StatusCode))
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxRevocationListBytes))
+	if err != nil {
+		return nil, errors.New(errors.CodeTransportError, "failed to read revocation list", errors.WithCause(err))
+	}
+	var l RevocationList
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&l); err != nil {
+		return nil, invalid(ReasonMalformed, "revocation list is not valid JSON")
+	}
+	if l.Issuer != issuer {
+		return nil, invalid(ReasonBadSignature, "revocation list is for a different issuer")
+	}
+	if err := l.Verify(); err != nil {
+		return nil, err
+	}
+	if now.Unix() > l.NextUpdate {
+		return nil, errors.New(errors.CodeInvalidCapability, fmt.Sprintf("revocation list for %s is stale", issuer))
+	}
+	if prev != nil && l.IssuedAt < prev.issuedAt {
+		return nil, invalid(ReasonRollback,
+			fmt.Sprintf("revocation list for %s is older than the one already seen", issuer))
+	}
+
+	expires := now.Add(h.ttl)
+	if next := time.Unix(l.NextUpdate, 0); next.Before(expires) {
+		expires = next
+	}
+	c := &cachedList{ids: make(map[string]bool, len(l.Revoked)), issuedAt: l.IssuedAt, expires: expires}
+	for _, id := range l.Revoked {
+		c.ids[id] = true
+	}
+	return c, nil
+}
@@ -0,0 +1,193 @@
+package capability
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+)
+
+func TestRevocation(t *testing.T) {
+	root, orchestrator, worker, other := newWallet(t, 1), newWallet(t, 2), newWallet(t, 3), newWallet(t, 4)
+	parent, _ := Mint(root, Capability{
+		Subject: orchestrator.DID(), Servers: []string{"fs"}, Tools: []string{Wildcard},
+		IssuedAt: now.Unix(), ExpiresAt: now.Add(time.Hour).Unix(),
+	})
+	child, _ := Delegate(orchestrator, parent, Capability{
+		Subject: worker.DID(), Servers: []string{"fs"}, Tools: []string{"read"},
+		IssuedAt: now.Unix(), ExpiresAt: now.Add(time.Hour).Unix(),
+	})
+	parentID, _ := parent.ID()
+	childID, _ := child.ID()
+	if parentID == childID || len(parentID) != 64 {
+		t.Fatalf("unexpected IDs %q, %q", parentID, childID)
+	}
+	req := Request{ServerID: "fs", ToolName: "read"}
+
+	verifier := func(rc RevocationChecker, p RevocationPolicy) *Verifier {
+		return NewVerifier(WithTrustedIssuers(root.DID()), WithClock(func() time.Time { return now }),
+			WithRevocationChecker(rc, p))
+	}
+
+	store := NewMemoryRevocations()
+	v := verifier(store, FailClosed)
+	if err := v.Verify(child, req); err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+
+	// Revoking the parent revokes everything delegated from it.
+	l, _ := SignRevocationList(root, []string{parentID}, time.Now().Add(time.Hour))
+	if err := store.Add(l); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	expectError(t, v.Verify(child, req), errors.CodeInvalidCapability, ReasonRevoked)
+
+	// The orchestrator can revoke its own delegation.
+	store = NewMemoryRevocations()
+	l, _ = SignRevocationList(orchestrator, []string{childID}, time.Now().Add(time.Hour))
+	store.Add(l)
+	expectError(t, verifier(store, FailClosed).Verify(child, req), errors.CodeInvalidCapability, ReasonRevoked)
+
+	// An issuer outside the chain cannot revoke it.
+	store = NewMemoryRevocations()
+	l, _ = SignRevocationList(other, []string{parentID, childID}, time.Now().Add(time.Hour))
+	store.Add(l)
+	if err := verifier(store, FailClosed).Verify(child, req); err != nil {
+		t.Errorf("unrelated revocation applied: %v", err)
+	}
+
+	// Tampered lists are refused.
+	l.Revoked = nil
+	if err := store.Add(l); err == nil {
+		t.Error("expected tampered list to be rejected")
+	}
+
+	// A stale list leaves the status unknown; the policy decides.
+	store = NewMemoryRevocations()
+	l, _ = SignRevocationList(root, nil, time.Now().Add(time.Hour))
+	store.Add(l)
+	store.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	expectError(t, verifier(store, FailClosed).Verify(child, req), errors.CodeInvalidCapability, ReasonStatusUnknown)
+	if err := verifier(store, FailOpen).Verify(child, req); err != nil {
+		t.Errorf("fail-open verify failed: %v", err)
+	}
+}
+
+func TestHTTPRevocations(t *testing.T) {
+	root, agent := newWallet(t, 1), newWallet(t, 2)
+	c, _ := Mint(root, Capability{
+		Subject: agent.DID(), Servers: []string{"fs"}, Tools: []string{"read"},
+		IssuedAt: now.Unix(), ExpiresAt: now.Add(time.Hour).Unix(),
+	})
+	id, _ := c.ID()
+	list, _ := SignRevocationList(root, []string{id}, time.Now().Add(time.Hour))
+
+	var fetches int32
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		if r.URL.EscapedPath() != "/revocations/"+url.PathEscape(root.DID()) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		json.NewEncoder(w).Encode(list)
+	}))
+	defer srv.Close()
+
+	h := NewHTTPRevocations(srv.URL+"/revocations/", WithHTTPClient(srv.Client()), WithCacheTTL(time.Minute))
+	ctx := context.Background()
+	revoked, err := h.IsRevoked(ctx, root.DID(), id)
+	if err != nil || !revoked {
+		t.Fatalf("expected revoked, got %v, %v", revoked, err)
+	}
+	if revoked, err := h.IsRevoked(ctx, root.DID(), "other"); err != nil || revoked {
+		t.Errorf("expected not revoked, got %v, %v", revoked, err)
+	}
+	if n := atomic.LoadInt32(&fetches); n != 1 {
+		t.Errorf("expected one cached fetch, got %d", n)
+	}
+
+	// Issuers without a published list have revoked nothing.
+	if revoked, err := h.IsRevoked(ctx, agent.DID(), id); err != nil || revoked {
+		t.Errorf("expected not revoked for unknown issuer, got %v, %v", revoked, err)
+	}
+
+	v := NewVerifier(WithTrustedIssuers(root.DID()), WithClock(func() time.Time { return now }),
+		WithRevocationChecker(h, FailClosed))
+	expectError(t, v.Verify(c, Request{ServerID: "fs", ToolName: "read"}), errors.CodeInvalidCapability, ReasonRevoked)
+
+	// Once the cache expires, a failing server makes the status unknown.
+	h.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	status = http.StatusInternalServerError
+	if _, err := h.IsRevoked(ctx, root.DID(), id); err == nil {
+		t.Error("expected error from failing server")
+	}
+	expectError(t, v.Verify(c, Request{ServerID: "fs", ToolName: "read"}), errors.CodeInvalidCapability, ReasonStatusUnknown)
+
+	// A list signed by someone else is rejected.
+	status = http.StatusOK
+	list, _ = SignRevocationList(agent, nil, time.Now().Add(time.Hour))
+	if _, err := h.IsRevoked(ctx, root.DID(), id); err == nil {
+		t.Error("expected error for list from wrong issuer")
+	}
+}
+
+func TestHTTPRevocationsRollback(t *testing.T) {
+	root := newWallet(t, 1)
+	newer, _ := SignRevocationList(root, []string{"a"}, time.Now().Add(time.Hour))
+	// An older list that is signed and still fresh, but predates the revocation.
+	older := &RevocationList{Issuer: root.DID(), IssuedAt: newer.IssuedAt - 60, NextUpdate: newer.NextUpdate}
+	msg, _ := older.signingBytes()
+	older.Signature = base64.RawURLEncoding.EncodeToString(root.Sign(msg))
+
+	list, status := newer, http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		json.NewEncoder(w).Encode(list)
+	}))
+	defer srv.Close()
+
+	h := NewHTTPRevocations(srv.URL, WithHTTPClient(srv.Client()), WithCacheTTL(time.Minute))
+	ctx := context.Background()
+	if revoked, err := h.IsRevoked(ctx, root.DID(), "a"); err != nil || !revoked {
+		t.Fatalf("expected revoked, got %v, %v", revoked, err)
+	}
+
+	offset := time.Duration(0)
+	h.now = func() time.Time { return time.Now().Add(offset) }
+	for _, tt := range []struct {
+		name   string
+		list   *RevocationList
+		status int
+	}{
+		{"older list", older, http.StatusOK},
+		{"missing list", nil, http.StatusNotFound},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			offset += 2 * time.Minute
+			list, status = tt.list, tt.status
+			_, err := h.IsRevoked(ctx, root.DID(), "a")
+			expectError(t, err, errors.CodeInvalidCapability, ReasonRollback)
+		})
+	}
+
+	// The same or a newer list is still accepted.
+	list, status = newer, http.StatusOK
+	if revoked, err := h.IsRevoked(ctx, root.DID(), "a"); err != nil || !revoked {
+		t.Errorf("expected revoked, got %v, %v", revoked, err)
+	}
+}
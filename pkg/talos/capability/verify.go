@@ -0,0 +1,194 @@
+package capability
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+)
+
+// DefaultLeeway is the clock skew tolerated on issue and expiry times.
+const DefaultLeeway = 30 * time.Second
+
+// Request is the action a capability is checked against.
+type Request struct {
+	// Caller is the DID presenting the capability. If set it must equal the
+	// capability subject.
+	Caller   string
+	ServerID string
+	ToolName string
+	// Input is the tool call input, checked against argument constraints.
+	Input interface{}
+}
+
+// Verifier checks capabilities against a set of trusted issuers.
+type Verifier struct {
+	trusted map[string]bool
+	leeway  time.Duration
+	now     func() time.Time
+
+	revocations      RevocationChecker
+	revocationPolicy RevocationPolicy
+}
+
+// VerifierOption configures a Verifier.
+type VerifierOption func(*Verifier)
+
+// WithTrustedIssuers adds DIDs whose capabilities are accepted.
+func WithTrustedIssuers(dids ...string) VerifierOption {
+	return func(v *Verifier) {
+		for _, d := range dids {
+			v.trusted[d] = true
+		}
+	}
+}
+
+// WithLeeway sets the tolerated clock skew.
+func WithLeeway(d time.Duration) VerifierOption {
+	return func(v *Verifier) {
+		v.leeway = d
+	}
+}
+
+// WithClock overrides the time source.
+func WithClock(now func() time.Time) VerifierOption {
+	return func(v *Verifier) {
+		v.now = now
+	}
+}
+
+// NewVerifier creates a verifier. At least one trusted issuer must be
+// configured for any capability to verify.
+func NewVerifier(opts ...VerifierOption) *Verifier {
+	v := &Verifier{trusted: make(map[string]bool), leeway: DefaultLeeway, now: time.Now}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+// Verify checks that c and every capability it was delegated from are well
+// formed and correctly signed, that the chain only attenuates and ends at a
+// trusted root issuer, that every link is currently valid and that c is
+// scoped to req, including the argument constraints of every link. Errors
+// carry a precise code:
+//
+//   - CodeInvalidCapability: malformed, badly signed, untrusted, or a
+//     delegation that escalates
+//   - CodeCapabilityExpired: expired or not yet valid
+//   - CodeDenied: valid, but not for this caller, server, tool or input
+//
+// Details["reason"] refines the code; Details["depth"] gives the failing
+// link's distance from c for chain errors. Revoked capabilities are rejected
+// with CodeInvalidCapability when a RevocationChecker is configured.
+func (v *Verifier) Verify(c *Capability, req Request) error {
+	return v.VerifyContext(context.Background(), c, req)
+}
+
+// VerifyContext is Verify with a context for revocation lookups.
+func (v *Verifier) VerifyContext(ctx context.Context, c *Capability, req Request) error {
+	if depth(c) > MaxChainDepth {
+		return invalid(ReasonChainTooDeep, fmt.Sprintf("delegation chain exceeds %d levels", MaxChainDepth))
+	}
+	for d, link := 0, c; link != nil; d, link = d+1, link.Parent {
+		if err := v.checkLink(link); err != nil {
+			return withDepth(err, d)
+		}
+		if link.Parent == nil {
+			break
+		}
+		if link.Issuer != link.Parent.Subject {
+			return withDepth(invalid(ReasonBrokenChain, "capability is not issued by its parent's subject"), d)
+		}
+		if err := attenuates(link, link.Parent); err != nil {
+			return withDepth(err, d)
+		}
+	}
+	if !v.trusted[c.Root().Issuer] {
+		return invalid(ReasonUntrustedIssuer, "capability root issuer is not trusted")
+	}
+	if err := v.checkRevocation(ctx, c); err != nil {
+		return err
+	}
+	return checkScope(c, req)
+}
+
+func (v *Verifier) checkLink(c *Capability) error {
+	if err := c.validate(); err != nil {
+		return err
+	}
+	if err := c.verifySignature(); err != nil {
+		return err
+	}
+	return v.checkTime(c)
+}
+
+// VerifyToken parses and verifies an encoded capability.
+func (v *Verifier) VerifyToken(token string, req Request) (*Capability, error) {
+	c, err := Parse(token)
+	if err != nil {
+		return nil, err
+	}
+	if err := v.Verify(c, req); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (v *Verifier) checkTime(c *Capability) error {
+	now := v.now().Unix()
+	leeway := int64(v.leeway / time.Second)
+	if now > c.ExpiresAt+leeway {
+		return errors.New(errors.CodeCapabilityExpired, "capability has expired",
+			errors.WithDetails(map[string]interface{}{"reason": ReasonExpired, "expires_at": c.ExpiresAt}))
+	}
+	if now+leeway < c.IssuedAt {
+		return errors.New(errors.CodeCapabilityExpired, "capability is not yet valid",
+			errors.WithDetails(map[string]interface{}{"reason": ReasonNotYetValid, "issued_at": c.IssuedAt}))
+	}
+	return nil
+}
+
+func checkScope(c *Capability, req Request) error {
+	if req.Caller != "" && req.Caller != c.Subject {
+		return denied(ReasonWrongSubject, "capability was issued to a different subject",
+			map[string]interface{}{"subject": c.Subject, "caller": req.Caller})
+	}
+	if !contains(c.Servers, req.ServerID) {
+		return denied(ReasonServerNotAllowed, fmt.Sprintf("capability does not cover server %q", req.ServerID),
+			map[string]interface{}{"server_id": req.ServerID})
+	}
+	if !contains(c.Tools, req.ToolName) {
+		return denied(ReasonToolNotAllowed, fmt.Sprintf("capability does not cover tool %q", req.ToolName),
+			map[string]interface{}{"tool_name": req.ToolName})
+	}
+
+	var input interface{}
+	for link := c; link != nil; link = link.Parent {
+		if len(link.Constraints) > 0 && input == nil {
+			var err error
+			if input, err = normalizeInput(req.Input); err != nil {
+				return err
+			}
+		}
+		for i := range link.Constraints {
+			if err := link.Constraints[i].check(input); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func denied(reason, message string, details map[string]interface{}) error {
+	details["reason"] = reason
+	return errors.New(errors.CodeDenied, message, errors.WithDetails(details))
+}
+
+func withDepth(err error, depth int) error {
+	if te, ok := err.(*errors.TalosError); ok && te.Details != nil {
+		te.Details["depth"] = depth
+	}
+	return err
+}
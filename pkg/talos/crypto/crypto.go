@@ -1,10 +1,16 @@
 package crypto
 
 import (
+	stdcrypto "crypto"
+	"crypto/ecdh"
 	"crypto/ed25519"
 	"crypto/rand"
 	"crypto/sha256"
+	"crypto/sha512"
 	"fmt"
+	"io"
+
+	"filippo.io/edwards25519"
 )
 
 // Sign signs the message with the private key using Ed25519.
@@ -17,11 +23,86 @@ func Verify(publicKey ed25519.PublicKey, message, signature []byte) bool {
 	return ed25519.Verify(publicKey, message, signature)
 }
 
+// MaxContextSize is the longest context string Ed25519ctx/ph accept.
+const MaxContextSize = 255
+
+// SignCtx signs the message using Ed25519ctx (RFC 8032) with a non-empty
+// domain-separation context.
+func SignCtx(privateKey ed25519.PrivateKey, message []byte, context string) ([]byte, error) {
+	if context == "" {
+		return nil, fmt.Errorf("Ed25519ctx requires a non-empty context")
+	}
+	return privateKey.Sign(nil, message, &ed25519.Options{Context: context})
+}
+
+// VerifyCtx verifies an Ed25519ctx signature.
+func VerifyCtx(publicKey ed25519.PublicKey, message, signature []byte, context string) bool {
+	if context == "" || len(publicKey) != ed25519.PublicKeySize {
+		return false
+	}
+	return ed25519.VerifyWithOptions(publicKey, message, signature, &ed25519.Options{Context: context}) == nil
+}
+
+// PrehashSHA512 streams r through SHA-512, the pre-hash used by Ed25519ph.
+func PrehashSHA512(r io.Reader) ([]byte, error) {
+	h := sha512.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// SignPH signs the message read from r using Ed25519ph (RFC 8032). The
+// message is hashed as it is read, so it never has to fit in memory. The
+// context may be empty.
+func SignPH(privateKey ed25519.PrivateKey, r io.Reader, context string) ([]byte, error) {
+	digest, err := PrehashSHA512(r)
+	if err != nil {
+		return nil, err
+	}
+	return SignPrehashed(privateKey, digest, context)
+}
+
+// SignPrehashed signs a SHA-512 digest using Ed25519ph.
+func SignPrehashed(privateKey ed25519.PrivateKey, digest []byte, context string) ([]byte, error) {
+	if len(digest) != sha512.Size {
+		return nil, fmt.Errorf("invalid digest length: got %d, want %d", len(digest), sha512.Size)
+	}
+	return privateKey.Sign(nil, digest, &ed25519.Options{Hash: stdcrypto.SHA512, Context: context})
+}
+
+// VerifyPH verifies an Ed25519ph signature over the message read from r.
+func VerifyPH(publicKey ed25519.PublicKey, r io.Reader, signature []byte, context string) (bool, error) {
+	digest, err := PrehashSHA512(r)
+	if err != nil {
+		return false, err
+	}
+	return VerifyPrehashed(publicKey, digest, signature, context), nil
+}
+
+// VerifyPrehashed verifies an Ed25519ph signature over a SHA-512 digest.
+func VerifyPrehashed(publicKey ed25519.PublicKey, digest, signature []byte, context string) bool {
+	if len(publicKey) != ed25519.PublicKeySize || len(digest) != sha512.Size {
+		return false
+	}
+	opts := &ed25519.Options{Hash: stdcrypto.SHA512, Context: context}
+	return ed25519.VerifyWithOptions(publicKey, digest, signature, opts) == nil
+}
+
 // GenerateKey generates a new Ed25519 key pair.
 func GenerateKey() (ed25519.PublicKey, ed25519.PrivateKey, error) {
 	return ed25519.GenerateKey(rand.Reader)
 }
 
+// GenerateKeyFrom generates a key pair from the entropy source r. A nil r
+// means crypto/rand.
+func GenerateKeyFrom(r io.Reader) (ed25519.PublicKey, ed25519.PrivateKey, error) {
+	if r == nil {
+		r = rand.Reader
+	}
+	return ed25519.GenerateKey(r)
+}
+
 // FromSeed generates a key pair from a 32-byte seed.
 func FromSeed(seed []byte) (ed25519.PublicKey, ed25519.PrivateKey, error) {
 	if len(seed) != ed25519.SeedSize {
@@ -36,3 +117,27 @@ func SHA256(data []byte) []byte {
 	hash := sha256.Sum256(data)
 	return hash[:]
 }
+
+// X25519PublicKey converts an Ed25519 public key to its X25519 (Montgomery)
+// form, as used to encrypt to a did:key identity.
+func X25519PublicKey(publicKey ed25519.PublicKey) (*ecdh.PublicKey, error) {
+	if len(publicKey) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key length: got %d, want %d", len(publicKey), ed25519.PublicKeySize)
+	}
+	p, err := new(edwards25519.Point).SetBytes(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Ed25519 public key: %w", err)
+	}
+	return ecdh.X25519().NewPublicKey(p.BytesMontgomery())
+}
+
+// X25519PrivateKey converts an Ed25519 private key to the X25519 private key
+// matching X25519PublicKey of its public half (RFC 8032 clamped scalar).
+func X25519PrivateKey(privateKey ed25519.PrivateKey) (*ecdh.PrivateKey, error) {
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid private key length: got %d, want %d", len(privateKey), ed25519.PrivateKeySize)
+	}
+	h := sha512.Sum512(privateKey.Seed())
+	// X25519 clamps the scalar itself, so the raw hash prefix is enough.
+	return ecdh.X25519().NewPrivateKey(h[:32])
+}
@@ -1,9 +1,14 @@
 package crypto
 
 import (
+	"bytes"
 	"crypto/ed25519"
+	"crypto/sha512"
 	"encoding/hex"
+	"strings"
 	"testing"
+
+	"filippo.io/edwards25519"
 )
 
 func TestGenerateKey(t *testing.T) {
@@ -85,3 +90,206 @@ func TestSHA256(t *testing.T) {
 		t.Errorf("expected hash %s, got %x", expected, hash)
 	}
 }
+
+func TestX25519Conversion(t *testing.T) {
+	pub, priv, _ := GenerateKey()
+
+	xPub, err := X25519PublicKey(pub)
+	if err != nil {
+		t.Fatalf("X25519PublicKey failed: %v", err)
+	}
+	xPriv, err := X25519PrivateKey(priv)
+	if err != nil {
+		t.Fatalf("X25519PrivateKey failed: %v", err)
+	}
+	if !xPriv.PublicKey().Equal(xPub) {
+		t.Error("converted private key does not match converted public key")
+	}
+
+	if _, err := X25519PublicKey(make([]byte, 31)); err == nil {
+		t.Error("expected error for short key")
+	}
+}
+
+func batchEntries(n int) []BatchEntry {
+	entries := make([]BatchEntry, n)
+	for i := range entries {
+		pub, priv, _ := GenerateKey()
+		msg := []byte{byte(i), byte(i >> 8), 'm'}
+		entries[i] = BatchEntry{PublicKey: pub, Message: msg, Signature: Sign(priv, msg)}
+	}
+	return entries
+}
+
+func TestVerifyBatch(t *testing.T) {
+	entries := batchEntries(150)
+	if invalid := VerifyBatch(entries); invalid != nil {
+		t.Fatalf("valid batch reported invalid indices %v", invalid)
+	}
+	if VerifyBatch(nil) != nil {
+		t.Error("empty batch should be valid")
+	}
+
+	// Corrupt entries in different chunks in different ways.
+	entries[3].Message = []byte("tampered")
+	entries[70].Signature = append([]byte(nil), entries[70].Signature...)
+	entries[70].Signature[63] |= 0xf0 // non-canonical s
+	entries[149].PublicKey = entries[0].PublicKey
+	entries[100].Signature = entries[100].Signature[:10]
+
+	got := VerifyBatch(entries)
+	want := []int{3, 70, 100, 149}
+	if len(got) != len(want) {
+		t.Fatalf("expected invalid %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected invalid %v, got %v", want, got)
+		}
+	}
+}
+
+func TestVerifyBatch_Cofactored(t *testing.T) {
+	// A key with a small-order component: A' = aB + T for T = (0, -1) of
+	// order 2. With k = H(R, A', M) odd, s = r + ka satisfies the cofactored
+	// equation but not the cofactorless one Verify uses.
+	torsion, err := new(edwards25519.Point).SetBytes(mustHex(t, "ecffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff7f"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	seed := bytes.Repeat([]byte{9}, 64)
+	a, _ := edwards25519.NewScalar().SetUniformBytes(seed)
+	r, _ := edwards25519.NewScalar().SetUniformBytes(append(seed[1:], 1))
+	pub := new(edwards25519.Point).Add(new(edwards25519.Point).ScalarBaseMult(a), torsion).Bytes()
+	R := new(edwards25519.Point).ScalarBaseMult(r).Bytes()
+
+	for i := 0; ; i++ {
+		msg := []byte{byte(i)}
+		h := sha512.New()
+		h.Write(R)
+		h.Write(pub)
+		h.Write(msg)
+		k, _ := edwards25519.NewScalar().SetUniformBytes(h.Sum(nil))
+		if k.Bytes()[0]&1 == 0 {
+			continue
+		}
+		s := edwards25519.NewScalar().MultiplyAdd(k, a, r)
+		sig := append(append([]byte(nil), R...), s.Bytes()...)
+		if Verify(pub, msg, sig) {
+			t.Fatal("Verify accepted a torsion-tweaked signature")
+		}
+		if invalid := VerifyBatch([]BatchEntry{{PublicKey: pub, Message: msg, Signature: sig}}); invalid != nil {
+			t.Fatalf("expected the cofactored batch check to accept, got %v", invalid)
+		}
+		// The verdict must not depend on the rest of the chunk.
+		junk := BatchEntry{PublicKey: pub, Message: []byte("junk"), Signature: sig}
+		if invalid := VerifyBatch([]BatchEntry{{PublicKey: pub, Message: msg, Signature: sig}, junk}); len(invalid) != 1 || invalid[0] != 1 {
+			t.Fatalf("expected only the junk entry rejected, got %v", invalid)
+		}
+		return
+	}
+}
+
+func BenchmarkVerifySequential(b *testing.B) {
+	entries := batchEntries(batchChunkSize)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, e := range entries {
+			if !Verify(e.PublicKey, e.Message, e.Signature) {
+				b.Fatal("verify failed")
+			}
+		}
+	}
+}
+
+func BenchmarkVerifyBatch(b *testing.B) {
+	entries := batchEntries(batchChunkSize)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if VerifyBatch(entries) != nil {
+			b.Fatal("batch verify failed")
+		}
+	}
+}
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("bad hex: %v", err)
+	}
+	return b
+}
+
+func TestEd25519ctx_RFC8032(t *testing.T) {
+	// RFC 8032, Section 7.2 ("foo").
+	_, priv, _ := FromSeed(mustHex(t, "0305334e381af78f141cb666f6199f57bc3495335a256a95bd2a55bf546663f6"))
+	pub := priv.Public().(ed25519.PublicKey)
+	if hex.EncodeToString(pub) != "dfc9425e4f968f7f0c29f0259cf5f9aed6851c2bb4ad8bfb860cfee0ab248292" {
+		t.Fatalf("public key mismatch: %x", pub)
+	}
+	msg := mustHex(t, "f726936d19c800494e3fdaff20b276a8")
+	want := "55a4cc2f70a54e04288c5f4cd1e45a7bb520b36292911876cada7323198dd87a8b36950b95130022907a7fb7c4e9b2d5f6cca685a587b4b21f4b888e4e7edb0d"
+
+	sig, err := SignCtx(priv, msg, "foo")
+	if err != nil {
+		t.Fatalf("SignCtx failed: %v", err)
+	}
+	if hex.EncodeToString(sig) != want {
+		t.Errorf("signature mismatch: %x", sig)
+	}
+	if !VerifyCtx(pub, msg, sig, "foo") {
+		t.Error("VerifyCtx failed")
+	}
+	if VerifyCtx(pub, msg, sig, "bar") || Verify(pub, msg, sig) {
+		t.Error("signature verified under a different domain")
+	}
+	if _, err := SignCtx(priv, msg, ""); err == nil {
+		t.Error("expected error for empty context")
+	}
+}
+
+func TestEd25519ph_RFC8032(t *testing.T) {
+	// RFC 8032, Section 7.3 ("abc").
+	_, priv, _ := FromSeed(mustHex(t, "833fe62409237b9d62ec77587520911e9a759cec1d19755b7da901b96dca3d42"))
+	pub := priv.Public().(ed25519.PublicKey)
+	if hex.EncodeToString(pub) != "ec172b93ad5e563bf4932c70e1245034c35467ef2efd4d64ebf819683467e2bf" {
+		t.Fatalf("public key mismatch: %x", pub)
+	}
+	want := "98a70222f0b8121aa9d30f813d683f809e462b469c7ff87639499bb94e6dae4131f85042463c2a355a2003d062adf5aaa10b8c61e636062aaad11c2a26083406"
+
+	sig, err := SignPH(priv, strings.NewReader("abc"), "")
+	if err != nil {
+		t.Fatalf("SignPH failed: %v", err)
+	}
+	if hex.EncodeToString(sig) != want {
+		t.Errorf("signature mismatch: %x", sig)
+	}
+	ok, err := VerifyPH(pub, strings.NewReader("abc"), sig, "")
+	if err != nil || !ok {
+		t.Errorf("VerifyPH failed: %v", err)
+	}
+	if ok, _ := VerifyPH(pub, strings.NewReader("abd"), sig, ""); ok {
+		t.Error("VerifyPH accepted wrong message")
+	}
+	if Verify(pub, []byte("abc"), sig) {
+		t.Error("pre-hash signature verified as pure Ed25519")
+	}
+}
+
+func TestEd25519ph_Streaming(t *testing.T) {
+	pub, priv, _ := GenerateKey()
+	large := bytes.Repeat([]byte("artifact"), 1<<17) // 1 MiB
+
+	sig, err := SignPH(priv, bytes.NewReader(large), "talos-artifact")
+	if err != nil {
+		t.Fatalf("SignPH failed: %v", err)
+	}
+	digest, _ := PrehashSHA512(bytes.NewReader(large))
+	if !VerifyPrehashed(pub, digest, sig, "talos-artifact") {
+		t.Error("VerifyPrehashed failed")
+	}
+	if VerifyPrehashed(pub, digest, sig, "") {
+		t.Error("context not bound into signature")
+	}
+}
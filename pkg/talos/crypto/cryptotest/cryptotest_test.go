@@ -0,0 +1,27 @@
+package cryptotest
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestReaderIsDeterministic(t *testing.T) {
+	a := make([]byte, 100)
+	b := make([]byte, 100)
+	NewReader([]byte("seed")).Read(a)
+
+	// Reading in odd-sized chunks yields the same stream.
+	r := NewReader([]byte("seed"))
+	r.Read(b[:7])
+	r.Read(b[7:40])
+	r.Read(b[40:])
+	if !bytes.Equal(a, b) {
+		t.Error("stream depends on read sizes")
+	}
+
+	c := make([]byte, 100)
+	NewReader([]byte("other")).Read(c)
+	if bytes.Equal(a, c) {
+		t.Error("different seeds produced the same stream")
+	}
+}
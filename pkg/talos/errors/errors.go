@@ -11,10 +11,12 @@ const (
 	// Authorization Errors
 	CodeDenied            TalosErrorCode = "TALOS_DENIED"
 	CodeInvalidCapability TalosErrorCode = "TALOS_INVALID_CAPABILITY"
+	CodeCapabilityExpired TalosErrorCode = "TALOS_CAPABILITY_EXPIRED"
 
 	// Protocol Errors
 	CodeProtocolMismatch TalosErrorCode = "TALOS_PROTOCOL_MISMATCH"
 	CodeFrameInvalid     TalosErrorCode = "TALOS_FRAME_INVALID"
+	CodeReplayDetected   TalosErrorCode = "TALOS_REPLAY_DETECTED"
 
 	// Crypto Errors
 	CodeCryptoError  TalosErrorCode = "TALOS_CRYPTO_ERROR"
@@ -23,6 +25,13 @@ const (
 	// Transport Errors
 	CodeTransportTimeout TalosErrorCode = "TALOS_TRANSPORT_TIMEOUT"
 	CodeTransportError   TalosErrorCode = "TALOS_TRANSPORT_ERROR"
+
+	// Session Errors
+	CodeSessionNotFound TalosErrorCode = "TALOS_SESSION_NOT_FOUND"
+	CodeSessionConflict TalosErrorCode = "TALOS_SESSION_CONFLICT"
+
+	// Storage Errors
+	CodeIOError TalosErrorCode = "TALOS_IO_ERROR"
 )
 
 // TalosError is the canonical error type for Talos SDK.
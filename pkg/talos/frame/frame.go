@@ -0,0 +1,361 @@
+package frame
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/wallet"
+)
+
+// Version is the wire format version produced by this SDK.
+const Version uint8 = 1
+
+// Size limits enforced by the encoder and decoder.
+const (
+	MaxSenderSize     = 256
+	MaxHeaderSize     = 4 * 1024
+	MaxCiphertextSize = 1024 * 1024
+	SignatureSize     = 64
+
+	// fixedSize covers magic, version, type, sequence and the four length
+	// prefixes.
+	fixedSize = 2 + 1 + 1 + 8 + 2 + 2 + 4 + 2
+
+	// MaxFrameSize is the largest valid binary frame.
+	MaxFrameSize = fixedSize + MaxSenderSize + MaxHeaderSize + MaxCiphertextSize + SignatureSize
+	// MaxJSONFrameSize bounds JSON input before it is parsed.
+	MaxJSONFrameSize = 2 * MaxFrameSize
+)
+
+var magic = [2]byte{'T', 'L'}
+
+// Type identifies the purpose of a frame.
+type Type uint8
+
+const (
+	TypeHandshake Type = 1
+	TypeData      Type = 2
+	TypeAck       Type = 3
+	TypeClose     Type = 4
+)
+
+var typeNames = map[Type]string{
+	TypeHandshake: "handshake",
+	TypeData:      "data",
+	TypeAck:       "ack",
+	TypeClose:     "close",
+}
+
+func (t Type) String() string {
+	if name, ok := typeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("type(%d)", uint8(t))
+}
+
+// Reasons reported in TalosError.Details["reason"] for invalid frames.
+const (
+	ReasonTruncated          = "truncated"
+	ReasonTrailingBytes      = "trailing_bytes"
+	ReasonBadMagic           = "bad_magic"
+	ReasonUnknownType        = "unknown_type"
+	ReasonSenderTooLarge     = "sender_too_large"
+	ReasonInvalidSender      = "invalid_sender"
+	ReasonHeaderTooLarge     = "header_too_large"
+	ReasonCiphertextTooLarge = "ciphertext_too_large"
+	ReasonBadSignatureSize   = "bad_signature_size"
+	ReasonFrameTooLarge      = "frame_too_large"
+	ReasonMalformedJSON      = "malformed_json"
+	ReasonBadEncoding        = "bad_encoding"
+)
+
+// Frame is a single Talos protocol message on the wire.
+//
+// Binary layout (big endian):
+//
+//	magic "TL" | version u8 | type u8 | sequence u64 |
+//	sender_len u16 | header_len u16 | ciphertext_len u32 | signature_len u16 |
+//	sender | header | ciphertext | signature
+//
+// The signature covers every preceding byte of the signed encoding (that is,
+// with signature_len set to 64).
+type Frame struct {
+	Version    uint8
+	Type       Type
+	Sender     string // sender DID
+	Sequence   uint64
+	Header     []byte
+	Ciphertext []byte
+	Signature  []byte
+}
+
+// Validate checks the frame against the format rules and size limits.
+func (f *Frame) Validate() error {
+	if f.Version != Version {
+		return mismatch(f.Version)
+	}
+	if _, ok := typeNames[f.Type]; !ok {
+		return invalid(ReasonUnknownType, fmt.Sprintf("unknown frame type %d", uint8(f.Type)))
+	}
+	if len(f.Sender) > MaxSenderSize {
+		return invalid(ReasonSenderTooLarge, fmt.Sprintf("sender is %d bytes, max %d", len(f.Sender), MaxSenderSize))
+	}
+	if !strings.HasPrefix(f.Sender, "did:") || !utf8.ValidString(f.Sender) {
+		return invalid(ReasonInvalidSender, "sender must be a DID")
+	}
+	if len(f.Header) > MaxHeaderSize {
+		return invalid(ReasonHeaderTooLarge, fmt.Sprintf("header is %d bytes, max %d", len(f.Header), MaxHeaderSize))
+	}
+	if len(f.Ciphertext) > MaxCiphertextSize {
+		return invalid(ReasonCiphertextTooLarge, fmt.Sprintf("ciphertext is %d bytes, max %d", len(f.Ciphertext), MaxCiphertextSize))
+	}
+	if len(f.Signature) != 0 && len(f.Signature) != SignatureSize {
+		return invalid(ReasonBadSignatureSize, fmt.Sprintf("signature is %d bytes, want %d", len(f.Signature), SignatureSize))
+	}
+	return nil
+}
+
+// SigningBytes returns the bytes covered by the frame signature.
+func (f *Frame) SigningBytes() ([]byte, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	return f.appendUnsigned(nil, SignatureSize), nil
+}
+
+// Sign sets the frame signature using w.
+func (f *Frame) Sign(w *wallet.Wallet) error {
+	f.Signature = nil
+	msg, err := f.SigningBytes()
+	if err != nil {
+		return err
+	}
+	f.Signature = w.Sign(msg)
+	return nil
+}
+
+// Verify reports whether the frame carries a valid signature by publicKey.
+func (f *Frame) Verify(publicKey []byte) bool {
+	if len(f.Signature) != SignatureSize {
+		return false
+	}
+	msg, err := f.SigningBytes()
+	if err != nil {
+		return false
+	}
+	return wallet.Verify(publicKey, msg, f.Signature)
+}
+
+// MarshalBinary encodes the frame in the binary wire format.
+func (f *Frame) MarshalBinary() ([]byte, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	out := f.appendUnsigned(make([]byte, 0, f.size()), len(f.Signature))
+	return append(out, f.Signature...), nil
+}
+
+func (f *Frame) size() int {
+	return fixedSize + len(f.Sender) + len(f.Header) + len(f.Ciphertext) + len(f.Signature)
+}
+
+func (f *Frame) appendUnsigned(b []byte, sigLen int) []byte {
+	b = append(b, magic[:]...)
+	b = append(b, f.Version, byte(f.Type))
+	b = binary.BigEndian.AppendUint64(b, f.Sequence)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(f.Sender)))
+	b = binary.BigEndian.AppendUint16(b, uint16(len(f.Header)))
+	b = binary.BigEndian.AppendUint32(b, uint32(len(f.Ciphertext)))
+	b = binary.BigEndian.AppendUint16(b, uint16(sigLen))
+	b = append(b, f.Sender...)
+	b = append(b, f.Header...)
+	return append(b, f.Ciphertext...)
+}
+
+// UnmarshalBinary decodes a binary frame. Every malformed input yields a
+// TalosError with CodeFrameInvalid (or CodeProtocolMismatch for an unknown
+// version) and the reason in Details["reason"].
+func (f *Frame) UnmarshalBinary(data []byte) error {
+	if len(data) > MaxFrameSize {
+		return invalid(ReasonFrameTooLarge, fmt.Sprintf("frame is %d bytes, max %d", len(data), MaxFrameSize))
+	}
+	if len(data) < 3 {
+		return invalid(ReasonTruncated, "frame shorter than preamble")
+	}
+	if data[0] != magic[0] || data[1] != magic[1] {
+		return invalid(ReasonBadMagic, "frame does not start with TL magic")
+	}
+	// Check the version before the rest so that future formats report a
+	// protocol mismatch instead of a parse failure.
+	if data[2] != Version {
+		return mismatch(data[2])
+	}
+	if len(data) < fixedSize {
+		return invalid(ReasonTruncated, fmt.Sprintf("frame header needs %d bytes, got %d", fixedSize, len(data)))
+	}
+
+	var out Frame
+	out.Version = data[2]
+	out.Type = Type(data[3])
+	out.Sequence = binary.BigEndian.Uint64(data[4:12])
+	senderLen := int(binary.BigEndian.Uint16(data[12:14]))
+	headerLen := int(binary.BigEndian.Uint16(data[14:16]))
+	ctLen := int(binary.BigEndian.Uint32(data[16:20]))
+	sigLen := int(binary.BigEndian.Uint16(data[20:22]))
+
+	// Reject oversize lengths before slicing so the reason is precise.
+	switch {
+	case senderLen > MaxSenderSize:
+		return invalid(ReasonSenderTooLarge, fmt.Sprintf("sender is %d bytes, max %d", senderLen, MaxSenderSize))
+	case headerLen > MaxHeaderSize:
+		return invalid(ReasonHeaderTooLarge, fmt.Sprintf("header is %d bytes, max %d", headerLen, MaxHeaderSize))
+	case ctLen > MaxCiphertextSize:
+		return invalid(ReasonCiphertextTooLarge, fmt.Sprintf("ciphertext is %d bytes, max %d", ctLen, MaxCiphertextSize))
+	case sigLen != 0 && sigLen != SignatureSize:
+		return invalid(ReasonBadSignatureSize, fmt.Sprintf("signature is %d bytes, want %d", sigLen, SignatureSize))
+	}
+
+	want := fixedSize + senderLen + headerLen + ctLen + sigLen
+	if len(data) < want {
+		return invalid(ReasonTruncated, fmt.Sprintf("frame needs %d bytes, got %d", want, len(data)))
+	}
+	if len(data) > want {
+		return invalid(ReasonTrailingBytes, fmt.Sprintf("%d unexpected bytes after frame", len(data)-want))
+	}
+
+	rest := data[fixedSize:]
+	out.Sender, rest = string(rest[:senderLen]), rest[senderLen:]
+	out.Header, rest = clone(rest[:headerLen]), rest[headerLen:]
+	out.Ciphertext, rest = clone(rest[:ctLen]), rest[ctLen:]
+	out.Signature = clone(rest[:sigLen])
+
+	if err := out.Validate(); err != nil {
+		return err
+	}
+	*f = out
+	return nil
+}
+
+// Decode parses a binary frame.
+func Decode(data []byte) (*Frame, error) {
+	var f Frame
+	if err := f.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+type jsonFrame struct {
+	Version    *uint8  `json:"version"`
+	Type       *string `json:"type"`
+	Sender     *string `json:"sender"`
+	Sequence   *uint64 `json:"sequence"`
+	Header     string  `json:"header,omitempty"`
+	Ciphertext string  `json:"ciphertext,omitempty"`
+	Signature  string  `json:"signature,omitempty"`
+}
+
+// MarshalJSON encodes the frame as JSON with base64url (unpadded) byte fields.
+func (f *Frame) MarshalJSON() ([]byte, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	typ := f.Type.String()
+	return json.Marshal(jsonFrame{
+		Version:    &f.Version,
+		Type:       &typ,
+		Sender:     &f.Sender,
+		Sequence:   &f.Sequence,
+		Header:     base64.RawURLEncoding.EncodeToString(f.Header),
+		Ciphertext: base64.RawURLEncoding.EncodeToString(f.Ciphertext),
+		Signature:  base64.RawURLEncoding.EncodeToString(f.Signature),
+	})
+}
+
+// UnmarshalJSON decodes a JSON frame, rejecting unknown fields, missing
+// required fields and invalid base64.
+func (f *Frame) UnmarshalJSON(data []byte) error {
+	if len(data) > MaxJSONFrameSize {
+		return invalid(ReasonFrameTooLarge, fmt.Sprintf("frame is %d bytes, max %d", len(data), MaxJSONFrameSize))
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var j jsonFrame
+	if err := dec.Decode(&j); err != nil {
+		return invalid(ReasonMalformedJSON, err.Error())
+	}
+	if dec.More() {
+		return invalid(ReasonTrailingBytes, "unexpected data after frame object")
+	}
+	if j.Version == nil || j.Type == nil || j.Sender == nil || j.Sequence == nil {
+		return invalid(ReasonMalformedJSON, "version, type, sender and sequence are required")
+	}
+	if *j.Version != Version {
+		return mismatch(*j.Version)
+	}
+
+	out := Frame{Version: *j.Version, Sender: *j.Sender, Sequence: *j.Sequence}
+	for t, name := range typeNames {
+		if name == *j.Type {
+			out.Type = t
+		}
+	}
+	if out.Type == 0 {
+		return invalid(ReasonUnknownType, fmt.Sprintf("unknown frame type %q", *j.Type))
+	}
+
+	var err error
+	if out.Header, err = decodeField("header", j.Header); err != nil {
+		return err
+	}
+	if out.Ciphertext, err = decodeField("ciphertext", j.Ciphertext); err != nil {
+		return err
+	}
+	if out.Signature, err = decodeField("signature", j.Signature); err != nil {
+		return err
+	}
+
+	if err := out.Validate(); err != nil {
+		return err
+	}
+	*f = out
+	return nil
+}
+
+func decodeField(name, s string) ([]byte, error) {
+	if s == "" {
+		return nil, nil
+	}
+	b, err := base64.RawURLEncoding.Strict().DecodeString(s)
+	if err != nil {
+		return nil, invalid(ReasonBadEncoding, fmt.Sprintf("%s is not unpadded base64url", name))
+	}
+	return b, nil
+}
+
+func clone(b []byte) []byte {
+	if len(b) == 0 {
+		return nil
+	}
+	return append([]byte(nil), b...)
+}
+
+func invalid(reason, message string) error {
+	return errors.New(errors.CodeFrameInvalid, message,
+		errors.WithDetails(map[string]interface{}{"reason": reason}))
+}
+
+func mismatch(got uint8) error {
+	return errors.New(errors.CodeProtocolMismatch,
+		fmt.Sprintf("unsupported frame version %d", got),
+		errors.WithDetails(map[string]interface{}{
+			"version":           got,
+			"supported_version": Version,
+		}))
+}
@@ -0,0 +1,202 @@
+package frame
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/wallet"
+)
+
+func sampleFrame(t testing.TB) (*Frame, *wallet.Wallet) {
+	w, err := wallet.FromSeed(make([]byte, 32), "sender")
+	if err != nil {
+		t.Fatalf("FromSeed failed: %v", err)
+	}
+	f := &Frame{
+		Version:    Version,
+		Type:       TypeData,
+		Sender:     w.DID(),
+		Sequence:   42,
+		Header:     []byte(`{"dh":"abc"}`),
+		Ciphertext: []byte("opaque ciphertext"),
+	}
+	if err := f.Sign(w); err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	return f, w
+}
+
+func expectReason(t *testing.T, err error, code errors.TalosErrorCode, reason string) {
+	t.Helper()
+	te, ok := err.(*errors.TalosError)
+	if !ok {
+		t.Fatalf("expected *TalosError, got %T (%v)", err, err)
+	}
+	if te.Code != code {
+		t.Errorf("expected code %s, got %s (%v)", code, te.Code, te)
+	}
+	if reason != "" && te.Details["reason"] != reason {
+		t.Errorf("expected reason %s, got %v (%v)", reason, te.Details["reason"], te)
+	}
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	f, w := sampleFrame(t)
+	b, err := f.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary failed: %v", err)
+	}
+	got, err := Decode(b)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, f) {
+		t.Errorf("round trip mismatch:\n got %+v\nwant %+v", got, f)
+	}
+	if !got.Verify(w.PublicKey()) {
+		t.Error("signature did not verify after round trip")
+	}
+
+	got.Sequence++
+	if got.Verify(w.PublicKey()) {
+		t.Error("signature verified after tampering with sequence")
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	f, _ := sampleFrame(t)
+	b, err := json.Marshal(f)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var got Frame
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !reflect.DeepEqual(&got, f) {
+		t.Errorf("round trip mismatch:\n got %+v\nwant %+v", &got, f)
+	}
+}
+
+func TestDecodeBinary_Invalid(t *testing.T) {
+	f, _ := sampleFrame(t)
+	valid, _ := f.MarshalBinary()
+
+	mutate := func(fn func(b []byte) []byte) []byte {
+		return fn(append([]byte(nil), valid...))
+	}
+
+	tests := []struct {
+		name   string
+		input  []byte
+		code   errors.TalosErrorCode
+		reason string
+	}{
+		{"empty", nil, errors.CodeFrameInvalid, ReasonTruncated},
+		{"bad magic", mutate(func(b []byte) []byte { b[0] = 'X'; return b }), errors.CodeFrameInvalid, ReasonBadMagic},
+		{"future version", mutate(func(b []byte) []byte { b[2] = 2; return b }), errors.CodeProtocolMismatch, ""},
+		{"short header", valid[:10], errors.CodeFrameInvalid, ReasonTruncated},
+		{"truncated body", valid[:len(valid)-1], errors.CodeFrameInvalid, ReasonTruncated},
+		{"trailing bytes", append(append([]byte(nil), valid...), 0), errors.CodeFrameInvalid, ReasonTrailingBytes},
+		{"unknown type", mutate(func(b []byte) []byte { b[3] = 99; return b }), errors.CodeFrameInvalid, ReasonUnknownType},
+		{"sender too large", mutate(func(b []byte) []byte { binary.BigEndian.PutUint16(b[12:], MaxSenderSize+1); return b }), errors.CodeFrameInvalid, ReasonSenderTooLarge},
+		{"header too large", mutate(func(b []byte) []byte { binary.BigEndian.PutUint16(b[14:], MaxHeaderSize+1); return b }), errors.CodeFrameInvalid, ReasonHeaderTooLarge},
+		{"ciphertext too large", mutate(func(b []byte) []byte { binary.BigEndian.PutUint32(b[16:], MaxCiphertextSize+1); return b }), errors.CodeFrameInvalid, ReasonCiphertextTooLarge},
+		{"bad signature size", mutate(func(b []byte) []byte { binary.BigEndian.PutUint16(b[20:], 10); return b }), errors.CodeFrameInvalid, ReasonBadSignatureSize},
+		{"invalid sender", mutate(func(b []byte) []byte { b[fixedSize] = 'x'; return b }), errors.CodeFrameInvalid, ReasonInvalidSender},
+		{"too large", make([]byte, MaxFrameSize+1), errors.CodeFrameInvalid, ReasonFrameTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode(tt.input)
+			expectReason(t, err, tt.code, tt.reason)
+		})
+	}
+}
+
+func TestDecodeJSON_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		code   errors.TalosErrorCode
+		reason string
+	}{
+		{"not json", `{`, errors.CodeFrameInvalid, ReasonMalformedJSON},
+		{"unknown field", `{"version":1,"type":"data","sender":"did:key:z","sequence":1,"extra":true}`, errors.CodeFrameInvalid, ReasonMalformedJSON},
+		{"missing sequence", `{"version":1,"type":"data","sender":"did:key:z"}`, errors.CodeFrameInvalid, ReasonMalformedJSON},
+		{"future version", `{"version":7,"type":"data","sender":"did:key:z","sequence":1}`, errors.CodeProtocolMismatch, ""},
+		{"unknown type", `{"version":1,"type":"gossip","sender":"did:key:z","sequence":1}`, errors.CodeFrameInvalid, ReasonUnknownType},
+		{"bad base64", `{"version":1,"type":"data","sender":"did:key:z","sequence":1,"ciphertext":"a+b="}`, errors.CodeFrameInvalid, ReasonBadEncoding},
+		{"invalid sender", `{"version":1,"type":"data","sender":"alice","sequence":1}`, errors.CodeFrameInvalid, ReasonInvalidSender},
+		{"trailing", `{"version":1,"type":"data","sender":"did:key:z","sequence":1} {}`, errors.CodeFrameInvalid, ReasonTrailingBytes},
+		{"bad signature size", `{"version":1,"type":"data","sender":"did:key:z","sequence":1,"signature":"AAAA"}`, errors.CodeFrameInvalid, ReasonBadSignatureSize},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var f Frame
+			expectReason(t, f.UnmarshalJSON([]byte(tt.input)), tt.code, tt.reason)
+		})
+	}
+}
+
+func TestMarshal_EnforcesLimits(t *testing.T) {
+	f, _ := sampleFrame(t)
+	f.Ciphertext = make([]byte, MaxCiphertextSize+1)
+	_, err := f.MarshalBinary()
+	expectReason(t, err, errors.CodeFrameInvalid, ReasonCiphertextTooLarge)
+}
+
+func FuzzDecode(f *testing.F) {
+	valid, _ := sampleFrame(f)
+	b, _ := valid.MarshalBinary()
+	f.Add(b)
+	f.Add([]byte("TL\x01"))
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fr, err := Decode(data)
+		if err != nil {
+			if _, ok := err.(*errors.TalosError); !ok {
+				t.Fatalf("non-Talos error %T: %v", err, err)
+			}
+			return
+		}
+		// Any accepted frame has exactly one encoding.
+		out, err := fr.MarshalBinary()
+		if err != nil {
+			t.Fatalf("re-encode failed: %v", err)
+		}
+		if !bytes.Equal(out, data) {
+			t.Fatalf("non-canonical frame accepted")
+		}
+	})
+}
+
+func FuzzDecodeJSON(f *testing.F) {
+	valid, _ := sampleFrame(f)
+	b, _ := json.Marshal(valid)
+	f.Add(b)
+	f.Add([]byte(`{"version":1}`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var fr Frame
+		if err := fr.UnmarshalJSON(data); err != nil {
+			if _, ok := err.(*errors.TalosError); !ok {
+				t.Fatalf("non-Talos error %T: %v", err, err)
+			}
+			return
+		}
+		out, err := json.Marshal(&fr)
+		if err != nil {
+			t.Fatalf("re-encode failed: %v", err)
+		}
+		var again Frame
+		if err := json.Unmarshal(out, &again); err != nil || !reflect.DeepEqual(&again, &fr) {
+			t.Fatalf("JSON round trip mismatch: %v", err)
+		}
+	})
+}
@@ -0,0 +1,273 @@
+package frost
+
+import (
+	"crypto/rand"
+	"fmt"
+
+	"filippo.io/edwards25519"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+)
+
+// DKGRound1Package is broadcast to every participant in round 1.
+type DKGRound1Package struct {
+	Identifier Identifier `json:"identifier"`
+	// Commitment holds a_k * G for each polynomial coefficient a_k.
+	Commitment [][]byte `json:"commitment"`
+	// ProofR and ProofZ prove knowledge of the constant coefficient.
+	ProofR []byte `json:"proof_r"`
+	ProofZ []byte `json:"proof_z"`
+}
+
+// DKGRound2Package carries one secret share and must be sent to its
+// recipient over a confidential, authenticated channel.
+type DKGRound2Package struct {
+	From  Identifier `json:"from"`
+	To    Identifier `json:"to"`
+	Share []byte     `json:"share"`
+}
+
+// DKGParticipant runs one participant's side of the Pedersen DKG with
+// proofs of knowledge (FROST paper, Figure 1).
+type DKGParticipant struct {
+	id         Identifier
+	minSigners int
+	maxSigners int
+	coeffs     []*edwards25519.Scalar
+	round1     map[Identifier]*DKGRound1Package
+	finalized  bool
+}
+
+// NewDKGParticipant starts key generation for participant id in a
+// minSigners-of-maxSigners group.
+func NewDKGParticipant(id Identifier, minSigners, maxSigners int) (*DKGParticipant, error) {
+	if err := validateParams(minSigners, maxSigners); err != nil {
+		return nil, err
+	}
+	if id == 0 || int(id) > maxSigners {
+		return nil, invalidInput(fmt.Sprintf("identifier must be in 1..%d", maxSigners), nil)
+	}
+	return &DKGParticipant{id: id, minSigners: minSigners, maxSigners: maxSigners}, nil
+}
+
+// Round1 samples the secret polynomial and returns the package to broadcast.
+func (p *DKGParticipant) Round1() (*DKGRound1Package, error) {
+	if p.finalized {
+		return nil, invalidInput("DKG has already been finalized", nil)
+	}
+	p.coeffs = make([]*edwards25519.Scalar, p.minSigners)
+	for i := range p.coeffs {
+		s, err := randomScalar()
+		if err != nil {
+			return nil, err
+		}
+		p.coeffs[i] = s
+	}
+
+	pkg := &DKGRound1Package{Identifier: p.id}
+	for _, a := range p.coeffs {
+		pkg.Commitment = append(pkg.Commitment, new(edwards25519.Point).ScalarBaseMult(a).Bytes())
+	}
+
+	k, err := randomScalar()
+	if err != nil {
+		return nil, err
+	}
+	R := new(edwards25519.Point).ScalarBaseMult(k).Bytes()
+	c := dkgChallenge(p.id, pkg.Commitment[0], R)
+	pkg.ProofR = R
+	pkg.ProofZ = edwards25519.NewScalar().MultiplyAdd(p.coeffs[0], c, k).Bytes()
+	return pkg, nil
+}
+
+// Round2 verifies every other participant's round 1 package and returns the
+// secret share to send to each of them.
+func (p *DKGParticipant) Round2(received []*DKGRound1Package) (map[Identifier]*DKGRound2Package, error) {
+	if p.finalized {
+		return nil, invalidInput("DKG has already been finalized", nil)
+	}
+	if p.coeffs == nil {
+		return nil, invalidInput("Round1 has not been run", nil)
+	}
+	p.round1 = make(map[Identifier]*DKGRound1Package)
+	var culprits []Identifier
+	for _, pkg := range received {
+		if pkg.Identifier == p.id {
+			continue
+		}
+		if pkg.Identifier == 0 || int(pkg.Identifier) > p.maxSigners || p.round1[pkg.Identifier] != nil {
+			return nil, invalidInput(fmt.Sprintf("unexpected or duplicate participant %d", pkg.Identifier), nil)
+		}
+		if err := verifyRound1(pkg, p.minSigners); err != nil {
+			culprits = append(culprits, pkg.Identifier)
+			continue
+		}
+		p.round1[pkg.Identifier] = pkg
+	}
+	if len(culprits) > 0 {
+		return nil, misbehavior("invalid proof of knowledge in DKG round 1", culprits)
+	}
+	if len(p.round1) != p.maxSigners-1 {
+		return nil, invalidInput(fmt.Sprintf("expected %d round 1 packages, got %d", p.maxSigners-1, len(p.round1)), nil)
+	}
+
+	out := make(map[Identifier]*DKGRound2Package, len(p.round1))
+	for id := range p.round1 {
+		out[id] = &DKGRound2Package{From: p.id, To: id, Share: p.evaluate(id).Bytes()}
+	}
+	return out, nil
+}
+
+// Finalize checks the shares received in round 2 against the round 1
+// commitments and derives this participant's key share and the group's
+// public key package. It succeeds at most once.
+func (p *DKGParticipant) Finalize(received []*DKGRound2Package) (*KeyShare, *PublicKeyPackage, error) {
+	if p.finalized {
+		return nil, nil, invalidInput("DKG has already been finalized", nil)
+	}
+	if p.round1 == nil {
+		return nil, nil, invalidInput("Round2 has not been run", nil)
+	}
+	secret := p.evaluate(p.id)
+	seen := make(map[Identifier]bool)
+	var culprits []Identifier
+	for _, pkg := range received {
+		from := p.round1[pkg.From]
+		if pkg.To != p.id || from == nil || seen[pkg.From] {
+			return nil, nil, invalidInput(fmt.Sprintf("unexpected round 2 package from %d", pkg.From), nil)
+		}
+		seen[pkg.From] = true
+		share, err := decodeScalar(pkg.Share)
+		if err != nil {
+			culprits = append(culprits, pkg.From)
+			continue
+		}
+		expected, err := evaluateCommitment(from.Commitment, p.id)
+		if err != nil || new(edwards25519.Point).ScalarBaseMult(share).Equal(expected) != 1 {
+			culprits = append(culprits, pkg.From)
+			continue
+		}
+		secret.Add(secret, share)
+	}
+	if len(culprits) > 0 {
+		return nil, nil, misbehavior("invalid secret share in DKG round 2", culprits)
+	}
+	if len(seen) != len(p.round1) {
+		return nil, nil, invalidInput(fmt.Sprintf("expected %d round 2 packages, got %d", len(p.round1), len(seen)), nil)
+	}
+
+	// Include our own commitment and compute the public outputs.
+	all := make([][][]byte, 0, p.maxSigners)
+	own := make([][]byte, len(p.coeffs))
+	for i, a := range p.coeffs {
+		own[i] = new(edwards25519.Point).ScalarBaseMult(a).Bytes()
+	}
+	all = append(all, own)
+	for _, pkg := range p.round1 {
+		all = append(all, pkg.Commitment)
+	}
+
+	group := edwards25519.NewIdentityPoint()
+	for _, c := range all {
+		c0, _ := decodePoint(c[0])
+		group.Add(group, c0)
+	}
+	pub := &PublicKeyPackage{
+		MinSigners:      p.minSigners,
+		GroupPublicKey:  group.Bytes(),
+		VerifyingShares: make(map[Identifier][]byte, p.maxSigners),
+	}
+	for id := Identifier(1); int(id) <= p.maxSigners; id++ {
+		share := edwards25519.NewIdentityPoint()
+		for _, c := range all {
+			e, _ := evaluateCommitment(c, id)
+			share.Add(share, e)
+		}
+		pub.VerifyingShares[id] = share.Bytes()
+	}
+
+	key := &KeyShare{
+		Identifier:     p.id,
+		MinSigners:     p.minSigners,
+		signingShare:   secret,
+		VerifyingShare: new(edwards25519.Point).ScalarBaseMult(secret).Bytes(),
+		GroupPublicKey: pub.GroupPublicKey,
+	}
+	// Forget the polynomial: only the share is needed from here on.
+	p.coeffs = nil
+	p.finalized = true
+	return key, pub, nil
+}
+
+// evaluate returns f(id) for our secret polynomial.
+func (p *DKGParticipant) evaluate(id Identifier) *edwards25519.Scalar {
+	x := id.scalar()
+	acc := edwards25519.NewScalar()
+	for i := len(p.coeffs) - 1; i >= 0; i-- {
+		acc.MultiplyAdd(acc, x, p.coeffs[i])
+	}
+	return acc
+}
+
+func verifyRound1(pkg *DKGRound1Package, minSigners int) error {
+	if len(pkg.Commitment) != minSigners {
+		return invalidInput("commitment has wrong degree", nil)
+	}
+	for _, c := range pkg.Commitment {
+		if _, err := decodePoint(c); err != nil {
+			return err
+		}
+	}
+	R, err := decodePoint(pkg.ProofR)
+	if err != nil {
+		return err
+	}
+	z, err := decodeScalar(pkg.ProofZ)
+	if err != nil {
+		return err
+	}
+	c0, _ := decodePoint(pkg.Commitment[0])
+	c := dkgChallenge(pkg.Identifier, pkg.Commitment[0], pkg.ProofR)
+	// z*G == R + c*C0
+	lhs := new(edwards25519.Point).ScalarBaseMult(z)
+	rhs := new(edwards25519.Point).ScalarMult(c, c0)
+	rhs.Add(rhs, R)
+	if lhs.Equal(rhs) != 1 {
+		return invalidInput("proof of knowledge does not verify", nil)
+	}
+	return nil
+}
+
+// evaluateCommitment returns Σ C_k * id^k, the public image of f(id).
+func evaluateCommitment(commitment [][]byte, id Identifier) (*edwards25519.Point, error) {
+	x := id.scalar()
+	xk := scalarOne()
+	acc := edwards25519.NewIdentityPoint()
+	for _, c := range commitment {
+		p, err := decodePoint(c)
+		if err != nil {
+			return nil, err
+		}
+		acc.Add(acc, new(edwards25519.Point).ScalarMult(xk, p))
+		xk = edwards25519.NewScalar().Multiply(xk, x)
+	}
+	return acc, nil
+}
+
+func dkgChallenge(id Identifier, c0, R []byte) *edwards25519.Scalar {
+	return hashToScalar("dkg", id.scalar().Bytes(), c0, R)
+}
+
+func randomScalar() (*edwards25519.Scalar, error) {
+	var b [64]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return nil, errors.New(errors.CodeCryptoError, "failed to read randomness", errors.WithCause(err))
+	}
+	s, _ := edwards25519.NewScalar().SetUniformBytes(b[:])
+	return s, nil
+}
+
+func misbehavior(message string, culprits []Identifier) error {
+	return errors.New(errors.CodeCryptoError, message,
+		errors.WithDetails(map[string]interface{}{"culprits": culprits}))
+}
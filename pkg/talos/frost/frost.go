@@ -0,0 +1,167 @@
+// Package frost implements FROST(Ed25519, SHA-512) threshold signatures
+// (RFC 9591) with a Pedersen distributed key generation.
+//
+// A t-of-n group produces ordinary Ed25519 signatures: they verify with
+// wallet.Verify against the group public key, and the group can be addressed
+// by wallet.DIDFromPublicKey(GroupPublicKey).
+package frost
+
+import (
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+
+	"filippo.io/edwards25519"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+)
+
+// contextString is the RFC 9591 ciphersuite context for FROST(Ed25519, SHA-512).
+const contextString = "FROST-ED25519-SHA512-v1"
+
+// Identifier names a participant. Valid identifiers are 1..MaxSigners.
+type Identifier uint16
+
+func (id Identifier) scalar() *edwards25519.Scalar {
+	var b [32]byte
+	binary.LittleEndian.PutUint16(b[:], uint16(id))
+	s, _ := edwards25519.NewScalar().SetCanonicalBytes(b[:])
+	return s
+}
+
+// KeyShare is one participant's long-lived signing key material. The
+// signing share is secret and must be stored like a private key.
+type KeyShare struct {
+	Identifier     Identifier
+	MinSigners     int
+	signingShare   *edwards25519.Scalar
+	VerifyingShare []byte // signingShare * G
+	GroupPublicKey []byte // Ed25519 public key of the group
+}
+
+// SigningShare returns the encoded secret share, e.g. for storage.
+func (k *KeyShare) SigningShare() []byte {
+	return k.signingShare.Bytes()
+}
+
+// NewKeyShare rebuilds a KeyShare from its stored parts.
+func NewKeyShare(id Identifier, minSigners int, signingShare, groupPublicKey []byte) (*KeyShare, error) {
+	s, err := edwards25519.NewScalar().SetCanonicalBytes(signingShare)
+	if err != nil {
+		return nil, invalidInput("invalid signing share", err)
+	}
+	if _, err := decodePoint(groupPublicKey); err != nil {
+		return nil, err
+	}
+	return &KeyShare{
+		Identifier:     id,
+		MinSigners:     minSigners,
+		signingShare:   s,
+		VerifyingShare: new(edwards25519.Point).ScalarBaseMult(s).Bytes(),
+		GroupPublicKey: append([]byte(nil), groupPublicKey...),
+	}, nil
+}
+
+// PublicKeyPackage is the public output of key generation, shared by all
+// participants and by the aggregator.
+type PublicKeyPackage struct {
+	MinSigners      int
+	GroupPublicKey  []byte
+	VerifyingShares map[Identifier][]byte
+}
+
+func validateParams(minSigners, maxSigners int) error {
+	if minSigners < 2 || maxSigners < minSigners || maxSigners > 0xffff {
+		return errors.New(errors.CodeInvalidInput,
+			fmt.Sprintf("invalid threshold %d-of-%d", minSigners, maxSigners))
+	}
+	return nil
+}
+
+// hashToScalar implements H1, H3 and the DKG hash: SHA-512 over the context
+// string, a tag and the inputs, reduced mod L.
+func hashToScalar(tag string, parts ...[]byte) *edwards25519.Scalar {
+	h := sha512.New()
+	h.Write([]byte(contextString))
+	h.Write([]byte(tag))
+	for _, p := range parts {
+		h.Write(p)
+	}
+	s, _ := edwards25519.NewScalar().SetUniformBytes(h.Sum(nil))
+	return s
+}
+
+// hash implements H4 and H5.
+func hash(tag string, m []byte) []byte {
+	h := sha512.New()
+	h.Write([]byte(contextString))
+	h.Write([]byte(tag))
+	h.Write(m)
+	return h.Sum(nil)
+}
+
+// challenge implements H2, the Ed25519 challenge SHA-512(R || A || m).
+func challenge(R, A, message []byte) *edwards25519.Scalar {
+	h := sha512.New()
+	h.Write(R)
+	h.Write(A)
+	h.Write(message)
+	s, _ := edwards25519.NewScalar().SetUniformBytes(h.Sum(nil))
+	return s
+}
+
+// decodePoint decodes a canonical encoding of a non-identity point.
+func decodePoint(b []byte) (*edwards25519.Point, error) {
+	p, err := new(edwards25519.Point).SetBytes(b)
+	if err != nil || string(p.Bytes()) != string(b) {
+		return nil, invalidInput("invalid group element", err)
+	}
+	if p.Equal(edwards25519.NewIdentityPoint()) == 1 {
+		return nil, invalidInput("group element is the identity", nil)
+	}
+	return p, nil
+}
+
+func decodeScalar(b []byte) (*edwards25519.Scalar, error) {
+	s, err := edwards25519.NewScalar().SetCanonicalBytes(b)
+	if err != nil {
+		return nil, invalidInput("invalid scalar", err)
+	}
+	return s, nil
+}
+
+// lagrange returns the Lagrange coefficient of id for interpolation at zero
+// over the given set of identifiers.
+func lagrange(id Identifier, ids []Identifier) (*edwards25519.Scalar, error) {
+	num := scalarOne()
+	den := scalarOne()
+	x := id.scalar()
+	found := false
+	for _, j := range ids {
+		if j == id {
+			found = true
+			continue
+		}
+		xj := j.scalar()
+		num.Multiply(num, xj)
+		den.Multiply(den, edwards25519.NewScalar().Subtract(xj, x))
+	}
+	if !found {
+		return nil, invalidInput(fmt.Sprintf("participant %d not in signing set", id), nil)
+	}
+	return num.Multiply(num, edwards25519.NewScalar().Invert(den)), nil
+}
+
+func scalarOne() *edwards25519.Scalar {
+	var b [32]byte
+	b[0] = 1
+	s, _ := edwards25519.NewScalar().SetCanonicalBytes(b[:])
+	return s
+}
+
+func invalidInput(message string, cause error) error {
+	if cause != nil {
+		return errors.New(errors.CodeInvalidInput, message, errors.WithCause(cause))
+	}
+	return errors.New(errors.CodeInvalidInput, message)
+}
@@ -0,0 +1,179 @@
+package frost
+
+import (
+	"reflect"
+	"testing"
+
+	"filippo.io/edwards25519"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/wallet"
+)
+
+func culprits(t *testing.T, err error) []Identifier {
+	t.Helper()
+	te, ok := err.(*errors.TalosError)
+	if !ok {
+		t.Fatalf("expected *TalosError, got %v", err)
+	}
+	c, _ := te.Details["culprits"].([]Identifier)
+	return c
+}
+
+func TestThresholdSignature(t *testing.T) {
+	keys, pub, err := SimulateDKG(3, 5)
+	if err != nil {
+		t.Fatalf("SimulateDKG failed: %v", err)
+	}
+	for _, k := range keys {
+		if string(k.GroupPublicKey) != string(pub.GroupPublicKey) {
+			t.Fatal("participants disagree on group key")
+		}
+		if string(k.VerifyingShare) != string(pub.VerifyingShares[k.Identifier]) {
+			t.Fatalf("verifying share mismatch for %d", k.Identifier)
+		}
+	}
+
+	msg := []byte("rotate production credentials")
+	// Any 3 of 5 can sign, and the result is a plain Ed25519 signature.
+	for _, subset := range [][]int{{0, 1, 2}, {1, 3, 4}, {0, 2, 4}, {0, 1, 2, 3, 4}} {
+		var signers []*KeyShare
+		for _, i := range subset {
+			signers = append(signers, keys[i])
+		}
+		sig, err := SimulateSign(signers, pub, msg)
+		if err != nil {
+			t.Fatalf("%v: SimulateSign failed: %v", subset, err)
+		}
+		if !wallet.Verify(pub.GroupPublicKey, msg, sig) {
+			t.Errorf("%v: signature does not verify with wallet.Verify", subset)
+		}
+		if wallet.Verify(pub.GroupPublicKey, []byte("other"), sig) {
+			t.Errorf("%v: signature verifies for a different message", subset)
+		}
+	}
+
+	// Too few signers.
+	if _, err := SimulateSign(keys[:2], pub, msg); err == nil {
+		t.Error("expected error with fewer than threshold signers")
+	}
+}
+
+func TestSecretReconstruction(t *testing.T) {
+	// Interpolating any threshold of shares yields the secret whose public
+	// key is the group key.
+	keys, pub, _ := SimulateDKG(2, 3)
+	ids := []Identifier{keys[0].Identifier, keys[2].Identifier}
+	secret := edwards25519.NewScalar()
+	for _, k := range []*KeyShare{keys[0], keys[2]} {
+		l, _ := lagrange(k.Identifier, ids)
+		secret.MultiplyAdd(l, k.signingShare, secret)
+	}
+	if string(new(edwards25519.Point).ScalarBaseMult(secret).Bytes()) != string(pub.GroupPublicKey) {
+		t.Error("reconstructed secret does not match group key")
+	}
+}
+
+func TestAggregate_IdentifiesCheater(t *testing.T) {
+	keys, pub, _ := SimulateDKG(2, 3)
+	signers := keys[:2]
+	msg := []byte("m")
+
+	var nonces []*SigningNonces
+	var commitments []*SigningCommitment
+	for _, k := range signers {
+		n, c, _ := Commit(k)
+		nonces = append(nonces, n)
+		commitments = append(commitments, c)
+	}
+	var shares []*SignatureShare
+	for i, k := range signers {
+		s, err := SignShare(k, nonces[i], msg, commitments)
+		if err != nil {
+			t.Fatalf("SignShare failed: %v", err)
+		}
+		shares = append(shares, s)
+	}
+
+	// Participant 2 submits a corrupted share.
+	bad := edwards25519.NewScalar().Add(mustScalar(t, shares[1].Share), scalarOne())
+	shares[1] = &SignatureShare{Identifier: shares[1].Identifier, Share: bad.Bytes()}
+
+	_, err := Aggregate(pub, msg, commitments, shares)
+	if got := culprits(t, err); !reflect.DeepEqual(got, []Identifier{2}) {
+		t.Errorf("expected culprit [2], got %v", got)
+	}
+
+	// Nonces are single use.
+	if _, err := SignShare(signers[0], nonces[0], msg, commitments); err == nil {
+		t.Error("expected error when reusing nonces")
+	}
+}
+
+func TestDKG_RejectsBadPackages(t *testing.T) {
+	parts := make([]*DKGParticipant, 3)
+	round1 := make([]*DKGRound1Package, 3)
+	for i := range parts {
+		parts[i], _ = NewDKGParticipant(Identifier(i+1), 2, 3)
+		round1[i], _ = parts[i].Round1()
+	}
+
+	// A forged proof of knowledge is caught in round 2.
+	forged := *round1[2]
+	forged.ProofZ = scalarOne().Bytes()
+	_, err := parts[0].Round2([]*DKGRound1Package{round1[0], round1[1], &forged})
+	if got := culprits(t, err); !reflect.DeepEqual(got, []Identifier{3}) {
+		t.Errorf("expected culprit [3], got %v", got)
+	}
+
+	// An inconsistent secret share is caught when finalizing.
+	out := make([]map[Identifier]*DKGRound2Package, 3)
+	for i, p := range parts {
+		var err error
+		if out[i], err = p.Round2(round1); err != nil {
+			t.Fatalf("Round2 failed: %v", err)
+		}
+	}
+	tampered := *out[1][1]
+	tampered.Share = scalarOne().Bytes()
+	_, _, err = parts[0].Finalize([]*DKGRound2Package{&tampered, out[2][1]})
+	if got := culprits(t, err); !reflect.DeepEqual(got, []Identifier{2}) {
+		t.Errorf("expected culprit [2], got %v", got)
+	}
+
+	// Finalizing succeeds once; the polynomial is gone afterwards.
+	if _, _, err := parts[0].Finalize([]*DKGRound2Package{out[1][1], out[2][1]}); err != nil {
+		t.Fatalf("Finalize failed: %v", err)
+	}
+	if _, _, err := parts[0].Finalize([]*DKGRound2Package{out[1][1], out[2][1]}); err == nil {
+		t.Error("expected error for second Finalize")
+	}
+	if _, err := parts[0].Round2(round1); err == nil {
+		t.Error("expected error for Round2 after Finalize")
+	}
+
+	if _, err := NewDKGParticipant(1, 1, 3); err == nil {
+		t.Error("expected error for threshold below 2")
+	}
+}
+
+func TestKeyShareRoundTrip(t *testing.T) {
+	keys, pub, _ := SimulateDKG(2, 2)
+	restored, err := NewKeyShare(keys[0].Identifier, keys[0].MinSigners, keys[0].SigningShare(), keys[0].GroupPublicKey)
+	if err != nil {
+		t.Fatalf("NewKeyShare failed: %v", err)
+	}
+	sig, err := SimulateSign([]*KeyShare{restored, keys[1]}, pub, []byte("x"))
+	if err != nil || !wallet.Verify(pub.GroupPublicKey, []byte("x"), sig) {
+		t.Errorf("restored key share cannot sign: %v", err)
+	}
+}
+
+func mustScalar(t *testing.T, b []byte) *edwards25519.Scalar {
+	t.Helper()
+	s, err := decodeScalar(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
@@ -0,0 +1,241 @@
+package frost
+
+import (
+	"crypto/rand"
+	"fmt"
+	"sort"
+
+	"filippo.io/edwards25519"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+)
+
+// SigningCommitment is a participant's round 1 output, sent to the
+// coordinator.
+type SigningCommitment struct {
+	Identifier Identifier `json:"identifier"`
+	Hiding     []byte     `json:"hiding"`
+	Binding    []byte     `json:"binding"`
+}
+
+// SigningNonces are the secret nonces behind a SigningCommitment. They must
+// be used for exactly one SignShare call and never persisted.
+type SigningNonces struct {
+	hiding, binding *edwards25519.Scalar
+	commitment      SigningCommitment
+}
+
+// SignatureShare is a participant's round 2 output.
+type SignatureShare struct {
+	Identifier Identifier `json:"identifier"`
+	Share      []byte     `json:"share"`
+}
+
+// Commit runs signing round 1: it generates fresh nonces bound to the
+// participant's secret share (RFC 9591, Section 5.1).
+func Commit(key *KeyShare) (*SigningNonces, *SigningCommitment, error) {
+	hiding, err := nonceGenerate(key.signingShare)
+	if err != nil {
+		return nil, nil, err
+	}
+	binding, err := nonceGenerate(key.signingShare)
+	if err != nil {
+		return nil, nil, err
+	}
+	c := SigningCommitment{
+		Identifier: key.Identifier,
+		Hiding:     new(edwards25519.Point).ScalarBaseMult(hiding).Bytes(),
+		Binding:    new(edwards25519.Point).ScalarBaseMult(binding).Bytes(),
+	}
+	out := c
+	return &SigningNonces{hiding: hiding, binding: binding, commitment: c}, &out, nil
+}
+
+// SignShare runs signing round 2 over message, given the commitments of
+// every participant in the signing set (RFC 9591, Section 5.2). The nonces
+// are erased whether or not signing succeeds.
+func SignShare(key *KeyShare, nonces *SigningNonces, message []byte, commitments []*SigningCommitment) (*SignatureShare, error) {
+	if nonces == nil || nonces.hiding == nil {
+		return nil, invalidInput("signing nonces already used", nil)
+	}
+	hiding, binding, own := nonces.hiding, nonces.binding, nonces.commitment
+	nonces.hiding, nonces.binding = nil, nil
+
+	set, err := newSigningSet(commitments, key.MinSigners)
+	if err != nil {
+		return nil, err
+	}
+	mine, ok := set.byID[key.Identifier]
+	if !ok || string(mine.Hiding) != string(own.Hiding) || string(mine.Binding) != string(own.Binding) {
+		return nil, invalidInput("own commitment missing from signing set", nil)
+	}
+
+	rho := set.bindingFactors(key.GroupPublicKey, message)
+	R, err := set.groupCommitment(rho)
+	if err != nil {
+		return nil, err
+	}
+	lambda, err := lagrange(key.Identifier, set.ids)
+	if err != nil {
+		return nil, err
+	}
+	c := challenge(R.Bytes(), key.GroupPublicKey, message)
+
+	// z = d + e*rho + lambda*s*c
+	z := edwards25519.NewScalar().Multiply(lambda, key.signingShare)
+	z.Multiply(z, c)
+	z.MultiplyAdd(binding, rho[key.Identifier], z)
+	z.Add(z, hiding)
+	return &SignatureShare{Identifier: key.Identifier, Share: z.Bytes()}, nil
+}
+
+// Aggregate combines signature shares into an Ed25519 signature over
+// message. If the result does not verify, every share is checked against its
+// verifying share and the misbehaving participants are reported in
+// Details["culprits"].
+func Aggregate(pub *PublicKeyPackage, message []byte, commitments []*SigningCommitment, shares []*SignatureShare) ([]byte, error) {
+	set, err := newSigningSet(commitments, pub.MinSigners)
+	if err != nil {
+		return nil, err
+	}
+	if len(shares) != len(set.ids) {
+		return nil, invalidInput(fmt.Sprintf("expected %d signature shares, got %d", len(set.ids), len(shares)), nil)
+	}
+
+	rho := set.bindingFactors(pub.GroupPublicKey, message)
+	R, err := set.groupCommitment(rho)
+	if err != nil {
+		return nil, err
+	}
+
+	z := edwards25519.NewScalar()
+	byID := make(map[Identifier]*edwards25519.Scalar, len(shares))
+	for _, s := range shares {
+		if _, ok := set.byID[s.Identifier]; !ok || byID[s.Identifier] != nil {
+			return nil, invalidInput(fmt.Sprintf("unexpected signature share from %d", s.Identifier), nil)
+		}
+		zi, err := decodeScalar(s.Share)
+		if err != nil {
+			return nil, misbehavior("malformed signature share", []Identifier{s.Identifier})
+		}
+		byID[s.Identifier] = zi
+		z.Add(z, zi)
+	}
+
+	sig := append(R.Bytes(), z.Bytes()...)
+	if verifySignature(pub.GroupPublicKey, message, R, z) {
+		return sig, nil
+	}
+
+	// Identify cheaters: z_i*G == D_i + rho_i*E_i + (c*lambda_i)*Y_i.
+	c := challenge(R.Bytes(), pub.GroupPublicKey, message)
+	var culprits []Identifier
+	for _, id := range set.ids {
+		if !set.verifyShare(pub, id, byID[id], rho[id], c) {
+			culprits = append(culprits, id)
+		}
+	}
+	return nil, misbehavior("aggregate signature does not verify", culprits)
+}
+
+type signingSet struct {
+	ids     []Identifier
+	byID    map[Identifier]*SigningCommitment
+	encoded []byte
+}
+
+func newSigningSet(commitments []*SigningCommitment, minSigners int) (*signingSet, error) {
+	if len(commitments) < minSigners {
+		return nil, errors.New(errors.CodeInvalidInput,
+			fmt.Sprintf("need at least %d signers, got %d", minSigners, len(commitments)))
+	}
+	set := &signingSet{byID: make(map[Identifier]*SigningCommitment, len(commitments))}
+	for _, c := range commitments {
+		if c.Identifier == 0 || set.byID[c.Identifier] != nil {
+			return nil, invalidInput(fmt.Sprintf("invalid or duplicate signer %d", c.Identifier), nil)
+		}
+		if _, err := decodePoint(c.Hiding); err != nil {
+			return nil, err
+		}
+		if _, err := decodePoint(c.Binding); err != nil {
+			return nil, err
+		}
+		set.byID[c.Identifier] = c
+		set.ids = append(set.ids, c.Identifier)
+	}
+	sort.Slice(set.ids, func(i, j int) bool { return set.ids[i] < set.ids[j] })
+	for _, id := range set.ids {
+		c := set.byID[id]
+		set.encoded = append(set.encoded, id.scalar().Bytes()...)
+		set.encoded = append(set.encoded, c.Hiding...)
+		set.encoded = append(set.encoded, c.Binding...)
+	}
+	return set, nil
+}
+
+// bindingFactors computes rho_i for every signer (RFC 9591, Section 4.4).
+func (s *signingSet) bindingFactors(groupPublicKey, message []byte) map[Identifier]*edwards25519.Scalar {
+	prefix := append([]byte(nil), groupPublicKey...)
+	prefix = append(prefix, hash("msg", message)...)
+	prefix = append(prefix, hash("com", s.encoded)...)
+	out := make(map[Identifier]*edwards25519.Scalar, len(s.ids))
+	for _, id := range s.ids {
+		out[id] = hashToScalar("rho", prefix, id.scalar().Bytes())
+	}
+	return out
+}
+
+// groupCommitment computes R = Σ D_i + rho_i*E_i (RFC 9591, Section 4.5).
+func (s *signingSet) groupCommitment(rho map[Identifier]*edwards25519.Scalar) (*edwards25519.Point, error) {
+	R := edwards25519.NewIdentityPoint()
+	for _, id := range s.ids {
+		c := s.byID[id]
+		D, _ := decodePoint(c.Hiding)
+		E, _ := decodePoint(c.Binding)
+		R.Add(R, D)
+		R.Add(R, new(edwards25519.Point).ScalarMult(rho[id], E))
+	}
+	if R.Equal(edwards25519.NewIdentityPoint()) == 1 {
+		return nil, invalidInput("group commitment is the identity", nil)
+	}
+	return R, nil
+}
+
+func (s *signingSet) verifyShare(pub *PublicKeyPackage, id Identifier, z, rho, c *edwards25519.Scalar) bool {
+	Y, err := decodePoint(pub.VerifyingShares[id])
+	if err != nil {
+		return false
+	}
+	lambda, err := lagrange(id, s.ids)
+	if err != nil {
+		return false
+	}
+	D, _ := decodePoint(s.byID[id].Hiding)
+	E, _ := decodePoint(s.byID[id].Binding)
+
+	rhs := new(edwards25519.Point).ScalarMult(rho, E)
+	rhs.Add(rhs, D)
+	rhs.Add(rhs, new(edwards25519.Point).ScalarMult(edwards25519.NewScalar().Multiply(c, lambda), Y))
+	return new(edwards25519.Point).ScalarBaseMult(z).Equal(rhs) == 1
+}
+
+func verifySignature(groupPublicKey, message []byte, R *edwards25519.Point, z *edwards25519.Scalar) bool {
+	A, err := decodePoint(groupPublicKey)
+	if err != nil {
+		return false
+	}
+	c := challenge(R.Bytes(), groupPublicKey, message)
+	// z*G == R + c*A
+	rhs := new(edwards25519.Point).ScalarMult(c, A)
+	rhs.Add(rhs, R)
+	return new(edwards25519.Point).ScalarBaseMult(z).Equal(rhs) == 1
+}
+
+// nonceGenerate implements RFC 9591 nonce_generate: H3(random || secret).
+func nonceGenerate(secret *edwards25519.Scalar) (*edwards25519.Scalar, error) {
+	var r [32]byte
+	if _, err := rand.Read(r[:]); err != nil {
+		return nil, errors.New(errors.CodeCryptoError, "failed to read randomness", errors.WithCause(err))
+	}
+	return hashToScalar("nonce", r[:], secret.Bytes()), nil
+}
@@ -0,0 +1,77 @@
+package frost
+
+import (
+	"sort"
+)
+
+// SimulateDKG runs a complete DKG for maxSigners participants in-process and
+// returns their key shares (ordered by identifier) and the public key
+// package. It exercises exactly the messages a networked deployment would
+// exchange and is intended for tests and local tooling.
+func SimulateDKG(minSigners, maxSigners int) ([]*KeyShare, *PublicKeyPackage, error) {
+	if err := validateParams(minSigners, maxSigners); err != nil {
+		return nil, nil, err
+	}
+	parts := make([]*DKGParticipant, maxSigners)
+	round1 := make([]*DKGRound1Package, maxSigners)
+	for i := range parts {
+		p, err := NewDKGParticipant(Identifier(i+1), minSigners, maxSigners)
+		if err != nil {
+			return nil, nil, err
+		}
+		pkg, err := p.Round1()
+		if err != nil {
+			return nil, nil, err
+		}
+		parts[i], round1[i] = p, pkg
+	}
+
+	inbox := make(map[Identifier][]*DKGRound2Package)
+	for _, p := range parts {
+		out, err := p.Round2(round1)
+		if err != nil {
+			return nil, nil, err
+		}
+		for to, pkg := range out {
+			inbox[to] = append(inbox[to], pkg)
+		}
+	}
+
+	keys := make([]*KeyShare, maxSigners)
+	var pub *PublicKeyPackage
+	for i, p := range parts {
+		key, pk, err := p.Finalize(inbox[p.id])
+		if err != nil {
+			return nil, nil, err
+		}
+		keys[i], pub = key, pk
+	}
+	return keys, pub, nil
+}
+
+// SimulateSign runs both signing rounds and aggregation in-process for the
+// given signers and returns the Ed25519 signature.
+func SimulateSign(signers []*KeyShare, pub *PublicKeyPackage, message []byte) ([]byte, error) {
+	signers = append([]*KeyShare(nil), signers...)
+	sort.Slice(signers, func(i, j int) bool { return signers[i].Identifier < signers[j].Identifier })
+
+	nonces := make([]*SigningNonces, len(signers))
+	commitments := make([]*SigningCommitment, len(signers))
+	for i, key := range signers {
+		n, c, err := Commit(key)
+		if err != nil {
+			return nil, err
+		}
+		nonces[i], commitments[i] = n, c
+	}
+
+	shares := make([]*SignatureShare, len(signers))
+	for i, key := range signers {
+		s, err := SignShare(key, nonces[i], message, commitments)
+		if err != nil {
+			return nil, err
+		}
+		shares[i] = s
+	}
+	return Aggregate(pub, message, commitments, shares)
+}
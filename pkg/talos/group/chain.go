@@ -0,0 +1,96 @@
+package group
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+)
+
+// MaxSkip bounds how many message keys a receiver will derive ahead of the
+// next expected iteration to cope with reordering.
+const MaxSkip = 1000
+
+var (
+	messageKeySeed = []byte{0x01}
+	chainKeySeed   = []byte{0x02}
+)
+
+// senderChain is our own sending chain. The signing key is generated per
+// chain and distributed alongside the chain key.
+type senderChain struct {
+	generation uint64
+	iteration  uint32
+	chainKey   []byte
+	signingKey ed25519.PrivateKey
+}
+
+func newSenderChain(generation uint64) (*senderChain, error) {
+	ck := make([]byte, 32)
+	if _, err := rand.Read(ck); err != nil {
+		return nil, errors.New(errors.CodeCryptoError, "failed to generate chain key", errors.WithCause(err))
+	}
+	_, sk, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, errors.New(errors.CodeCryptoError, "failed to generate signing key", errors.WithCause(err))
+	}
+	return &senderChain{generation: generation, chainKey: ck, signingKey: sk}, nil
+}
+
+// next returns the message key for the current iteration and advances the
+// chain, discarding the previous chain key.
+func (c *senderChain) next() (iteration uint32, messageKey []byte) {
+	iteration = c.iteration
+	messageKey, c.chainKey = step(c.chainKey)
+	c.iteration++
+	return iteration, messageKey
+}
+
+// receiverChain tracks another member's sending chain.
+type receiverChain struct {
+	generation uint64
+	iteration  uint32 // next expected iteration
+	chainKey   []byte
+	verifyKey  ed25519.PublicKey
+	skipped    map[uint32][]byte
+}
+
+// messageKey returns the key for iteration, deriving forward as needed. Each
+// key can be retrieved once; used and skipped-over chain keys are erased.
+func (c *receiverChain) messageKey(iteration uint32) ([]byte, error) {
+	if iteration < c.iteration {
+		mk, ok := c.skipped[iteration]
+		if !ok {
+			return nil, errors.New(errors.CodeReplayDetected, "group message key already used",
+				errors.WithDetails(map[string]interface{}{"iteration": iteration}))
+		}
+		delete(c.skipped, iteration)
+		return mk, nil
+	}
+	if iteration-c.iteration > MaxSkip || len(c.skipped)+int(iteration-c.iteration) > MaxSkip {
+		return nil, errors.New(errors.CodeInvalidInput, "group message too far ahead of chain",
+			errors.WithDetails(map[string]interface{}{"iteration": iteration, "expected": c.iteration}))
+	}
+	for c.iteration < iteration {
+		var mk []byte
+		mk, c.chainKey = step(c.chainKey)
+		c.skipped[c.iteration] = mk
+		c.iteration++
+	}
+	mk, ck := step(c.chainKey)
+	c.chainKey = ck
+	c.iteration++
+	return mk, nil
+}
+
+func step(chainKey []byte) (messageKey, nextChainKey []byte) {
+	return hmacSHA256(chainKey, messageKeySeed), hmacSHA256(chainKey, chainKeySeed)
+}
+
+func hmacSHA256(key, data []byte) []byte {
+	m := hmac.New(sha256.New, key)
+	m.Write(data)
+	return m.Sum(nil)
+}
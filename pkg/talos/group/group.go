@@ -0,0 +1,299 @@
+package group
+
+import (
+	"crypto/ed25519"
+	"encoding/binary"
+	"encoding/json"
+	"sort"
+	"sync"
+
+	"golang.org/x/crypto/chacha20poly1305"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/canonical"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/seal"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/wallet"
+)
+
+const distributionType = "talos.group.sender_key.v1"
+
+// Group is one member's view of a sender-key group.
+//
+// Each member encrypts with its own symmetric sender chain, so a broadcast
+// costs one encryption regardless of group size. Chains are handed to other
+// members as distribution messages sealed over a pairwise channel (see the
+// seal package). Removing a member rotates our chain; every remaining member
+// must do the same so the removed member cannot read later traffic.
+//
+// A Group is safe for concurrent use.
+type Group struct {
+	id   string
+	self *wallet.Wallet
+
+	mu      sync.Mutex
+	members map[string]bool
+	own     *senderChain
+	peers   map[string]*receiverChain
+}
+
+// Message is a group message as sent on the wire.
+type Message struct {
+	GroupID    string `json:"group_id"`
+	Sender     string `json:"sender"`
+	Generation uint64 `json:"generation"`
+	Iteration  uint32 `json:"iteration"`
+	Ciphertext []byte `json:"ciphertext"`
+	Signature  []byte `json:"signature,omitempty"`
+}
+
+// Decrypted is a verified, decrypted group message.
+type Decrypted struct {
+	Sender    string
+	Plaintext []byte
+}
+
+type distribution struct {
+	Type       string `json:"type"`
+	GroupID    string `json:"group_id"`
+	Generation uint64 `json:"generation"`
+	Iteration  uint32 `json:"iteration"`
+	ChainKey   []byte `json:"chain_key"`
+	SigningKey []byte `json:"signing_key"`
+}
+
+// New creates a group in which self is the only member.
+func New(self *wallet.Wallet, groupID string) (*Group, error) {
+	if groupID == "" {
+		return nil, errors.New(errors.CodeInvalidInput, "group ID is required")
+	}
+	own, err := newSenderChain(0)
+	if err != nil {
+		return nil, err
+	}
+	return &Group{
+		id:      groupID,
+		self:    self,
+		members: map[string]bool{self.DID(): true},
+		own:     own,
+		peers:   make(map[string]*receiverChain),
+	}, nil
+}
+
+// ID returns the group identifier.
+func (g *Group) ID() string {
+	return g.id
+}
+
+// Members returns the member DIDs, sorted.
+func (g *Group) Members() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	out := make([]string, 0, len(g.members))
+	for did := range g.members {
+		out = append(out, did)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// AddMember adds did to the group and returns our sender key sealed to it.
+// The new member must receive the distribution of every existing member,
+// and add each of them in turn.
+func (g *Group) AddMember(did string) ([]byte, error) {
+	if _, err := wallet.PublicKeyFromDID(did); err != nil {
+		return nil, err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.members[did] = true
+	return g.distributionLocked(did)
+}
+
+// RemoveMember removes did, forgets its chain, and rotates our own chain.
+// It returns our new sender key sealed to each remaining member, keyed by
+// DID.
+func (g *Group) RemoveMember(did string) (map[string][]byte, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if did == g.self.DID() {
+		return nil, errors.New(errors.CodeInvalidInput, "cannot remove self from group")
+	}
+	delete(g.members, did)
+	delete(g.peers, did)
+	return g.rotateLocked()
+}
+
+// Rotate replaces our sender chain with a fresh one and returns it sealed to
+// each other member.
+func (g *Group) Rotate() (map[string][]byte, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.rotateLocked()
+}
+
+func (g *Group) rotateLocked() (map[string][]byte, error) {
+	own, err := newSenderChain(g.own.generation + 1)
+	if err != nil {
+		return nil, err
+	}
+	g.own = own
+	out := make(map[string][]byte)
+	for did := range g.members {
+		if did == g.self.DID() {
+			continue
+		}
+		sealed, err := g.distributionLocked(did)
+		if err != nil {
+			return nil, err
+		}
+		out[did] = sealed
+	}
+	return out, nil
+}
+
+func (g *Group) distributionLocked(did string) ([]byte, error) {
+	d := distribution{
+		Type:       distributionType,
+		GroupID:    g.id,
+		Generation: g.own.generation,
+		Iteration:  g.own.iteration,
+		ChainKey:   g.own.chainKey,
+		SigningKey: g.own.signingKey.Public().(ed25519.PublicKey),
+	}
+	b, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+	return seal.Seal(did, b, seal.WithSender(g.self))
+}
+
+// ProcessDistribution installs a sender key received from another member.
+// The distribution must be sender-authenticated, addressed to this group and
+// come from a current member. Older generations are ignored.
+func (g *Group) ProcessDistribution(sealed []byte) error {
+	msg, err := seal.Open(g.self, sealed)
+	if err != nil {
+		return err
+	}
+	if !msg.Authenticated() {
+		return errors.New(errors.CodeDenied, "sender key distribution is not authenticated")
+	}
+	var d distribution
+	if err := json.Unmarshal(msg.Plaintext, &d); err != nil || d.Type != distributionType {
+		return errors.New(errors.CodeInvalidInput, "malformed sender key distribution")
+	}
+	if d.GroupID != g.id {
+		return errors.New(errors.CodeInvalidInput, "sender key is for another group",
+			errors.WithDetails(map[string]interface{}{"group_id": d.GroupID}))
+	}
+	if len(d.ChainKey) != 32 || len(d.SigningKey) != ed25519.PublicKeySize {
+		return errors.New(errors.CodeInvalidInput, "malformed sender key distribution")
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.members[msg.Sender] {
+		return notMember(msg.Sender)
+	}
+	if cur, ok := g.peers[msg.Sender]; ok && cur.generation >= d.Generation {
+		return nil
+	}
+	g.peers[msg.Sender] = &receiverChain{
+		generation: d.Generation,
+		iteration:  d.Iteration,
+		chainKey:   d.ChainKey,
+		verifyKey:  d.SigningKey,
+		skipped:    make(map[uint32][]byte),
+	}
+	return nil
+}
+
+// Encrypt encrypts plaintext for all current members.
+func (g *Group) Encrypt(plaintext []byte) ([]byte, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	iteration, mk := g.own.next()
+	m := Message{
+		GroupID:    g.id,
+		Sender:     g.self.DID(),
+		Generation: g.own.generation,
+		Iteration:  iteration,
+	}
+	aead, err := chacha20poly1305.New(mk)
+	if err != nil {
+		return nil, errors.New(errors.CodeCryptoError, "failed to init cipher", errors.WithCause(err))
+	}
+	m.Ciphertext = aead.Seal(nil, make([]byte, aead.NonceSize()), plaintext, associatedData(&m))
+
+	signed, err := signingBytes(&m)
+	if err != nil {
+		return nil, err
+	}
+	m.Signature = ed25519.Sign(g.own.signingKey, signed)
+	return json.Marshal(&m)
+}
+
+// Decrypt verifies and decrypts a message from another member.
+func (g *Group) Decrypt(data []byte) (*Decrypted, error) {
+	var m Message
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, errors.New(errors.CodeInvalidInput, "malformed group message", errors.WithCause(err))
+	}
+	if m.GroupID != g.id {
+		return nil, errors.New(errors.CodeInvalidInput, "message is for another group")
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.members[m.Sender] {
+		return nil, notMember(m.Sender)
+	}
+	chain, ok := g.peers[m.Sender]
+	if !ok || chain.generation != m.Generation {
+		return nil, errors.New(errors.CodeSessionNotFound, "no sender key for message",
+			errors.WithDetails(map[string]interface{}{"sender": m.Sender, "generation": m.Generation}))
+	}
+
+	// Verify before touching the chain so forged messages cannot advance it.
+	signed, err := signingBytes(&m)
+	if err != nil {
+		return nil, err
+	}
+	if !ed25519.Verify(chain.verifyKey, signed, m.Signature) {
+		return nil, errors.New(errors.CodeCryptoError, "group message signature is invalid")
+	}
+
+	mk, err := chain.messageKey(m.Iteration)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.New(mk)
+	if err != nil {
+		return nil, errors.New(errors.CodeCryptoError, "failed to init cipher", errors.WithCause(err))
+	}
+	plaintext, err := aead.Open(nil, make([]byte, aead.NonceSize()), m.Ciphertext, associatedData(&m))
+	if err != nil {
+		return nil, errors.New(errors.CodeCryptoError, "failed to decrypt group message", errors.WithCause(err))
+	}
+	return &Decrypted{Sender: m.Sender, Plaintext: plaintext}, nil
+}
+
+func signingBytes(m *Message) ([]byte, error) {
+	unsigned := *m
+	unsigned.Signature = nil
+	return canonical.Marshal(&unsigned)
+}
+
+func associatedData(m *Message) []byte {
+	ad := binary.BigEndian.AppendUint64(nil, m.Generation)
+	ad = binary.BigEndian.AppendUint32(ad, m.Iteration)
+	ad = append(ad, m.GroupID...)
+	ad = append(ad, 0)
+	return append(ad, m.Sender...)
+}
+
+func notMember(did string) error {
+	return errors.New(errors.CodeDenied, "sender is not a group member",
+		errors.WithDetails(map[string]interface{}{"sender": did}))
+}
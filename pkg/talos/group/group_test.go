@@ -0,0 +1,166 @@
+package group
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/wallet"
+)
+
+type member struct {
+	w *wallet.Wallet
+	g *Group
+}
+
+func expectCode(t *testing.T, err error, code errors.TalosErrorCode) {
+	t.Helper()
+	te, ok := err.(*errors.TalosError)
+	if !ok || te.Code != code {
+		t.Errorf("expected %s, got %v", code, err)
+	}
+}
+
+// newTeam builds a fully connected group where every member holds every
+// other member's sender key.
+func newTeam(t *testing.T, names ...string) map[string]*member {
+	team := make(map[string]*member)
+	for _, name := range names {
+		w, _ := wallet.Generate(name)
+		g, err := New(w, "team-1")
+		if err != nil {
+			t.Fatalf("New failed: %v", err)
+		}
+		team[name] = &member{w: w, g: g}
+	}
+	for _, a := range team {
+		for _, b := range team {
+			if a == b {
+				continue
+			}
+			dist, err := a.g.AddMember(b.w.DID())
+			if err != nil {
+				t.Fatalf("AddMember failed: %v", err)
+			}
+			// b must know a before accepting its key.
+			if _, err := b.g.AddMember(a.w.DID()); err != nil {
+				t.Fatalf("AddMember failed: %v", err)
+			}
+			if err := b.g.ProcessDistribution(dist); err != nil {
+				t.Fatalf("ProcessDistribution failed: %v", err)
+			}
+		}
+	}
+	return team
+}
+
+func deliver(t *testing.T, dists map[string][]byte, team map[string]*member) {
+	t.Helper()
+	for _, m := range team {
+		if d, ok := dists[m.w.DID()]; ok {
+			if err := m.g.ProcessDistribution(d); err != nil {
+				t.Fatalf("ProcessDistribution failed: %v", err)
+			}
+		}
+	}
+}
+
+func TestGroup_Broadcast(t *testing.T) {
+	team := newTeam(t, "alice", "bob", "carol")
+
+	msg, err := team["alice"].g.Encrypt([]byte("deploy at 5"))
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	for _, name := range []string{"bob", "carol"} {
+		got, err := team[name].g.Decrypt(msg)
+		if err != nil {
+			t.Fatalf("%s: Decrypt failed: %v", name, err)
+		}
+		if string(got.Plaintext) != "deploy at 5" || got.Sender != team["alice"].w.DID() {
+			t.Errorf("%s: unexpected message %+v", name, got)
+		}
+	}
+
+	// Each message key is single use.
+	_, err = team["bob"].g.Decrypt(msg)
+	expectCode(t, err, errors.CodeReplayDetected)
+}
+
+func TestGroup_OutOfOrder(t *testing.T) {
+	team := newTeam(t, "alice", "bob")
+	m1, _ := team["alice"].g.Encrypt([]byte("one"))
+	m2, _ := team["alice"].g.Encrypt([]byte("two"))
+
+	if got, err := team["bob"].g.Decrypt(m2); err != nil || string(got.Plaintext) != "two" {
+		t.Fatalf("Decrypt m2 failed: %v", err)
+	}
+	if got, err := team["bob"].g.Decrypt(m1); err != nil || string(got.Plaintext) != "one" {
+		t.Fatalf("Decrypt m1 failed: %v", err)
+	}
+}
+
+func TestGroup_RemovalForwardSecrecy(t *testing.T) {
+	team := newTeam(t, "alice", "bob", "carol")
+	carol := team["carol"]
+	carolDID := carol.w.DID()
+
+	// Carol is removed; every remaining member rotates and redistributes.
+	remaining := map[string]*member{"alice": team["alice"], "bob": team["bob"]}
+	for _, m := range remaining {
+		dists, err := m.g.RemoveMember(carolDID)
+		if err != nil {
+			t.Fatalf("RemoveMember failed: %v", err)
+		}
+		if _, ok := dists[carolDID]; ok {
+			t.Fatal("removed member received new sender key")
+		}
+		deliver(t, dists, remaining)
+	}
+
+	msg, _ := team["alice"].g.Encrypt([]byte("after removal"))
+	if got, err := team["bob"].g.Decrypt(msg); err != nil || string(got.Plaintext) != "after removal" {
+		t.Fatalf("bob Decrypt failed: %v", err)
+	}
+
+	// Carol still holds Alice's old chain but cannot read the new generation.
+	_, err := carol.g.Decrypt(msg)
+	expectCode(t, err, errors.CodeSessionNotFound)
+
+	// Even forcing the new generation onto her old chain fails: the keys are
+	// unrelated.
+	var m Message
+	_ = json.Unmarshal(msg, &m)
+	carol.g.peers[m.Sender].generation = m.Generation
+	if _, err := carol.g.Decrypt(msg); err == nil {
+		t.Error("removed member decrypted post-removal message")
+	}
+
+	// And her messages are no longer accepted.
+	fromCarol, _ := carol.g.Encrypt([]byte("still here"))
+	_, err = team["bob"].g.Decrypt(fromCarol)
+	expectCode(t, err, errors.CodeDenied)
+}
+
+func TestGroup_RejectsForgery(t *testing.T) {
+	team := newTeam(t, "alice", "bob", "mallory")
+	msg, _ := team["alice"].g.Encrypt([]byte("pay 10"))
+
+	var m Message
+	_ = json.Unmarshal(msg, &m)
+	m.Ciphertext[0] ^= 1
+	forged, _ := json.Marshal(&m)
+	_, err := team["bob"].g.Decrypt(forged)
+	expectCode(t, err, errors.CodeCryptoError)
+
+	// The forgery did not consume the real message key.
+	if _, err := team["bob"].g.Decrypt(msg); err != nil {
+		t.Errorf("genuine message rejected after forgery: %v", err)
+	}
+
+	// A distribution from a non-member is refused.
+	outsider, _ := wallet.Generate("outsider")
+	og, _ := New(outsider, "team-1")
+	dist, _ := og.AddMember(team["bob"].w.DID())
+	expectCode(t, team["bob"].g.ProcessDistribution(dist), errors.CodeDenied)
+}
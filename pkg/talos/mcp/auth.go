@@ -0,0 +1,248 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultRefreshMargin is how long before expiry ClientCredentials fetches a
+// new token.
+const DefaultRefreshMargin = 30 * time.Second
+
+// TokenSource supplies the bearer token for each request.
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// WithTokenSource authenticates requests with tokens from ts instead of the
+// static API key.
+func WithTokenSource(ts TokenSource) Option {
+	return func(c *McpClient) {
+		c.TokenSource = ts
+	}
+}
+
+// StaticToken is a fixed token, e.g. an API key.
+type StaticToken string
+
+// Token implements TokenSource.
+func (s StaticToken) Token(context.Context) (string, error) {
+	return string(s), nil
+}
+
+// ClientCredentialsConfig configures an OAuth 2.0 client credentials grant
+// (RFC 6749, Section 4.4).
+type ClientCredentialsConfig struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	// HTTPClient defaults to an http.Client with DefaultTimeout.
+	HTTPClient Doer
+	// RefreshMargin defaults to DefaultRefreshMargin and is capped at half
+	// of each token's lifetime.
+	RefreshMargin time.Duration
+}
+
+// ClientCredentials fetches and caches access tokens from an OAuth 2.0 token
+// endpoint. A token is refreshed once it is within the refresh margin of its
+// expiry, or halfway through its lifetime if that is shorter than the
+// margin; concurrent callers share a single refresh. Tokens without an
+// expires_in are kept until Invalidate is called.
+type ClientCredentials struct {
+	cfg ClientCredentialsConfig
+	now func() time.Time
+
+	mu        sync.Mutex
+	token     string
+	expiry    time.Time // zero if the token does not expire
+	refreshAt time.Time
+	pending   *tokenFetch
+}
+
+type tokenFetch struct {
+	done      chan struct{}
+	token     string
+	expiry    time.Time
+	refreshAt time.Time
+	err       error
+}
+
+// NewClientCredentials creates a token source for cfg.
+func NewClientCredentials(cfg ClientCredentialsConfig) *ClientCredentials {
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: DefaultTimeout}
+	}
+	if cfg.RefreshMargin == 0 {
+		cfg.RefreshMargin = DefaultRefreshMargin
+	}
+	return &ClientCredentials{cfg: cfg, now: time.Now}
+}
+
+// Token implements TokenSource. If a refresh fails while the cached token
+// has not yet expired, the cached token is returned.
+func (c *ClientCredentials) Token(ctx context.Context) (string, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	c.mu.Lock()
+	now := c.now()
+	if c.token != "" && (c.expiry.IsZero() || now.Before(c.refreshAt)) {
+		token := c.token
+		c.mu.Unlock()
+		return token, nil
+	}
+	f := c.pending
+	if f == nil {
+		f = &tokenFetch{done: make(chan struct{})}
+		c.pending = f
+		go c.refresh(f)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-f.done:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	if f.err == nil {
+		return f.token, nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && (c.expiry.IsZero() || c.now().Before(c.expiry)) {
+		return c.token, nil
+	}
+	return "", f.err
+}
+
+// Invalidate discards the cached token, e.g. after the server rejected it.
+func (c *ClientCredentials) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token, c.expiry, c.refreshAt = "", time.Time{}, time.Time{}
+}
+
+// refresh runs one fetch for all waiting callers. It is detached from any
+// single caller's context so that one cancellation does not fail the rest.
+func (c *ClientCredentials) refresh(f *tokenFetch) {
+	f.token, f.expiry, f.refreshAt, f.err = c.fetch(context.Background())
+	c.mu.Lock()
+	c.pending = nil
+	if f.err == nil {
+		c.token, c.expiry, c.refreshAt = f.token, f.expiry, f.refreshAt
+	}
+	c.mu.Unlock()
+	close(f.done)
+}
+
+// fetch requests a token and returns it with its expiry and the time to
+// refresh it.
+func (c *ClientCredentials) fetch(ctx context.Context) (token string, expiry, refreshAt time.Time, err error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(c.cfg.Scopes) > 0 {
+		form.Set("scope", strings.Join(c.cfg.Scopes, " "))
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", c.cfg.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", time.Time{}, time.Time{}, err
+	}
+	req.SetBasicAuth(url.QueryEscape(c.cfg.ClientID), url.QueryEscape(c.cfg.ClientSecret))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "talos-sdk-go/"+Version)
+
+	start := c.now()
+	resp, err := c.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return "", time.Time{}, time.Time{}, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, MaxErrorSnippetBytes))
+	if err != nil {
+		return "", time.Time{}, time.Time{}, fmt.Errorf("failed to read token response: %w", err)
+	}
+
+	var result struct {
+		AccessToken      string `json:"access_token"`
+		TokenType        string `json:"token_type"`
+		ExpiresIn        int64  `json:"expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	_ = json.Unmarshal(body, &result)
+	if resp.StatusCode != http.StatusOK || result.Error != "" {
+		if result.Error != "" {
+			return "", time.Time{}, time.Time{}, fmt.Errorf("token endpoint error [status=%d, error=%s]: %s", resp.StatusCode, result.Error, result.ErrorDescription)
+		}
+		return "", time.Time{}, time.Time{}, fmt.Errorf("token endpoint http error [status=%d]: %s", resp.StatusCode, body)
+	}
+	if result.AccessToken == "" {
+		return "", time.Time{}, time.Time{}, fmt.Errorf("token response has no access_token")
+	}
+	if result.TokenType != "" && !strings.EqualFold(result.TokenType, "bearer") {
+		return "", time.Time{}, time.Time{}, fmt.Errorf("unsupported token type %q", result.TokenType)
+	}
+	if result.ExpiresIn > 0 {
+		// Measured from when the request was sent, to err on the early side.
+		// A margin as long as the token's lifetime would refresh it on
+		// every call, so short-lived tokens are refreshed at half-life.
+		lifetime := time.Duration(result.ExpiresIn) * time.Second
+		margin := c.cfg.RefreshMargin
+		if margin > lifetime/2 {
+			margin = lifetime / 2
+		}
+		expiry = start.Add(lifetime)
+		refreshAt = expiry.Add(-margin)
+	}
+	return result.AccessToken, expiry, refreshAt, nil
+}
+
+// FileToken reads a token from a file, e.g. a projected service account
+// token, and re-reads it whenever the file's modification time or size
+// changes. Surrounding whitespace is ignored.
+type FileToken struct {
+	path string
+
+	mu      sync.Mutex
+	token   string
+	modTime time.Time
+	size    int64
+}
+
+// NewFileToken creates a token source for the file at path.
+func NewFileToken(path string) *FileToken {
+	return &FileToken{path: path}
+}
+
+// Token implements TokenSource.
+func (f *FileToken) Token(context.Context) (string, error) {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return "", fmt.Errorf("failed to stat token file: %w", err)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.token != "" && info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return f.token, nil
+	}
+	b, err := os.ReadFile(f.path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read token file: %w", err)
+	}
+	token := string(bytes.TrimSpace(b))
+	if token == "" {
+		return "", fmt.Errorf("token file %s is empty", f.path)
+	}
+	f.token, f.modTime, f.size = token, info.ModTime(), info.Size()
+	return token, nil
+}
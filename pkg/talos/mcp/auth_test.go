@@ -0,0 +1,187 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func tokenServer(t *testing.T, expiresIn int64) (*httptest.Server, *int32) {
+	t.Helper()
+	var issued int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "agent" || secret != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client", "error_description": "bad credentials"})
+			return
+		}
+		if r.FormValue("grant_type") != "client_credentials" || r.FormValue("scope") != "mcp:call mcp:list" {
+			t.Errorf("Unexpected form: %v", r.Form)
+		}
+		time.Sleep(10 * time.Millisecond)
+		n := atomic.AddInt32(&issued, 1)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token": fmt.Sprintf("token-%d", n),
+			"token_type":   "Bearer",
+			"expires_in":   expiresIn,
+		})
+	}))
+	t.Cleanup(ts.Close)
+	return ts, &issued
+}
+
+func TestClientCredentials(t *testing.T) {
+	ts, issued := tokenServer(t, 300)
+	cc := NewClientCredentials(ClientCredentialsConfig{
+		TokenURL:     ts.URL,
+		ClientID:     "agent",
+		ClientSecret: "s3cret",
+		Scopes:       []string{"mcp:call", "mcp:list"},
+		HTTPClient:   ts.Client(),
+	})
+	now := time.Now()
+	var clockMu sync.Mutex
+	cc.now = func() time.Time {
+		clockMu.Lock()
+		defer clockMu.Unlock()
+		return now
+	}
+
+	// Concurrent callers share one fetch.
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if tok, err := cc.Token(context.Background()); err != nil || tok != "token-1" {
+				t.Errorf("Expected token-1, got %q (%v)", tok, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := atomic.LoadInt32(issued); n != 1 {
+		t.Fatalf("Expected 1 token request, got %d", n)
+	}
+
+	// Refreshed once within the margin of expiry.
+	clockMu.Lock()
+	now = now.Add(280 * time.Second)
+	clockMu.Unlock()
+	if tok, _ := cc.Token(context.Background()); tok != "token-2" {
+		t.Errorf("Expected refreshed token-2, got %q", tok)
+	}
+	if tok, _ := cc.Token(context.Background()); tok != "token-2" || atomic.LoadInt32(issued) != 2 {
+		t.Errorf("Expected cached token-2, got %q", tok)
+	}
+
+	cc.Invalidate()
+	if tok, _ := cc.Token(context.Background()); tok != "token-3" {
+		t.Errorf("Expected token-3 after Invalidate, got %q", tok)
+	}
+}
+
+func TestClientCredentials_ShortLived(t *testing.T) {
+	// A token living no longer than the refresh margin is refreshed at
+	// half-life rather than on every call.
+	ts, issued := tokenServer(t, 30)
+	cc := NewClientCredentials(ClientCredentialsConfig{
+		TokenURL: ts.URL, ClientID: "agent", ClientSecret: "s3cret", Scopes: []string{"mcp:call", "mcp:list"},
+	})
+	now := time.Now()
+	cc.now = func() time.Time { return now }
+	for i := 0; i < 5; i++ {
+		if tok, err := cc.Token(context.Background()); err != nil || tok != "token-1" {
+			t.Fatalf("Expected token-1, got %q (%v)", tok, err)
+		}
+	}
+	now = now.Add(16 * time.Second)
+	if tok, _ := cc.Token(context.Background()); tok != "token-2" {
+		t.Errorf("Expected token-2 past half-life, got %q", tok)
+	}
+	if n := atomic.LoadInt32(issued); n != 2 {
+		t.Errorf("Expected 2 token requests, got %d", n)
+	}
+}
+
+func TestClientCredentials_Errors(t *testing.T) {
+	ts, _ := tokenServer(t, 300)
+	cc := NewClientCredentials(ClientCredentialsConfig{TokenURL: ts.URL, ClientID: "agent", ClientSecret: "wrong"})
+	if _, err := cc.Token(context.Background()); err == nil {
+		t.Error("Expected error for bad credentials")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cc = NewClientCredentials(ClientCredentialsConfig{TokenURL: ts.URL, ClientID: "agent", ClientSecret: "s3cret"})
+	if _, err := cc.Token(ctx); err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestFileToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("first\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ft := NewFileToken(path)
+	if tok, err := ft.Token(context.Background()); err != nil || tok != "first" {
+		t.Fatalf("Expected first, got %q (%v)", tok, err)
+	}
+
+	if err := os.WriteFile(path, []byte("second-token"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	_ = os.Chtimes(path, later, later)
+	if tok, _ := ft.Token(context.Background()); tok != "second-token" {
+		t.Errorf("Expected rotated token, got %q", tok)
+	}
+
+	_ = os.WriteFile(path, nil, 0o600)
+	if _, err := ft.Token(context.Background()); err == nil {
+		t.Error("Expected error for empty token file")
+	}
+	if _, err := NewFileToken(filepath.Join(t.TempDir(), "missing")).Token(context.Background()); err == nil {
+		t.Error("Expected error for missing token file")
+	}
+}
+
+func TestCallTool_TokenSource(t *testing.T) {
+	tokenTS, _ := tokenServer(t, 300)
+	var auth []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = append(auth, r.Header.Get("Authorization"))
+		_ = json.NewEncoder(w).Encode(ToolCallResponse{})
+	}))
+	defer ts.Close()
+
+	cc := NewClientCredentials(ClientCredentialsConfig{
+		TokenURL: tokenTS.URL, ClientID: "agent", ClientSecret: "s3cret", Scopes: []string{"mcp:call", "mcp:list"},
+	})
+	client := NewClient(ts.URL, "ignored-key", WithTokenSource(cc))
+	for i := 0; i < 2; i++ {
+		if _, err := client.CallTool(context.Background(), "s", "t", nil, "", ""); err != nil {
+			t.Fatalf("CallTool failed: %v", err)
+		}
+	}
+	if len(auth) != 2 || auth[0] != "Bearer token-1" || auth[1] != "Bearer token-1" {
+		t.Errorf("Unexpected Authorization headers: %v", auth)
+	}
+
+	failing := NewClient(ts.URL, "", WithTokenSource(NewFileToken(filepath.Join(t.TempDir(), "missing"))))
+	if _, err := failing.CallTool(context.Background(), "s", "t", nil, "", ""); err == nil {
+		t.Error("Expected error when no token is available")
+	}
+	if len(auth) != 2 {
+		t.Error("Request was sent without a token")
+	}
+}
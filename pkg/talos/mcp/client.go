@@ -4,13 +4,22 @@ import (
 	"bytes"
 	"context"
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/audit"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/canonical"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/policy"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/wallet"
 )
 
 var Version = "dev"
@@ -34,10 +43,27 @@ type Doer interface {
 }
 
 type McpClient struct {
-	BaseURL          string
+	BaseURL string
+	// APIKey is sent as the bearer token unless TokenSource is set.
 	APIKey           string
+	TokenSource      TokenSource
 	HTTPClient       Doer
 	MaxResponseBytes int64
+	AuditLog         *audit.Log
+	// Entropy is the randomness source for generated request IDs. Nil
+	// means crypto/rand.
+	Entropy io.Reader
+	// Policy, if set, is evaluated before every tool call; denied calls
+	// never reach the gateway. Caller is the DID evaluated against it.
+	Policy *policy.Engine
+	Caller string
+	// Signer, if set, signs every tool call; Capability is an encoded
+	// capability token attached to every tool call.
+	Signer     *wallet.Wallet
+	Capability string
+
+	mu         sync.Mutex
+	serverTags map[string]map[string]string
 }
 
 type APIError struct {
@@ -81,6 +107,34 @@ func WithMaxResponseBytes(limit int64) Option {
 	}
 }
 
+// WithEntropy sets the randomness source for generated request IDs, e.g. a
+// cryptotest.Reader for reproducible tests. Tool calls fail if it cannot be
+// read.
+func WithEntropy(r io.Reader) Option {
+	return func(c *McpClient) {
+		c.Entropy = r
+	}
+}
+
+// WithAuditLog records every tool call, successful or not, in log. If an
+// entry cannot be written the call returns an *AuditError.
+func WithAuditLog(log *audit.Log) Option {
+	return func(c *McpClient) {
+		c.AuditLog = log
+	}
+}
+
+// WithPolicy refuses tool calls that engine denies for caller. If the policy
+// matches on server tags, the client fetches them with ListServers whenever
+// it calls a server it has not seen listed, and refuses calls to servers the
+// gateway does not list; ListServers calls also refresh them.
+func WithPolicy(engine *policy.Engine, caller string) Option {
+	return func(c *McpClient) {
+		c.Policy = engine
+		c.Caller = caller
+	}
+}
+
 func NewClient(baseURL string, apiKey string, opts ...Option) *McpClient {
 	c := &McpClient{
 		BaseURL:          strings.TrimRight(baseURL, "/"),
@@ -104,7 +158,9 @@ func (c *McpClient) ListServers(ctx context.Context) ([]Server, error) {
 		return nil, err
 	}
 
-	c.setHeaders(req, "")
+	if err := c.setHeaders(req, ""); err != nil {
+		return nil, err
+	}
 
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
@@ -123,6 +179,13 @@ func (c *McpClient) ListServers(ctx context.Context) ([]Server, error) {
 		return nil, err
 	}
 
+	c.mu.Lock()
+	c.serverTags = make(map[string]map[string]string, len(result.Servers))
+	for _, s := range result.Servers {
+		c.serverTags[s.ID] = s.Tags
+	}
+	c.mu.Unlock()
+
 	return result.Servers, nil
 }
 
@@ -136,7 +199,9 @@ func (c *McpClient) ListTools(ctx context.Context, serverID string) ([]Tool, err
 		return nil, err
 	}
 
-	c.setHeaders(req, "")
+	if err := c.setHeaders(req, ""); err != nil {
+		return nil, err
+	}
 
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
@@ -158,14 +223,24 @@ func (c *McpClient) ListTools(ctx context.Context, serverID string) ([]Tool, err
 	return result.Tools, nil
 }
 
-func (c *McpClient) setHeaders(req *http.Request, requestID string) {
-	req.Header.Set("Authorization", "Bearer "+c.APIKey)
+func (c *McpClient) setHeaders(req *http.Request, requestID string) error {
+	token := c.APIKey
+	if c.TokenSource != nil {
+		var err error
+		if token, err = c.TokenSource.Token(req.Context()); err != nil {
+			return fmt.Errorf("failed to obtain access token: %w", err)
+		}
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("User-Agent", "talos-sdk-go/"+Version)
 	if requestID != "" {
 		req.Header.Set("X-Request-Id", requestID)
 	}
+	return nil
 }
 
 func (c *McpClient) handleError(resp *http.Response) error {
@@ -196,10 +271,15 @@ func (c *McpClient) handleError(resp *http.Response) error {
 	return apiErr
 }
 
-func generateRequestID() string {
+func generateRequestID(r io.Reader) (string, error) {
+	if r == nil {
+		r = rand.Reader
+	}
 	b := make([]byte, 16)
-	_, _ = rand.Read(b)
-	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+	if _, err := io.ReadFull(r, b); err != nil {
+		return "", fmt.Errorf("failed to generate request ID: %w", err)
+	}
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
 }
 
 func pathEscape(s string) string {
@@ -226,13 +306,52 @@ func (r *ToolCallResponse) DecodeOutput(v any) error {
 	return json.Unmarshal(r.Output, v)
 }
 
+// AuditError reports that a tool call could not be recorded in the audit
+// log. The call has already reached the gateway, so it must not be retried
+// blindly: Response is its result, or nil if it failed with CallErr.
+type AuditError struct {
+	Err      error
+	Response *ToolCallResponse
+	CallErr  error
+}
+
+func (e *AuditError) Error() string {
+	return fmt.Sprintf("failed to record tool call in audit log: %v", e.Err)
+}
+
+func (e *AuditError) Unwrap() error {
+	return e.Err
+}
+
+// CallTool invokes a tool on the gateway. If an audit log is configured and
+// the call cannot be recorded, the error is an *AuditError and the response,
+// if any, is returned alongside it.
 func (c *McpClient) CallTool(ctx context.Context, serverID, toolName string, input any, requestID, sessionID string) (*ToolCallResponse, error) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
 
 	if requestID == "" {
-		requestID = generateRequestID()
+		var err error
+		if requestID, err = generateRequestID(c.Entropy); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.AuditLog == nil {
+		return c.callTool(ctx, serverID, toolName, input, requestID, sessionID)
+	}
+	start := time.Now()
+	resp, err := c.callTool(ctx, serverID, toolName, input, requestID, sessionID)
+	if auditErr := c.recordToolCall(serverID, toolName, input, requestID, sessionID, time.Since(start), resp, err); auditErr != nil {
+		return resp, &AuditError{Err: auditErr, Response: resp, CallErr: err}
+	}
+	return resp, err
+}
+
+func (c *McpClient) callTool(ctx context.Context, serverID, toolName string, input any, requestID, sessionID string) (*ToolCallResponse, error) {
+	if err := c.checkPolicy(ctx, serverID, toolName); err != nil {
+		return nil, err
 	}
 
 	// Manual deterministic URL assembly to avoid JoinPath normalization surprises
@@ -258,7 +377,16 @@ func (c *McpClient) CallTool(ctx context.Context, serverID, toolName string, inp
 		return nil, err
 	}
 
-	c.setHeaders(req, requestID)
+	if err := c.setHeaders(req, requestID); err != nil {
+		return nil, err
+	}
+	if c.Signer != nil {
+		if err := c.signRequest(req, bodyBytes); err != nil {
+			return nil, fmt.Errorf("failed to sign request: %w", err)
+		}
+	} else if c.Capability != "" {
+		req.Header.Set(HeaderCapability, c.Capability)
+	}
 
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
@@ -277,3 +405,76 @@ func (c *McpClient) CallTool(ctx context.Context, serverID, toolName string, inp
 
 	return &result, nil
 }
+
+// checkPolicy evaluates the client's policy, if any, for a tool call.
+func (c *McpClient) checkPolicy(ctx context.Context, serverID, toolName string) error {
+	if c.Policy == nil {
+		return nil
+	}
+	req := policy.Request{ServerID: serverID, ToolName: toolName, Caller: c.Caller}
+	if req.Caller == "" && c.Signer != nil {
+		req.Caller = c.Signer.DID()
+	}
+	if c.Policy.UsesTags() {
+		tags, known := c.lookupServerTags(serverID)
+		if !known {
+			// The server may have been added since the last listing.
+			if _, err := c.ListServers(ctx); err != nil {
+				return fmt.Errorf("failed to fetch server tags for policy: %w", err)
+			}
+			tags, known = c.lookupServerTags(serverID)
+		}
+		if !known {
+			// Without tags a deny rule on them cannot match, so refuse
+			// rather than risk allowing what the policy denies.
+			return errors.New(errors.CodeDenied, fmt.Sprintf("server %q is not listed by the gateway; its tags are unknown", serverID),
+				errors.WithDetails(map[string]interface{}{"reason": policy.ReasonPolicyDenied}))
+		}
+		req.Tags = tags
+	}
+	return c.Policy.Evaluate(req).Err()
+}
+
+func (c *McpClient) lookupServerTags(serverID string) (map[string]string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	tags, ok := c.serverTags[serverID]
+	return tags, ok
+}
+
+// recordToolCall writes an audit entry for a completed call. Input and
+// output are recorded as digests so the log does not retain payloads.
+func (c *McpClient) recordToolCall(serverID, toolName string, input any, requestID, sessionID string, elapsed time.Duration, resp *ToolCallResponse, callErr error) error {
+	data := map[string]any{
+		"server_id":   serverID,
+		"tool_name":   toolName,
+		"request_id":  requestID,
+		"duration_ms": elapsed.Milliseconds(),
+	}
+	if sessionID != "" {
+		data["session_id"] = sessionID
+	}
+	if b, err := canonical.Marshal(input); err == nil {
+		data["input_sha256"] = sha256Hex(b)
+	}
+	if callErr != nil {
+		data["outcome"] = "error"
+		data["error"] = callErr.Error()
+		if apiErr, ok := callErr.(*APIError); ok {
+			data["status"] = apiErr.Status
+		}
+	} else {
+		data["outcome"] = "ok"
+		data["output_sha256"] = sha256Hex(resp.Output)
+		if resp.AuditRef != "" {
+			data["audit_ref"] = resp.AuditRef
+		}
+	}
+	_, err := c.AuditLog.Append(audit.TypeToolCall, data)
+	return err
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
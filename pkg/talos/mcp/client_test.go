@@ -5,9 +5,16 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/audit"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/crypto/cryptotest"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/policy"
 )
 
 func TestCallTool_HappyPath(t *testing.T) {
@@ -181,3 +188,139 @@ func TestCallTool_Context(t *testing.T) {
 		t.Errorf("Expected context error, got %v", err)
 	}
 }
+
+func TestCallTool_AuditLog(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "fail") {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"error":{"code":"denied","message":"no"}}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(ToolCallResponse{Output: json.RawMessage(`{}`), AuditRef: "audit-1"})
+	}))
+	defer ts.Close()
+
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	log, err := audit.Open(path)
+	if err != nil {
+		t.Fatalf("audit.Open failed: %v", err)
+	}
+	client := NewClient(ts.URL, "sk", WithAuditLog(log))
+
+	if _, err := client.CallTool(context.Background(), "s", "ok", map[string]any{"secret": "x"}, "req-1", ""); err != nil {
+		t.Fatalf("CallTool failed: %v", err)
+	}
+	if _, err := client.CallTool(context.Background(), "s", "fail", nil, "req-2", ""); err == nil {
+		t.Fatal("Expected API error")
+	}
+	log.Close()
+
+	report, err := audit.VerifyFile(path)
+	if err != nil || report.Entries != 2 {
+		t.Fatalf("Expected 2 verified entries, got %+v (%v)", report, err)
+	}
+	b, _ := os.ReadFile(path)
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	var first, second audit.Entry
+	_ = json.Unmarshal([]byte(lines[0]), &first)
+	_ = json.Unmarshal([]byte(lines[1]), &second)
+	if !strings.Contains(string(first.Data), `"audit_ref":"audit-1"`) || !strings.Contains(string(first.Data), `"outcome":"ok"`) {
+		t.Errorf("Unexpected first entry: %s", first.Data)
+	}
+	if strings.Contains(string(first.Data), "secret") {
+		t.Errorf("Tool input leaked into audit log: %s", first.Data)
+	}
+	if !strings.Contains(string(second.Data), `"status":403`) {
+		t.Errorf("Unexpected second entry: %s", second.Data)
+	}
+
+	// With the log unwritable the result of the call is still returned.
+	resp, err := client.CallTool(context.Background(), "s", "ok", nil, "req-3", "")
+	auditErr, ok := err.(*AuditError)
+	if !ok || resp == nil || auditErr.Response != resp || resp.AuditRef != "audit-1" {
+		t.Errorf("Expected response with audit error, got %v, %v", resp, err)
+	}
+}
+
+func TestCallTool_DeterministicRequestID(t *testing.T) {
+	var ids []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ids = append(ids, r.Header.Get("X-Request-Id"))
+		_ = json.NewEncoder(w).Encode(ToolCallResponse{})
+	}))
+	defer ts.Close()
+
+	for i := 0; i < 2; i++ {
+		client := NewClient(ts.URL, "sk", WithEntropy(cryptotest.NewReader([]byte("fixed"))))
+		if _, err := client.CallTool(context.Background(), "s", "t", nil, "", ""); err != nil {
+			t.Fatalf("CallTool failed: %v", err)
+		}
+	}
+	if len(ids) != 2 || ids[0] == "" || ids[0] != ids[1] {
+		t.Errorf("Expected identical generated request IDs, got %v", ids)
+	}
+
+	// An exhausted entropy source fails the call instead of repeating IDs.
+	client := NewClient(ts.URL, "sk", WithEntropy(strings.NewReader("short")))
+	if _, err := client.CallTool(context.Background(), "s", "t", nil, "", ""); err == nil {
+		t.Error("Expected error from exhausted entropy source")
+	}
+	if len(ids) != 2 {
+		t.Error("Request was sent without a request ID")
+	}
+}
+
+func TestCallTool_Policy(t *testing.T) {
+	var calls, listings int
+	servers := []Server{
+		{ID: "db-prod", Tags: map[string]string{"env": "prod"}},
+		{ID: "db-dev", Tags: map[string]string{"env": "dev"}},
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/mcp/servers" {
+			listings++
+			_ = json.NewEncoder(w).Encode(map[string]any{"servers": servers})
+			return
+		}
+		calls++
+		_ = json.NewEncoder(w).Encode(ToolCallResponse{})
+	}))
+	defer ts.Close()
+
+	engine, err := policy.New(policy.Policy{Rules: []policy.Rule{
+		{ID: "read", Effect: policy.Allow, Tools: []string{"query"}},
+		{ID: "no-prod", Effect: policy.Deny, Tags: map[string]string{"env": "prod"}, Callers: []string{"did:key:zIntern"}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewClient(ts.URL, "sk", WithPolicy(engine, "did:key:zIntern"))
+
+	if _, err := client.CallTool(context.Background(), "db-dev", "query", nil, "", ""); err != nil {
+		t.Fatalf("CallTool failed: %v", err)
+	}
+	for _, tc := range []struct{ server, tool string }{{"db-prod", "query"}, {"db-dev", "drop"}} {
+		_, err := client.CallTool(context.Background(), tc.server, tc.tool, nil, "", "")
+		te, ok := err.(*errors.TalosError)
+		if !ok || te.Code != errors.CodeDenied {
+			t.Errorf("Expected policy denial for %s/%s, got %v", tc.server, tc.tool, err)
+		}
+	}
+	if calls != 1 || listings != 1 {
+		t.Errorf("Expected 1 call and 1 listing, got %d and %d", calls, listings)
+	}
+
+	// A server registered after the first listing is looked up again, and
+	// one the gateway does not list at all is refused.
+	servers = append(servers, Server{ID: "db-prod-2", Tags: map[string]string{"env": "prod"}})
+	for _, server := range []string{"db-prod-2", "db-unknown"} {
+		_, err := client.CallTool(context.Background(), server, "query", nil, "", "")
+		te, ok := err.(*errors.TalosError)
+		if !ok || te.Code != errors.CodeDenied {
+			t.Errorf("Expected policy denial for %s, got %v", server, err)
+		}
+	}
+	if calls != 1 || listings != 3 {
+		t.Errorf("Expected 1 call and 3 listings, got %d and %d", calls, listings)
+	}
+}
@@ -0,0 +1,253 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/canonical"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/capability"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/replay"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/wallet"
+)
+
+// Headers carried by signed tool call requests.
+const (
+	HeaderSigner        = "X-Talos-Signer"
+	HeaderTimestamp     = "X-Talos-Timestamp"
+	HeaderNonce         = "X-Talos-Nonce"
+	HeaderContentSHA256 = "X-Talos-Content-Sha256"
+	HeaderSignature     = "X-Talos-Signature"
+	HeaderCapability    = "X-Talos-Capability"
+)
+
+// requestSigningContext is the Ed25519ctx context for request signatures.
+const requestSigningContext = "talos-mcp-request-v1"
+
+// Reasons reported when a signed request is rejected.
+const (
+	ReasonMissingSignature  = "missing_signature"
+	ReasonDigestMismatch    = "digest_mismatch"
+	ReasonBadSignature      = "bad_signature"
+	ReasonMissingCapability = "missing_capability"
+	ReasonPathMismatch      = "path_mismatch"
+)
+
+// signedRequest is what a request signature covers, serialized with
+// canonical.Marshal. Binding the capability digest stops a token from being
+// swapped onto someone else's signed request.
+type signedRequest struct {
+	Method           string `json:"method"`
+	Path             string `json:"path"`
+	Timestamp        int64  `json:"ts"`
+	Nonce            string `json:"nonce"`
+	BodySHA256       string `json:"body_sha256"`
+	CapabilitySHA256 string `json:"capability_sha256,omitempty"`
+}
+
+func (s *signedRequest) bytes() ([]byte, error) {
+	return canonical.Marshal(s)
+}
+
+// WithSigner signs every tool call with w: the digest of the exact body
+// bytes, a timestamp and a nonce are signed with Ed25519ctx and carried in
+// X-Talos-* headers. The body is sent as encoded by encoding/json, so the
+// input reaches the tool unchanged. The signer's DID is also used as the
+// policy caller unless WithPolicy names one.
+func WithSigner(w *wallet.Wallet) Option {
+	return func(c *McpClient) {
+		c.Signer = w
+	}
+}
+
+// WithCapability attaches an encoded capability token to every tool call.
+// The gateway authorizes the call against it for the signing DID, so it is
+// normally combined with WithSigner.
+func WithCapability(token string) Option {
+	return func(c *McpClient) {
+		c.Capability = token
+	}
+}
+
+// signRequest adds the signature headers for body to req.
+func (c *McpClient) signRequest(req *http.Request, body []byte) error {
+	nonce := make([]byte, 16)
+	r := c.Entropy
+	if r == nil {
+		r = rand.Reader
+	}
+	if _, err := io.ReadFull(r, nonce); err != nil {
+		return errors.New(errors.CodeCryptoError, "failed to generate nonce", errors.WithCause(err))
+	}
+	s := signedRequest{
+		Method:     req.Method,
+		Path:       req.URL.EscapedPath(),
+		Timestamp:  time.Now().Unix(),
+		Nonce:      base64.RawURLEncoding.EncodeToString(nonce),
+		BodySHA256: sha256Hex(body),
+	}
+	if c.Capability != "" {
+		s.CapabilitySHA256 = sha256Hex([]byte(c.Capability))
+		req.Header.Set(HeaderCapability, c.Capability)
+	}
+	msg, err := s.bytes()
+	if err != nil {
+		return err
+	}
+	sig, err := c.Signer.SignWithOptions(msg, wallet.SignOptions{
+		Variant: wallet.VariantEd25519ctx,
+		Context: requestSigningContext,
+	})
+	if err != nil {
+		return err
+	}
+	req.Header.Set(HeaderSigner, c.Signer.DID())
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(s.Timestamp, 10))
+	req.Header.Set(HeaderNonce, s.Nonce)
+	req.Header.Set(HeaderContentSHA256, s.BodySHA256)
+	req.Header.Set(HeaderSignature, base64.RawURLEncoding.EncodeToString(sig))
+	return nil
+}
+
+// VerifiedCall is a tool call request whose signature has been checked.
+type VerifiedCall struct {
+	Signer  string
+	Request ToolCallRequest
+	// Capability is the verified capability, or nil if the verifier has no
+	// capability verifier.
+	Capability *capability.Capability
+}
+
+// RequestVerifier checks signed tool call requests on the gateway side.
+type RequestVerifier struct {
+	guard *replay.Guard
+	caps  *capability.Verifier
+}
+
+// NewRequestVerifier creates a verifier. guard rejects replayed or stale
+// requests; nil means a replay.NewGuard with defaults. If caps is non-nil,
+// every request must carry a capability that authorizes the signer for the
+// call.
+func NewRequestVerifier(guard *replay.Guard, caps *capability.Verifier) *RequestVerifier {
+	if guard == nil {
+		guard = replay.NewGuard()
+	}
+	return &RequestVerifier{guard: guard, caps: caps}
+}
+
+// Verify checks the signature headers of r against body, the request body
+// as received. The server and tool in the signed path must match those in
+// the body. Rejections are TalosErrors: CodeCryptoError for missing or bad
+// signatures and mismatched paths, CodeReplayDetected from the guard, and
+// the capability verifier's codes.
+func (v *RequestVerifier) Verify(ctx context.Context, r *http.Request, body []byte) (*VerifiedCall, error) {
+	signer := r.Header.Get(HeaderSigner)
+	sigB64 := r.Header.Get(HeaderSignature)
+	ts, tsErr := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+	s := signedRequest{
+		Method:     r.Method,
+		Path:       r.URL.EscapedPath(),
+		Timestamp:  ts,
+		Nonce:      r.Header.Get(HeaderNonce),
+		BodySHA256: r.Header.Get(HeaderContentSHA256),
+	}
+	if signer == "" || sigB64 == "" || tsErr != nil || s.Nonce == "" || s.BodySHA256 == "" {
+		return nil, rejectRequest(ReasonMissingSignature, "request is not signed")
+	}
+	if sha256Hex(body) != s.BodySHA256 {
+		return nil, rejectRequest(ReasonDigestMismatch, "request body does not match its digest")
+	}
+	token := r.Header.Get(HeaderCapability)
+	if token != "" {
+		s.CapabilitySHA256 = sha256Hex([]byte(token))
+	}
+
+	pub, err := wallet.PublicKeyFromDID(signer)
+	if err != nil {
+		return nil, rejectRequest(ReasonBadSignature, "request signer is not a valid DID")
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(sigB64)
+	if err != nil {
+		return nil, rejectRequest(ReasonBadSignature, "request signature is not valid base64url")
+	}
+	msg, err := s.bytes()
+	if err != nil {
+		return nil, err
+	}
+	if !wallet.VerifyWithOptions(pub, msg, sig, wallet.SignOptions{
+		Variant: wallet.VariantEd25519ctx,
+		Context: requestSigningContext,
+	}) {
+		return nil, rejectRequest(ReasonBadSignature, "request signature does not verify")
+	}
+	if err := v.guard.Check(ctx, signer, s.Nonce, time.Unix(ts, 0)); err != nil {
+		return nil, err
+	}
+
+	out := &VerifiedCall{Signer: signer}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&out.Request); err != nil {
+		return nil, errors.New(errors.CodeInvalidInput, "request body is not a tool call", errors.WithCause(err))
+	}
+	// The gateway routes on the path, so the server and tool the signer
+	// named there must be the ones the body, and the capability, refer to.
+	serverID, toolName, ok := parseCallPath(s.Path)
+	if !ok || serverID != out.Request.ServerID || toolName != out.Request.ToolName {
+		return nil, rejectRequest(ReasonPathMismatch, "request path does not match the tool call in its body")
+	}
+	if v.caps == nil {
+		return out, nil
+	}
+	if token == "" {
+		return nil, errors.New(errors.CodeDenied, "request carries no capability",
+			errors.WithDetails(map[string]interface{}{"reason": ReasonMissingCapability}))
+	}
+	if out.Capability, err = capability.Parse(token); err != nil {
+		return nil, err
+	}
+	err = v.caps.VerifyContext(ctx, out.Capability, capability.Request{
+		Caller:   signer,
+		ServerID: out.Request.ServerID,
+		ToolName: out.Request.ToolName,
+		Input:    out.Request.Input,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// parseCallPath extracts the server and tool from an escaped tool call path,
+// .../servers/{server}/tools/{tool}:call. Escaping keeps '/' and ':' out of
+// the components, so the last "/servers/" starts them.
+func parseCallPath(p string) (serverID, toolName string, ok bool) {
+	i := strings.LastIndex(p, "/servers/")
+	if i < 0 {
+		return "", "", false
+	}
+	parts := strings.Split(p[i+len("/servers/"):], "/")
+	if len(parts) != 3 || parts[1] != "tools" || !strings.HasSuffix(parts[2], ":call") {
+		return "", "", false
+	}
+	serverID, err1 := url.PathUnescape(parts[0])
+	toolName, err2 := url.PathUnescape(strings.TrimSuffix(parts[2], ":call"))
+	if err1 != nil || err2 != nil {
+		return "", "", false
+	}
+	return serverID, toolName, true
+}
+
+func rejectRequest(reason, message string) error {
+	return errors.New(errors.CodeCryptoError, message,
+		errors.WithDetails(map[string]interface{}{"reason": reason}))
+}
@@ -0,0 +1,155 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/capability"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/wallet"
+)
+
+func TestCallTool_Signed(t *testing.T) {
+	root, _ := wallet.FromSeed(bytes.Repeat([]byte{1}, 32), "root")
+	agent, _ := wallet.FromSeed(bytes.Repeat([]byte{2}, 32), "agent")
+	c, err := capability.Mint(root, capability.Capability{
+		Subject:     agent.DID(),
+		Servers:     []string{"fs"},
+		Tools:       []string{"read"},
+		ExpiresAt:   time.Now().Add(time.Hour).Unix(),
+		Constraints: []capability.Constraint{capability.PathPrefix("$.path", "/srv")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, _ := c.Encode()
+
+	verifier := NewRequestVerifier(nil, capability.NewVerifier(capability.WithTrustedIssuers(root.DID())))
+	var last *http.Request
+	var lastBody []byte
+	verify := true
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		last, lastBody = r, body
+		if !verify {
+			_ = json.NewEncoder(w).Encode(ToolCallResponse{})
+			return
+		}
+		call, err := verifier.Verify(r.Context(), r, body)
+		if err != nil {
+			w.WriteHeader(http.StatusForbidden)
+			_ = json.NewEncoder(w).Encode(map[string]any{"error": map[string]string{"code": "denied", "message": err.Error()}})
+			return
+		}
+		if call.Signer != agent.DID() || call.Capability == nil {
+			t.Errorf("Unexpected verified call: %+v", call)
+		}
+		_ = json.NewEncoder(w).Encode(ToolCallResponse{Output: json.RawMessage(`{}`)})
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "", WithSigner(agent), WithCapability(token))
+	if _, err := client.CallTool(context.Background(), "fs", "read", map[string]any{"path": "/srv/a"}, "req-1", ""); err != nil {
+		t.Fatalf("CallTool failed: %v", err)
+	}
+	if last.Header.Get("Authorization") != "" {
+		t.Error("Expected no bearer token without an API key")
+	}
+
+	// The capability's constraints are enforced on the input.
+	if _, err := client.CallTool(context.Background(), "fs", "read", map[string]any{"path": "/etc/passwd"}, "req-2", ""); err == nil {
+		t.Error("Expected constraint violation")
+	}
+
+	// Replaying a captured request is rejected.
+	if _, err := verifier.Verify(context.Background(), last, lastBody); !hasCode(err, errors.CodeReplayDetected) {
+		t.Errorf("Expected replay rejection, got %v", err)
+	}
+
+	// Tampering with the body, or swapping the capability, breaks
+	// verification. These requests are captured unverified so that each
+	// still has a fresh nonce.
+	verify = false
+	fresh := func() *http.Request {
+		if _, err := client.CallTool(context.Background(), "fs", "read", map[string]any{"path": "/srv/b"}, "", ""); err != nil {
+			t.Fatalf("CallTool failed: %v", err)
+		}
+		return last
+	}
+	req := fresh()
+	tampered := bytes.Replace(lastBody, []byte("/srv/b"), []byte("/srv/c"), 1)
+	if _, err := verifier.Verify(context.Background(), req, tampered); !hasReason(err, ReasonDigestMismatch) {
+		t.Errorf("Expected digest mismatch, got %v", err)
+	}
+	req = fresh()
+	other, _ := capability.Mint(root, capability.Capability{
+		Subject: agent.DID(), Servers: []string{"*"}, Tools: []string{"*"}, ExpiresAt: time.Now().Add(time.Hour).Unix(),
+	})
+	otherToken, _ := other.Encode()
+	req.Header.Set(HeaderCapability, otherToken)
+	if _, err := verifier.Verify(context.Background(), req, lastBody); !hasReason(err, ReasonBadSignature) {
+		t.Errorf("Expected bad signature, got %v", err)
+	}
+
+	// A signature over a path routing to another tool does not borrow the
+	// capability for the tool named in the body.
+	body, _ := json.Marshal(ToolCallRequest{ServerID: "fs", ToolName: "read", Input: map[string]any{"path": "/srv/a"}})
+	req, _ = http.NewRequest("POST", ts.URL+"/v1/mcp/servers/fs/tools/write:call", bytes.NewReader(body))
+	if err := client.signRequest(req, body); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifier.Verify(context.Background(), req, body); !hasReason(err, ReasonPathMismatch) {
+		t.Errorf("Expected path mismatch, got %v", err)
+	}
+
+	// Unsigned requests are refused.
+	req = fresh()
+	req.Header.Del(HeaderSignature)
+	if _, err := verifier.Verify(context.Background(), req, lastBody); !hasReason(err, ReasonMissingSignature) {
+		t.Errorf("Expected missing signature, got %v", err)
+	}
+}
+
+func TestCallTool_SignedBodyPreservesNumbers(t *testing.T) {
+	agent, _ := wallet.FromSeed(bytes.Repeat([]byte{2}, 32), "agent")
+	verifier := NewRequestVerifier(nil, nil)
+	var got ToolCallRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		call, err := verifier.Verify(r.Context(), r, body)
+		if err != nil {
+			t.Errorf("Verify failed: %v", err)
+		} else {
+			got = call.Request
+		}
+		_ = json.NewEncoder(w).Encode(ToolCallResponse{})
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "", WithSigner(agent))
+	input := map[string]any{"big": uint64(9007199254740993), "max": uint64(math.MaxUint64)}
+	if _, err := client.CallTool(context.Background(), "s", "t", input, "", ""); err != nil {
+		t.Fatalf("CallTool failed: %v", err)
+	}
+	in, _ := got.Input.(map[string]any)
+	if in["big"] != json.Number("9007199254740993") || in["max"] != json.Number("18446744073709551615") {
+		t.Errorf("Input changed in transit: %v", got.Input)
+	}
+}
+
+func hasCode(err error, code errors.TalosErrorCode) bool {
+	te, ok := err.(*errors.TalosError)
+	return ok && te.Code == code
+}
+
+func hasReason(err error, reason string) bool {
+	te, ok := err.(*errors.TalosError)
+	return ok && te.Details["reason"] == reason
+}
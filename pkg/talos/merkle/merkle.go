@@ -0,0 +1,188 @@
+// Package merkle implements the RFC 6962 (Certificate Transparency) Merkle
+// tree: domain-separated leaf and node hashing, and generation and
+// verification of inclusion and consistency proofs.
+//
+// A gateway that batches audit records into such a tree can hand clients an
+// InclusionProof for the record behind ToolCallResponse.AuditRef, and
+// consistency proofs between the signed roots it publishes over time.
+package merkle
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+)
+
+// HashSize is the size of every hash in the tree.
+const HashSize = sha256.Size
+
+const (
+	leafPrefix = 0x00
+	nodePrefix = 0x01
+)
+
+// Reasons reported in Details["reason"] when a proof is rejected.
+const (
+	ReasonOutOfRange     = "out_of_range"
+	ReasonProofLength    = "wrong_proof_length"
+	ReasonRootMismatch   = "root_mismatch"
+	ReasonMalformedProof = "malformed_proof"
+)
+
+// EmptyRoot is the root of the tree with no leaves, SHA-256 of the empty
+// string.
+func EmptyRoot() []byte {
+	sum := sha256.Sum256(nil)
+	return sum[:]
+}
+
+// LeafHash returns SHA-256(0x00 || data).
+func LeafHash(data []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{leafPrefix})
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+// NodeHash returns SHA-256(0x01 || left || right).
+func NodeHash(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{nodePrefix})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// InclusionProof proves that a leaf is at LeafIndex in the tree of TreeSize
+// leaves.
+type InclusionProof struct {
+	LeafIndex uint64   `json:"leaf_index"`
+	TreeSize  uint64   `json:"tree_size"`
+	Hashes    [][]byte `json:"audit_path"`
+}
+
+// Verify checks the proof for the given leaf data against root.
+func (p *InclusionProof) Verify(leaf, root []byte) error {
+	return VerifyInclusion(LeafHash(leaf), p.LeafIndex, p.TreeSize, p.Hashes, root)
+}
+
+// ConsistencyProof proves that the tree of FirstSize leaves is a prefix of
+// the tree of SecondSize leaves.
+type ConsistencyProof struct {
+	FirstSize  uint64   `json:"first_size"`
+	SecondSize uint64   `json:"second_size"`
+	Hashes     [][]byte `json:"consistency_path"`
+}
+
+// Verify checks the proof against the roots of both trees.
+func (p *ConsistencyProof) Verify(firstRoot, secondRoot []byte) error {
+	return VerifyConsistency(p.FirstSize, p.SecondSize, p.Hashes, firstRoot, secondRoot)
+}
+
+// VerifyInclusion checks that leafHash is at index in the tree of size
+// leaves with the given root (RFC 9162, Section 2.1.3.2).
+func VerifyInclusion(leafHash []byte, index, size uint64, proof [][]byte, root []byte) error {
+	if index >= size {
+		return reject(ReasonOutOfRange, fmt.Sprintf("leaf index %d not below tree size %d", index, size))
+	}
+	if err := checkHashes(proof); err != nil {
+		return err
+	}
+
+	fn, sn := index, size-1
+	r := leafHash
+	for _, p := range proof {
+		if sn == 0 {
+			return reject(ReasonProofLength, "inclusion proof too long")
+		}
+		if fn&1 == 1 || fn == sn {
+			r = NodeHash(p, r)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			r = NodeHash(r, p)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	if sn != 0 || !equal(r, root) {
+		return reject(ReasonRootMismatch, "inclusion proof does not match root")
+	}
+	return nil
+}
+
+// VerifyConsistency checks that the tree of size1 leaves with root1 is a
+// prefix of the tree of size2 leaves with root2 (RFC 9162, Section 2.1.4.2).
+func VerifyConsistency(size1, size2 uint64, proof [][]byte, root1, root2 []byte) error {
+	if size1 > size2 {
+		return reject(ReasonOutOfRange, fmt.Sprintf("first size %d exceeds second size %d", size1, size2))
+	}
+	if err := checkHashes(proof); err != nil {
+		return err
+	}
+	if size1 == size2 || size1 == 0 {
+		if len(proof) != 0 {
+			return reject(ReasonProofLength, "expected an empty consistency proof")
+		}
+		if size1 == size2 && !equal(root1, root2) {
+			return reject(ReasonRootMismatch, "roots of equal-size trees differ")
+		}
+		return nil
+	}
+
+	if size1&(size1-1) == 0 {
+		proof = append([][]byte{root1}, proof...)
+	}
+	if len(proof) == 0 {
+		return reject(ReasonProofLength, "consistency proof is empty")
+	}
+	fn, sn := size1-1, size2-1
+	for fn&1 == 1 {
+		fn >>= 1
+		sn >>= 1
+	}
+	fr, sr := proof[0], proof[0]
+	for _, c := range proof[1:] {
+		if sn == 0 {
+			return reject(ReasonProofLength, "consistency proof too long")
+		}
+		if fn&1 == 1 || fn == sn {
+			fr = NodeHash(c, fr)
+			sr = NodeHash(c, sr)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			sr = NodeHash(sr, c)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	if sn != 0 || !equal(fr, root1) || !equal(sr, root2) {
+		return reject(ReasonRootMismatch, "consistency proof does not match roots")
+	}
+	return nil
+}
+
+func checkHashes(proof [][]byte) error {
+	for _, h := range proof {
+		if len(h) != HashSize {
+			return reject(ReasonMalformedProof, fmt.Sprintf("proof hash must be %d bytes", HashSize))
+		}
+	}
+	return nil
+}
+
+func equal(a, b []byte) bool {
+	return len(a) == HashSize && subtle.ConstantTimeCompare(a, b) == 1
+}
+
+func reject(reason, message string) error {
+	return errors.New(errors.CodeCryptoError, message,
+		errors.WithDetails(map[string]interface{}{"reason": reason}))
+}
@@ -0,0 +1,170 @@
+package merkle
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+)
+
+// Leaves and roots from the certificate-transparency reference tests.
+var (
+	ctLeaves = []string{"", "00", "10", "2021", "3031", "40414243", "5051525354555657", "606162636465666768696a6b6c6d6e6f"}
+	ctRoots  = []string{
+		"6e340b9cffb37a989ca544e6bb780a2c78901d3fb33738768511a30617afa01d",
+		"fac54203e7cc696cf0dfcb42c92a1d9dbaf70ad9e621f4bd8d98662f00e3c125",
+		"aeb6bcfe274b70a14fb067a5e5578264db0fa9b51af5e0ba159158f329e06e77",
+		"d37ee418976dd95753c1c73862b9398fa2a2cf9b4ff0fdfe8b30cd95209614b7",
+		"4e3bbb1f7b478dcfe71fb631631519a3bca12c9aefca1612bfce4c13a86264d4",
+		"76e67dadbcdf1e10e1b74ddc608abd2f98dfb16fbce75277b5232a127f2087ef",
+		"ddb89be403809e325750d3d263cd78929c2942b7942a34b77e122c9594a74c8c",
+		"5dc9da79a70659a9ad559cb701ded9a2ab9d823aad2f4960cfe370eff4604328",
+	}
+)
+
+func reason(err error) string {
+	if te, ok := err.(*errors.TalosError); ok {
+		r, _ := te.Details["reason"].(string)
+		return r
+	}
+	return ""
+}
+
+func TestRootVectors(t *testing.T) {
+	if hex.EncodeToString(EmptyRoot()) != "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855" {
+		t.Error("unexpected empty root")
+	}
+	tree := NewTree()
+	for i, l := range ctLeaves {
+		data, _ := hex.DecodeString(l)
+		if idx := tree.Append(data); idx != uint64(i) {
+			t.Fatalf("expected index %d, got %d", i, idx)
+		}
+		if got := hex.EncodeToString(tree.Root()); got != ctRoots[i] {
+			t.Errorf("size %d: expected root %s, got %s", i+1, ctRoots[i], got)
+		}
+	}
+	for i := range ctRoots {
+		root, _ := tree.RootAt(uint64(i + 1))
+		if hex.EncodeToString(root) != ctRoots[i] {
+			t.Errorf("RootAt(%d) mismatch", i+1)
+		}
+	}
+}
+
+func TestInclusionProofs(t *testing.T) {
+	tree := NewTree()
+	for i := 0; i < 40; i++ {
+		tree.Append([]byte(fmt.Sprintf("leaf-%d", i)))
+	}
+	for size := uint64(1); size <= tree.Size(); size++ {
+		root, _ := tree.RootAt(size)
+		for index := uint64(0); index < size; index++ {
+			p, err := tree.InclusionProof(index, size)
+			if err != nil {
+				t.Fatalf("InclusionProof(%d, %d): %v", index, size, err)
+			}
+			leaf := []byte(fmt.Sprintf("leaf-%d", index))
+			if err := p.Verify(leaf, root); err != nil {
+				t.Fatalf("Verify(%d, %d): %v", index, size, err)
+			}
+			if err := p.Verify([]byte("other"), root); reason(err) != ReasonRootMismatch {
+				t.Fatalf("(%d, %d): wrong leaf accepted: %v", index, size, err)
+			}
+			if len(p.Hashes) > 0 {
+				short := p.Hashes[:len(p.Hashes)-1]
+				if VerifyInclusion(LeafHash(leaf), index, size, short, root) == nil {
+					t.Fatalf("(%d, %d): truncated proof accepted", index, size)
+				}
+			}
+			long := append(append([][]byte{}, p.Hashes...), root)
+			if VerifyInclusion(LeafHash(leaf), index, size, long, root) == nil {
+				t.Fatalf("(%d, %d): extended proof accepted", index, size)
+			}
+		}
+	}
+
+	if _, err := tree.InclusionProof(5, 5); err == nil {
+		t.Error("expected error for index outside tree")
+	}
+	if err := VerifyInclusion(LeafHash(nil), 3, 3, nil, tree.Root()); reason(err) != ReasonOutOfRange {
+		t.Errorf("expected %s, got %v", ReasonOutOfRange, err)
+	}
+}
+
+func TestConsistencyProofs(t *testing.T) {
+	tree := NewTree()
+	for i := 0; i < 33; i++ {
+		tree.Append([]byte{byte(i)})
+	}
+	for size2 := uint64(0); size2 <= tree.Size(); size2++ {
+		root2, _ := tree.RootAt(size2)
+		for size1 := uint64(0); size1 <= size2; size1++ {
+			root1, _ := tree.RootAt(size1)
+			p, err := tree.ConsistencyProof(size1, size2)
+			if err != nil {
+				t.Fatalf("ConsistencyProof(%d, %d): %v", size1, size2, err)
+			}
+			if err := p.Verify(root1, root2); err != nil {
+				t.Fatalf("Verify(%d, %d): %v", size1, size2, err)
+			}
+			if size1 > 0 && size1 < size2 {
+				bad := bytes.Repeat([]byte{1}, HashSize)
+				if p.Verify(bad, root2) == nil || p.Verify(root1, bad) == nil {
+					t.Fatalf("(%d, %d): wrong root accepted", size1, size2)
+				}
+				if len(p.Hashes) > 0 && VerifyConsistency(size1, size2, p.Hashes[1:], root1, root2) == nil {
+					t.Fatalf("(%d, %d): truncated proof accepted", size1, size2)
+				}
+			}
+		}
+	}
+
+	// A rewritten history is not consistent with the published root.
+	forked := NewTree()
+	for i := 0; i < 33; i++ {
+		b := byte(i)
+		if i == 3 {
+			b = 0xff
+		}
+		forked.Append([]byte{b})
+	}
+	old, _ := tree.RootAt(10)
+	p, _ := forked.ConsistencyProof(10, 33)
+	if err := p.Verify(old, forked.Root()); reason(err) != ReasonRootMismatch {
+		t.Errorf("expected %s for forked tree, got %v", ReasonRootMismatch, err)
+	}
+
+	if _, err := tree.ConsistencyProof(5, 40); err == nil {
+		t.Error("expected error for size beyond tree")
+	}
+}
+
+func TestTreeReturnsCopies(t *testing.T) {
+	tree := NewTree()
+	for _, l := range ctLeaves {
+		b, _ := hex.DecodeString(l)
+		tree.Append(b)
+	}
+	want := tree.Root()
+	scribble := func(hashes ...[]byte) {
+		for _, h := range hashes {
+			for i := range h {
+				h[i] ^= 0xff
+			}
+		}
+	}
+	scribble(tree.Root())
+	if r, _ := tree.RootAt(4); r != nil {
+		scribble(r)
+	}
+	ip, _ := tree.InclusionProof(4, 8)
+	scribble(ip.Hashes...)
+	cp, _ := tree.ConsistencyProof(4, 8)
+	scribble(cp.Hashes...)
+	if !bytes.Equal(tree.Root(), want) {
+		t.Fatal("mutating returned hashes changed the tree")
+	}
+}
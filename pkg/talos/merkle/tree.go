@@ -0,0 +1,172 @@
+package merkle
+
+import (
+	"fmt"
+	"math/bits"
+	"sync"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+)
+
+// Tree is an append-only, in-memory Merkle tree. It keeps the hash of every
+// complete subtree, so roots and proofs for any past size cost O(log n)
+// hashes. A Tree is safe for concurrent use.
+type Tree struct {
+	mu sync.RWMutex
+	// levels[l][i] is the hash of the complete subtree of 2^l leaves
+	// starting at leaf i*2^l.
+	levels [][][]byte
+}
+
+// NewTree returns an empty tree.
+func NewTree() *Tree {
+	return &Tree{}
+}
+
+// Append adds a leaf and returns its index.
+func (t *Tree) Append(data []byte) uint64 {
+	return t.AppendHash(LeafHash(data))
+}
+
+// AppendHash adds a precomputed leaf hash and returns its index.
+func (t *Tree) AppendHash(leafHash []byte) uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.levels) == 0 {
+		t.levels = append(t.levels, nil)
+	}
+	index := uint64(len(t.levels[0]))
+	h := append([]byte(nil), leafHash...)
+	t.levels[0] = append(t.levels[0], h)
+	// Complete every subtree this leaf closes.
+	for l := 0; len(t.levels[l])%2 == 0; l++ {
+		if l+1 == len(t.levels) {
+			t.levels = append(t.levels, nil)
+		}
+		n := len(t.levels[l])
+		h = NodeHash(t.levels[l][n-2], t.levels[l][n-1])
+		t.levels[l+1] = append(t.levels[l+1], h)
+	}
+	return index
+}
+
+// Size returns the number of leaves.
+func (t *Tree) Size() uint64 {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.size()
+}
+
+// LeafHash returns the hash of the leaf at index.
+func (t *Tree) LeafHash(index uint64) ([]byte, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if index >= t.size() {
+		return nil, outOfRange("leaf index %d not below tree size %d", index, t.size())
+	}
+	return append([]byte(nil), t.levels[0][index]...), nil
+}
+
+// Root returns the current root.
+func (t *Tree) Root() []byte {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.size() == 0 {
+		return EmptyRoot()
+	}
+	return t.hash(0, t.size())
+}
+
+// RootAt returns the root of the tree as it was with size leaves.
+func (t *Tree) RootAt(size uint64) ([]byte, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if size > t.size() {
+		return nil, outOfRange("size %d exceeds tree size %d", size, t.size())
+	}
+	if size == 0 {
+		return EmptyRoot(), nil
+	}
+	return t.hash(0, size), nil
+}
+
+// InclusionProof returns the audit path for the leaf at index in the tree
+// of size leaves (RFC 6962, Section 2.1.1).
+func (t *Tree) InclusionProof(index, size uint64) (*InclusionProof, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if size > t.size() || index >= size {
+		return nil, outOfRange("no leaf %d in tree of size %d", index, size)
+	}
+	return &InclusionProof{LeafIndex: index, TreeSize: size, Hashes: t.path(index, 0, size)}, nil
+}
+
+// ConsistencyProof returns the proof that the tree of size1 leaves is a
+// prefix of the tree of size2 leaves (RFC 6962, Section 2.1.2).
+func (t *Tree) ConsistencyProof(size1, size2 uint64) (*ConsistencyProof, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if size1 > size2 || size2 > t.size() {
+		return nil, outOfRange("invalid consistency range %d..%d for tree size %d", size1, size2, t.size())
+	}
+	p := &ConsistencyProof{FirstSize: size1, SecondSize: size2}
+	if size1 > 0 && size1 < size2 {
+		p.Hashes = t.subproof(size1, 0, size2, true)
+	}
+	return p, nil
+}
+
+func (t *Tree) size() uint64 {
+	if len(t.levels) == 0 {
+		return 0
+	}
+	return uint64(len(t.levels[0]))
+}
+
+// hash returns MTH(D[start:start+n]). Stored hashes are copied, since the
+// result reaches callers through roots and proofs.
+func (t *Tree) hash(start, n uint64) []byte {
+	if n&(n-1) == 0 && start%n == 0 {
+		l := bits.TrailingZeros64(n)
+		return append([]byte(nil), t.levels[l][start>>uint(l)]...)
+	}
+	k := split(n)
+	return NodeHash(t.hash(start, k), t.hash(start+k, n-k))
+}
+
+// path returns PATH(m, D[start:start+n]).
+func (t *Tree) path(m, start, n uint64) [][]byte {
+	if n == 1 {
+		return nil
+	}
+	k := split(n)
+	if m < k {
+		return append(t.path(m, start, k), t.hash(start+k, n-k))
+	}
+	return append(t.path(m-k, start+k, n-k), t.hash(start, k))
+}
+
+// subproof returns SUBPROOF(m, D[start:start+n], b).
+func (t *Tree) subproof(m, start, n uint64, b bool) [][]byte {
+	if m == n {
+		if b {
+			return nil
+		}
+		return [][]byte{t.hash(start, n)}
+	}
+	k := split(n)
+	if m <= k {
+		return append(t.subproof(m, start, k, b), t.hash(start+k, n-k))
+	}
+	return append(t.subproof(m-k, start+k, n-k, false), t.hash(start, k))
+}
+
+// split returns the largest power of two smaller than n, for n > 1.
+func split(n uint64) uint64 {
+	return 1 << uint(bits.Len64(n-1)-1)
+}
+
+func outOfRange(format string, args ...interface{}) error {
+	return errors.New(errors.CodeInvalidInput, fmt.Sprintf(format, args...),
+		errors.WithDetails(map[string]interface{}{"reason": ReasonOutOfRange}))
+}
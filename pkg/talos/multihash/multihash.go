@@ -0,0 +1,272 @@
+// Package multihash provides a registry of hash algorithms and the
+// multihash encoding (varint code || varint length || digest), so that
+// protocol objects can declare which hash function produced a digest and
+// algorithms can be migrated without breaking existing identifiers.
+package multihash
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"sort"
+	"sync"
+
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/sha3"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+)
+
+// Code is a multicodec hash function identifier.
+type Code uint64
+
+// Registered multicodec codes.
+const (
+	SHA2_256    Code = 0x12
+	SHA2_512    Code = 0x13
+	SHA3_256    Code = 0x16
+	BLAKE2b_256 Code = 0xb220
+)
+
+// Default is the algorithm used when none is specified.
+const Default = SHA2_256
+
+// Reasons reported in Details["reason"].
+const (
+	ReasonUnknownAlgorithm = "unknown_algorithm"
+	ReasonMalformed        = "malformed_multihash"
+	ReasonLength           = "length_mismatch"
+)
+
+// Algorithm describes a registered hash function.
+type Algorithm struct {
+	Code Code
+	Name string
+	Size int
+	New  func() hash.Hash
+}
+
+var (
+	registryMu sync.RWMutex
+	byCode     = make(map[Code]Algorithm)
+	byName     = make(map[string]Algorithm)
+)
+
+func init() {
+	mustRegister(Algorithm{Code: SHA2_256, Name: "sha2-256", Size: sha256.Size, New: sha256.New})
+	mustRegister(Algorithm{Code: SHA2_512, Name: "sha2-512", Size: sha512.Size, New: sha512.New})
+	mustRegister(Algorithm{Code: SHA3_256, Name: "sha3-256", Size: 32, New: sha3.New256})
+	mustRegister(Algorithm{Code: BLAKE2b_256, Name: "blake2b-256", Size: blake2b.Size256, New: func() hash.Hash {
+		h, _ := blake2b.New256(nil)
+		return h
+	}})
+}
+
+func mustRegister(a Algorithm) {
+	if err := Register(a); err != nil {
+		panic(err)
+	}
+}
+
+// Register adds an algorithm to the registry. Codes and names must be
+// unique.
+func Register(a Algorithm) error {
+	if a.Name == "" || a.Size <= 0 || a.New == nil {
+		return errors.New(errors.CodeInvalidInput, "incomplete algorithm definition")
+	}
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := byCode[a.Code]; ok {
+		return errors.New(errors.CodeInvalidInput, fmt.Sprintf("hash code 0x%x already registered", uint64(a.Code)))
+	}
+	if _, ok := byName[a.Name]; ok {
+		return errors.New(errors.CodeInvalidInput, fmt.Sprintf("hash %q already registered", a.Name))
+	}
+	byCode[a.Code] = a
+	byName[a.Name] = a
+	return nil
+}
+
+// Lookup returns the algorithm registered for code.
+func Lookup(code Code) (Algorithm, error) {
+	registryMu.RLock()
+	a, ok := byCode[code]
+	registryMu.RUnlock()
+	if !ok {
+		return Algorithm{}, reject(ReasonUnknownAlgorithm, fmt.Sprintf("unknown hash code 0x%x", uint64(code)))
+	}
+	return a, nil
+}
+
+// LookupName returns the algorithm registered under name, e.g. "sha3-256".
+func LookupName(name string) (Algorithm, error) {
+	registryMu.RLock()
+	a, ok := byName[name]
+	registryMu.RUnlock()
+	if !ok {
+		return Algorithm{}, reject(ReasonUnknownAlgorithm, fmt.Sprintf("unknown hash %q", name))
+	}
+	return a, nil
+}
+
+// Algorithms returns every registered algorithm ordered by code.
+func Algorithms() []Algorithm {
+	registryMu.RLock()
+	out := make([]Algorithm, 0, len(byCode))
+	for _, a := range byCode {
+		out = append(out, a)
+	}
+	registryMu.RUnlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].Code < out[j].Code })
+	return out
+}
+
+// String returns the algorithm name, or the hex code if unregistered.
+func (c Code) String() string {
+	if a, err := Lookup(c); err == nil {
+		return a.Name
+	}
+	return fmt.Sprintf("0x%x", uint64(c))
+}
+
+// Multihash is a self-describing digest. Its text form is lowercase hex.
+type Multihash []byte
+
+// Sum hashes data with the algorithm for code.
+func Sum(code Code, data []byte) (Multihash, error) {
+	a, err := Lookup(code)
+	if err != nil {
+		return nil, err
+	}
+	h := a.New()
+	h.Write(data)
+	return encode(a.Code, h.Sum(nil)), nil
+}
+
+// Encode wraps an existing digest, e.g. a legacy SHA-256 hex address.
+func Encode(code Code, digest []byte) (Multihash, error) {
+	a, err := Lookup(code)
+	if err != nil {
+		return nil, err
+	}
+	if len(digest) != a.Size {
+		return nil, reject(ReasonLength, fmt.Sprintf("%s digest must be %d bytes, got %d", a.Name, a.Size, len(digest)))
+	}
+	return encode(code, digest), nil
+}
+
+func encode(code Code, digest []byte) Multihash {
+	b := binary.AppendUvarint(nil, uint64(code))
+	b = binary.AppendUvarint(b, uint64(len(digest)))
+	return append(b, digest...)
+}
+
+// Decode splits a multihash into its algorithm and digest. Varints must be
+// minimally encoded, so each digest has exactly one multihash.
+func Decode(b []byte) (Algorithm, []byte, error) {
+	code, n := uvarint(b)
+	if n <= 0 {
+		return Algorithm{}, nil, reject(ReasonMalformed, "invalid hash code varint")
+	}
+	length, m := uvarint(b[n:])
+	if m <= 0 {
+		return Algorithm{}, nil, reject(ReasonMalformed, "invalid length varint")
+	}
+	digest := b[n+m:]
+	if uint64(len(digest)) != length {
+		return Algorithm{}, nil, reject(ReasonLength, fmt.Sprintf("declared length %d, got %d bytes", length, len(digest)))
+	}
+	a, err := Lookup(Code(code))
+	if err != nil {
+		return Algorithm{}, nil, err
+	}
+	if len(digest) != a.Size {
+		return Algorithm{}, nil, reject(ReasonLength, fmt.Sprintf("%s digest must be %d bytes", a.Name, a.Size))
+	}
+	return a, digest, nil
+}
+
+// uvarint is binary.Uvarint, but returns n == 0 for encodings that are not
+// minimal (e.g. 0x92 0x00 for 0x12).
+func uvarint(b []byte) (uint64, int) {
+	v, n := binary.Uvarint(b)
+	if n <= 0 {
+		return v, n
+	}
+	var buf [binary.MaxVarintLen64]byte
+	if !bytes.Equal(buf[:binary.PutUvarint(buf[:], v)], b[:n]) {
+		return 0, 0
+	}
+	return v, n
+}
+
+// Parse decodes the hex text form of a multihash.
+func Parse(s string) (Multihash, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, reject(ReasonMalformed, "multihash is not valid hex")
+	}
+	if _, _, err := Decode(b); err != nil {
+		return nil, err
+	}
+	return Multihash(b), nil
+}
+
+// Code returns the hash code, or 0 if m is malformed.
+func (m Multihash) Code() Code {
+	a, _, err := Decode(m)
+	if err != nil {
+		return 0
+	}
+	return a.Code
+}
+
+// Digest returns the raw digest, or nil if m is malformed.
+func (m Multihash) Digest() []byte {
+	_, d, err := Decode(m)
+	if err != nil {
+		return nil
+	}
+	return d
+}
+
+// Verify reports whether m is the digest of data under m's own algorithm.
+func (m Multihash) Verify(data []byte) (bool, error) {
+	a, digest, err := Decode(m)
+	if err != nil {
+		return false, err
+	}
+	h := a.New()
+	h.Write(data)
+	return subtle.ConstantTimeCompare(h.Sum(nil), digest) == 1, nil
+}
+
+// String returns the hex encoding.
+func (m Multihash) String() string {
+	return hex.EncodeToString(m)
+}
+
+// MarshalText encodes m as hex, so it appears as a string in JSON.
+func (m Multihash) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalText decodes and validates hex text.
+func (m *Multihash) UnmarshalText(text []byte) error {
+	parsed, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+func reject(reason, message string) error {
+	return errors.New(errors.CodeInvalidInput, message,
+		errors.WithDetails(map[string]interface{}{"reason": reason}))
+}
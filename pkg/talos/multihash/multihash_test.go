@@ -0,0 +1,124 @@
+package multihash
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+)
+
+func reason(err error) string {
+	if te, ok := err.(*errors.TalosError); ok {
+		r, _ := te.Details["reason"].(string)
+		return r
+	}
+	return ""
+}
+
+func TestSumVectors(t *testing.T) {
+	tests := []struct {
+		code Code
+		want string
+	}{
+		{SHA2_256, "1220e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		{SHA2_512, "1340cf83e1357eefb8bdf1542850d66d8007d620e4050b5715dc83f4a921d36ce9ce47d0d13c5d85f2b0ff8318d2877eec2f63b931bd47417a81a538327af927da3e"},
+		{SHA3_256, "1620a7ffc6f8bf1ed76651c14756a061d662f580ff4de43b49fa82d80a4b80f8434a"},
+		{BLAKE2b_256, "a0e402200e5751c026e543b2e8ab2eb06099daa1d1e5df47778f7787faab45cdf12fe3a8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code.String(), func(t *testing.T) {
+			mh, err := Sum(tt.code, nil)
+			if err != nil {
+				t.Fatalf("Sum failed: %v", err)
+			}
+			if mh.String() != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, mh)
+			}
+			parsed, err := Parse(tt.want)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			if parsed.Code() != tt.code {
+				t.Errorf("expected code %v, got %v", tt.code, parsed.Code())
+			}
+			if ok, _ := parsed.Verify(nil); !ok {
+				t.Error("Verify failed for matching data")
+			}
+			if ok, _ := parsed.Verify([]byte("x")); ok {
+				t.Error("Verify succeeded for different data")
+			}
+		})
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		reason string
+	}{
+		{"not hex", "zz", ReasonMalformed},
+		{"empty", "", ReasonMalformed},
+		{"short digest", "1220e3b0", ReasonLength},
+		{"trailing bytes", "1201aabb", ReasonLength},
+		{"unknown code", "990100", ReasonUnknownAlgorithm},
+		{"wrong size for algorithm", "1201aa", ReasonLength},
+		{"non-minimal code", "9200" + "20" + strings.Repeat("00", 32), ReasonMalformed},
+		{"non-minimal length", "12" + "a000" + strings.Repeat("00", 32), ReasonMalformed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.input)
+			if got := reason(err); got != tt.reason {
+				t.Errorf("expected reason %q, got %q (%v)", tt.reason, got, err)
+			}
+		})
+	}
+}
+
+func TestEncodeAndRegistry(t *testing.T) {
+	digest := sha256.Sum256([]byte("legacy"))
+	mh, err := Encode(SHA2_256, digest[:])
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if ok, _ := mh.Verify([]byte("legacy")); !ok {
+		t.Error("wrapped legacy digest does not verify")
+	}
+	if _, err := Encode(SHA3_256, digest[:16]); reason(err) != ReasonLength {
+		t.Errorf("expected %s, got %v", ReasonLength, err)
+	}
+
+	if a, err := LookupName("blake2b-256"); err != nil || a.Code != BLAKE2b_256 {
+		t.Errorf("LookupName failed: %v", err)
+	}
+	if len(Algorithms()) < 4 {
+		t.Error("expected built-in algorithms to be registered")
+	}
+	if err := Register(Algorithm{Code: SHA2_256, Name: "dup", Size: 32, New: sha256.New}); err == nil {
+		t.Error("expected error registering duplicate code")
+	}
+}
+
+func TestJSON(t *testing.T) {
+	type object struct {
+		Digest Multihash `json:"digest"`
+	}
+	mh, _ := Sum(SHA3_256, []byte("payload"))
+	b, err := json.Marshal(object{Digest: mh})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out object
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if out.Digest.Code() != SHA3_256 || out.Digest.String() != mh.String() {
+		t.Errorf("round trip mismatch: %s", b)
+	}
+	if err := json.Unmarshal([]byte(`{"digest":"1220"}`), &out); err == nil {
+		t.Error("expected error for truncated multihash")
+	}
+}
@@ -0,0 +1,452 @@
+// Package policy evaluates local allow/deny rules for tool calls so that
+// clearly disallowed calls can be refused before they reach the gateway.
+//
+// A policy is a list of rules, each matching on server ID, tool name, server
+// tags, caller DID and time of day. A matching deny rule always wins; failing
+// that, a matching allow rule permits the call; otherwise the policy default
+// applies, which is deny unless set to allow. Policies load from JSON or
+// YAML:
+//
+//	default: deny
+//	rules:
+//	  - id: read-only-fs
+//	    effect: allow
+//	    servers: ["fs-*"]
+//	    tools: [read, list]
+//	  - id: no-prod-weekends
+//	    effect: deny
+//	    tags: {env: prod}
+//	    time: {days: [sat, sun], timezone: Europe/Berlin}
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+)
+
+// Effects.
+const (
+	Allow = "allow"
+	Deny  = "deny"
+)
+
+// Wildcard matches any value.
+const Wildcard = "*"
+
+// Reasons reported in Details["reason"].
+const (
+	ReasonInvalidPolicy = "invalid_policy"
+	ReasonPolicyDenied  = "policy_denied"
+)
+
+// Policy is the declarative form of a rule set.
+type Policy struct {
+	// Default is the effect when no rule matches: Allow or Deny (the
+	// default).
+	Default string `json:"default,omitempty" yaml:"default,omitempty"`
+	Rules   []Rule `json:"rules" yaml:"rules"`
+}
+
+// Rule matches a call when every condition it sets holds. Empty conditions
+// match anything. Servers, Tools and Callers accept glob patterns: '*'
+// matches any run of characters, including '/', '?' any one character and
+// [...] a character class as in path.Match, with '\' escaping. A Tags value
+// of "*" only requires the tag to be present.
+type Rule struct {
+	ID          string            `json:"id" yaml:"id"`
+	Description string            `json:"description,omitempty" yaml:"description,omitempty"`
+	Effect      string            `json:"effect" yaml:"effect"`
+	Servers     []string          `json:"servers,omitempty" yaml:"servers,omitempty"`
+	Tools       []string          `json:"tools,omitempty" yaml:"tools,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Callers     []string          `json:"callers,omitempty" yaml:"callers,omitempty"`
+	Time        *TimeWindow       `json:"time,omitempty" yaml:"time,omitempty"`
+}
+
+// TimeWindow matches calls made on Days between Start and End ("15:04",
+// End exclusive) in Timezone. A window whose End is before its Start spans
+// midnight and belongs to the day it starts on. Empty fields match
+// anything; Timezone defaults to UTC.
+type TimeWindow struct {
+	Days     []string `json:"days,omitempty" yaml:"days,omitempty"`
+	Start    string   `json:"start,omitempty" yaml:"start,omitempty"`
+	End      string   `json:"end,omitempty" yaml:"end,omitempty"`
+	Timezone string   `json:"timezone,omitempty" yaml:"timezone,omitempty"`
+}
+
+// Request describes a tool call being evaluated.
+type Request struct {
+	ServerID string
+	ToolName string
+	// Tags are the server's tags as reported by the gateway.
+	Tags   map[string]string
+	Caller string
+	// Time is when the call is made; zero means now.
+	Time time.Time
+}
+
+// Decision is the outcome of an evaluation.
+type Decision struct {
+	Allowed bool
+	// Rule is the ID of the deciding rule, or "" if the default applied.
+	Rule        string
+	Explanation string
+}
+
+// Err returns nil for an allowed decision and a CodeDenied error carrying
+// the explanation otherwise.
+func (d Decision) Err() error {
+	if d.Allowed {
+		return nil
+	}
+	return errors.New(errors.CodeDenied, d.Explanation,
+		errors.WithDetails(map[string]interface{}{"reason": ReasonPolicyDenied, "rule": d.Rule}))
+}
+
+// Engine evaluates a validated policy. It is immutable and safe for
+// concurrent use.
+type Engine struct {
+	defaultAllow bool
+	rules        []compiledRule
+	now          func() time.Time
+}
+
+type compiledRule struct {
+	Rule
+	servers, tools, callers []*regexp.Regexp
+	days                    map[time.Weekday]bool
+	start, end              int // minutes after midnight; end < 0 means none
+	loc                     *time.Location
+}
+
+// Option configures an Engine.
+type Option func(*Engine)
+
+// WithClock overrides the time source used for requests without a Time.
+func WithClock(now func() time.Time) Option {
+	return func(e *Engine) {
+		e.now = now
+	}
+}
+
+// New validates p and returns an engine for it.
+func New(p Policy, opts ...Option) (*Engine, error) {
+	e := &Engine{now: time.Now}
+	switch p.Default {
+	case "", Deny:
+	case Allow:
+		e.defaultAllow = true
+	default:
+		return nil, invalidPolicy(fmt.Sprintf("unknown default effect %q", p.Default))
+	}
+	seen := make(map[string]bool)
+	for i, r := range p.Rules {
+		if r.ID == "" {
+			r.ID = fmt.Sprintf("rule-%d", i)
+		}
+		if seen[r.ID] {
+			return nil, invalidPolicy(fmt.Sprintf("duplicate rule id %q", r.ID))
+		}
+		seen[r.ID] = true
+		cr, err := compile(r)
+		if err != nil {
+			return nil, err
+		}
+		e.rules = append(e.rules, cr)
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e, nil
+}
+
+// ParseJSON decodes and validates a JSON policy. Unknown fields are errors.
+func ParseJSON(data []byte, opts ...Option) (*Engine, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var p Policy
+	if err := dec.Decode(&p); err != nil {
+		return nil, invalidPolicyCause("policy is not valid JSON", err)
+	}
+	return New(p, opts...)
+}
+
+// ParseYAML decodes and validates a YAML policy. Unknown fields are errors.
+func ParseYAML(data []byte, opts ...Option) (*Engine, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	var p Policy
+	if err := dec.Decode(&p); err != nil {
+		return nil, invalidPolicyCause("policy is not valid YAML", err)
+	}
+	return New(p, opts...)
+}
+
+// Load reads a policy file, choosing the format from its extension: .json,
+// or .yaml/.yml.
+func Load(file string, opts ...Option) (*Engine, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, errors.New(errors.CodeInvalidInput, "failed to read policy file", errors.WithCause(err))
+	}
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".json":
+		return ParseJSON(data, opts...)
+	case ".yaml", ".yml":
+		return ParseYAML(data, opts...)
+	}
+	return nil, invalidPolicy(fmt.Sprintf("unsupported policy file extension %q", filepath.Ext(file)))
+}
+
+// UsesTags reports whether any rule matches on server tags, i.e. whether
+// callers need to supply Request.Tags.
+func (e *Engine) UsesTags() bool {
+	for _, r := range e.rules {
+		if len(r.Tags) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Evaluate decides req.
+func (e *Engine) Evaluate(req Request) Decision {
+	if req.Time.IsZero() {
+		req.Time = e.now()
+	}
+	var allow *compiledRule
+	for i := range e.rules {
+		r := &e.rules[i]
+		if !r.matches(req) {
+			continue
+		}
+		if r.Effect == Deny {
+			return Decision{Rule: r.ID, Explanation: explain(r, req)}
+		}
+		if allow == nil {
+			allow = r
+		}
+	}
+	if allow != nil {
+		return Decision{Allowed: true, Rule: allow.ID, Explanation: explain(allow, req)}
+	}
+	effect := Deny
+	if e.defaultAllow {
+		effect = Allow
+	}
+	return Decision{
+		Allowed:     e.defaultAllow,
+		Explanation: fmt.Sprintf("no rule matched tool %q on server %q; default is %s", req.ToolName, req.ServerID, effect),
+	}
+}
+
+func explain(r *compiledRule, req Request) string {
+	verb := "allowed"
+	if r.Effect == Deny {
+		verb = "denied"
+	}
+	s := fmt.Sprintf("tool %q on server %q %s by rule %q", req.ToolName, req.ServerID, verb, r.ID)
+	if r.Description != "" {
+		s += ": " + r.Description
+	}
+	return s
+}
+
+func compile(r Rule) (compiledRule, error) {
+	cr := compiledRule{Rule: r, end: -1}
+	if r.Effect != Allow && r.Effect != Deny {
+		return cr, invalidPolicy(fmt.Sprintf("rule %q has unknown effect %q", r.ID, r.Effect))
+	}
+	for _, f := range []struct {
+		patterns []string
+		out      *[]*regexp.Regexp
+	}{{r.Servers, &cr.servers}, {r.Tools, &cr.tools}, {r.Callers, &cr.callers}} {
+		for _, p := range f.patterns {
+			re, err := compileGlob(p)
+			if err != nil {
+				return cr, invalidPolicy(fmt.Sprintf("rule %q has invalid pattern %q", r.ID, p))
+			}
+			*f.out = append(*f.out, re)
+		}
+	}
+	w := r.Time
+	if w == nil {
+		return cr, nil
+	}
+	cr.loc = time.UTC
+	if w.Timezone != "" {
+		loc, err := time.LoadLocation(w.Timezone)
+		if err != nil {
+			return cr, invalidPolicy(fmt.Sprintf("rule %q has unknown timezone %q", r.ID, w.Timezone))
+		}
+		cr.loc = loc
+	}
+	if len(w.Days) > 0 {
+		cr.days = make(map[time.Weekday]bool)
+		for _, d := range w.Days {
+			wd, ok := weekdays[strings.ToLower(d)]
+			if !ok {
+				return cr, invalidPolicy(fmt.Sprintf("rule %q has unknown day %q", r.ID, d))
+			}
+			cr.days[wd] = true
+		}
+	}
+	var err error
+	if cr.start, err = parseClock(w.Start, 0); err != nil {
+		return cr, invalidPolicy(fmt.Sprintf("rule %q has invalid start time %q", r.ID, w.Start))
+	}
+	if cr.end, err = parseClock(w.End, -1); err != nil {
+		return cr, invalidPolicy(fmt.Sprintf("rule %q has invalid end time %q", r.ID, w.End))
+	}
+	if cr.end == cr.start {
+		return cr, invalidPolicy(fmt.Sprintf("rule %q has an empty time window", r.ID))
+	}
+	return cr, nil
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+func parseClock(s string, empty int) (int, error) {
+	if s == "" {
+		return empty, nil
+	}
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func (r *compiledRule) matches(req Request) bool {
+	if !matchAny(r.servers, req.ServerID) || !matchAny(r.tools, req.ToolName) || !matchAny(r.callers, req.Caller) {
+		return false
+	}
+	for k, want := range r.Tags {
+		got, ok := req.Tags[k]
+		if !ok || want != Wildcard && got != want {
+			return false
+		}
+	}
+	return r.Time == nil || r.inWindow(req.Time)
+}
+
+func (r *compiledRule) inWindow(t time.Time) bool {
+	t = t.In(r.loc)
+	minute := t.Hour()*60 + t.Minute()
+	day := t.Weekday()
+	switch {
+	case r.end < 0:
+		if minute < r.start {
+			return false
+		}
+	case r.start < r.end:
+		if minute < r.start || minute >= r.end {
+			return false
+		}
+	default:
+		// Spans midnight: early-morning minutes belong to the previous day.
+		if minute >= r.end && minute < r.start {
+			return false
+		}
+		if minute < r.end {
+			day = (day + 6) % 7
+		}
+	}
+	return r.days == nil || r.days[day]
+}
+
+// matchAny reports whether v matches one of patterns; no patterns match
+// anything.
+func matchAny(patterns []*regexp.Regexp, v string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, re := range patterns {
+		if re.MatchString(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// compileGlob translates a glob pattern into an anchored regular
+// expression. Unlike path.Match, '*' and '?' also match '/', so that a deny
+// rule such as "prod-*" covers IDs like "prod-db/primary".
+func compileGlob(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString(`^(?s:`)
+	rs := []rune(pattern)
+	for i := 0; i < len(rs); i++ {
+		switch c := rs[i]; c {
+		case '*':
+			b.WriteString(`.*`)
+		case '?':
+			b.WriteString(`.`)
+		case '\\':
+			if i++; i == len(rs) {
+				return nil, fmt.Errorf("trailing escape in %q", pattern)
+			}
+			b.WriteString(regexp.QuoteMeta(string(rs[i])))
+		case '[':
+			j := i + 1
+			if j < len(rs) && rs[j] == '^' {
+				j++
+			}
+			start := j
+			for j < len(rs) && (rs[j] != ']' || j == start) {
+				if rs[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(rs) || j == start {
+				return nil, fmt.Errorf("unterminated character class in %q", pattern)
+			}
+			b.WriteByte('[')
+			if start > i+1 {
+				b.WriteByte('^')
+			}
+			for k := start; k < j; k++ {
+				switch rs[k] {
+				case '\\':
+					k++
+					b.WriteString(`\x{` + strconv.FormatInt(int64(rs[k]), 16) + `}`)
+				case '[', ']', '^':
+					b.WriteString(`\` + string(rs[k]))
+				default:
+					b.WriteRune(rs[k])
+				}
+			}
+			b.WriteByte(']')
+			i = j
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString(`)$`)
+	return regexp.Compile(b.String())
+}
+
+func invalidPolicy(message string) error {
+	return errors.New(errors.CodeInvalidInput, message,
+		errors.WithDetails(map[string]interface{}{"reason": ReasonInvalidPolicy}))
+}
+
+func invalidPolicyCause(message string, cause error) error {
+	return errors.New(errors.CodeInvalidInput, message, errors.WithCause(cause),
+		errors.WithDetails(map[string]interface{}{"reason": ReasonInvalidPolicy}))
+}
@@ -0,0 +1,172 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+)
+
+const yamlPolicy = `
+default: deny
+rules:
+  - id: fs-read
+    description: read-only filesystem access
+    effect: allow
+    servers: ["fs-*"]
+    tools: [read, list]
+  - id: ops-anything
+    effect: allow
+    callers: ["did:key:zOps"]
+  - id: no-prod-weekends
+    effect: deny
+    tags: {env: prod}
+    time: {days: [sat, sun], timezone: Europe/Berlin}
+  - id: nightly-freeze
+    effect: deny
+    servers: [db]
+    time: {start: "22:00", end: "06:00"}
+`
+
+// Monday 2024-01-01 12:00 UTC.
+var monday = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func TestEvaluate(t *testing.T) {
+	e, err := ParseYAML([]byte(yamlPolicy))
+	if err != nil {
+		t.Fatalf("ParseYAML failed: %v", err)
+	}
+	prod := map[string]string{"env": "prod"}
+	saturday := monday.AddDate(0, 0, 5)
+
+	tests := []struct {
+		name    string
+		req     Request
+		allowed bool
+		rule    string
+	}{
+		{"allowed read", Request{ServerID: "fs-home", ToolName: "read", Time: monday}, true, "fs-read"},
+		{"write not allowed", Request{ServerID: "fs-home", ToolName: "write", Time: monday}, false, ""},
+		{"other server", Request{ServerID: "mail", ToolName: "read", Time: monday}, false, ""},
+		{"ops caller", Request{ServerID: "mail", ToolName: "send", Caller: "did:key:zOps", Time: monday}, true, "ops-anything"},
+		{"prod on weekday", Request{ServerID: "fs-prod", ToolName: "read", Tags: prod, Time: monday}, true, "fs-read"},
+		{"prod on weekend", Request{ServerID: "fs-prod", ToolName: "read", Tags: prod, Time: saturday}, false, "no-prod-weekends"},
+		{"deny beats allow", Request{ServerID: "fs-prod", ToolName: "read", Caller: "did:key:zOps", Tags: prod, Time: saturday}, false, "no-prod-weekends"},
+		{"before freeze", Request{ServerID: "db", ToolName: "q", Caller: "did:key:zOps", Time: monday.Add(9 * time.Hour)}, true, "ops-anything"},
+		{"in freeze", Request{ServerID: "db", ToolName: "q", Caller: "did:key:zOps", Time: monday.Add(11 * time.Hour)}, false, "nightly-freeze"},
+		{"after freeze", Request{ServerID: "db", ToolName: "q", Caller: "did:key:zOps", Time: monday.Add(18 * time.Hour)}, true, "ops-anything"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := e.Evaluate(tt.req)
+			if d.Allowed != tt.allowed || d.Rule != tt.rule {
+				t.Errorf("expected allowed=%v rule=%q, got %+v", tt.allowed, tt.rule, d)
+			}
+			if d.Explanation == "" {
+				t.Error("expected an explanation")
+			}
+		})
+	}
+
+	d := e.Evaluate(Request{ServerID: "fs-home", ToolName: "read", Time: monday})
+	if !strings.Contains(d.Explanation, "read-only filesystem access") {
+		t.Errorf("explanation lacks rule description: %q", d.Explanation)
+	}
+	err = e.Evaluate(Request{ServerID: "mail", ToolName: "send", Time: monday}).Err()
+	te, ok := err.(*errors.TalosError)
+	if !ok || te.Code != errors.CodeDenied || te.Details["reason"] != ReasonPolicyDenied {
+		t.Errorf("expected policy denial, got %v", err)
+	}
+	if !e.UsesTags() {
+		t.Error("expected UsesTags")
+	}
+}
+
+func TestDefaultAllowAndClock(t *testing.T) {
+	e, err := ParseJSON([]byte(`{"default":"allow","rules":[{"id":"weekend","effect":"deny","time":{"days":["sat","sun"]}}]}`),
+		WithClock(func() time.Time { return monday.AddDate(0, 0, 6) }))
+	if err != nil {
+		t.Fatalf("ParseJSON failed: %v", err)
+	}
+	if d := e.Evaluate(Request{ServerID: "s", ToolName: "t"}); d.Allowed {
+		t.Errorf("expected weekend denial from clock, got %+v", d)
+	}
+	if d := e.Evaluate(Request{ServerID: "s", ToolName: "t", Time: monday}); !d.Allowed || d.Rule != "" {
+		t.Errorf("expected default allow, got %+v", d)
+	}
+}
+
+func TestGlobs(t *testing.T) {
+	e, err := New(Policy{Default: Allow, Rules: []Rule{
+		{ID: "no-prod", Effect: Deny, Servers: []string{"prod-*"}},
+		{ID: "no-admin", Effect: Deny, Tools: []string{"admin?[0-9]", `lit\*`}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		server, tool string
+		allowed      bool
+	}{
+		{"prod-db/primary", "query", false},
+		{"prod-db", "query", false},
+		{"dev/prod-db", "query", true},
+		{"dev", "admin/7", false},
+		{"dev", "admin/x", true},
+		{"dev", "lit*", false},
+		{"dev", "literal", true},
+	}
+	for _, tt := range tests {
+		if got := e.Evaluate(Request{ServerID: tt.server, ToolName: tt.tool}).Allowed; got != tt.allowed {
+			t.Errorf("%s/%s: expected allowed=%v", tt.server, tt.tool, tt.allowed)
+		}
+	}
+}
+
+func TestInvalidPolicies(t *testing.T) {
+	bad := []string{
+		`{"default":"maybe"}`,
+		`{"rules":[{"effect":"permit"}]}`,
+		`{"rules":[{"id":"a","effect":"allow"},{"id":"a","effect":"deny"}]}`,
+		`{"rules":[{"effect":"allow","servers":["["]}]}`,
+		`{"rules":[{"effect":"allow","servers":["[^]"]}]}`,
+		`{"rules":[{"effect":"allow","tools":["a\\"]}]}`,
+		`{"rules":[{"effect":"allow","time":{"days":["someday"]}}]}`,
+		`{"rules":[{"effect":"allow","time":{"start":"25:00"}}]}`,
+		`{"rules":[{"effect":"allow","time":{"start":"10:00","end":"10:00"}}]}`,
+		`{"rules":[{"effect":"allow","time":{"timezone":"Mars/Olympus"}}]}`,
+		`{"rules":[{"effect":"allow","unknown":1}]}`,
+	}
+	for _, b := range bad {
+		_, err := ParseJSON([]byte(b))
+		te, ok := err.(*errors.TalosError)
+		if !ok || te.Details["reason"] != ReasonInvalidPolicy {
+			t.Errorf("expected invalid policy for %s, got %v", b, err)
+		}
+	}
+	if _, err := ParseYAML([]byte("rules:\n  - effect: allow\n    extra: 1\n")); err == nil {
+		t.Error("expected unknown YAML field to be rejected")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"p.yaml": yamlPolicy,
+		"p.json": `{"rules":[{"effect":"allow"}]}`,
+	} {
+		file := filepath.Join(dir, name)
+		if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(file); err != nil {
+			t.Errorf("Load(%s) failed: %v", name, err)
+		}
+	}
+	if _, err := Load(filepath.Join(dir, "p.toml")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
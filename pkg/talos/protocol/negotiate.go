@@ -0,0 +1,140 @@
+package protocol
+
+import (
+	"sort"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+)
+
+// Compatibility declared by this SDK. These mirror talos_compatibility in
+// talos.json and must be kept in sync with it.
+const (
+	ProtocolRange = "^1.0.0"
+)
+
+var (
+	// SupportedVersions lists the protocol versions this SDK implements.
+	SupportedVersions = []string{"1.0.0"}
+	// SupportedFeatures lists the optional protocol features this SDK implements.
+	SupportedFeatures = []string{"ratchet"}
+)
+
+// Offer is what a peer advertises during negotiation.
+type Offer struct {
+	ProtocolRange string   `json:"protocol_range"`
+	Versions      []string `json:"versions"`
+	Features      []string `json:"features"`
+}
+
+// LocalOffer returns the offer for this SDK.
+func LocalOffer() Offer {
+	return Offer{
+		ProtocolRange: ProtocolRange,
+		Versions:      append([]string(nil), SupportedVersions...),
+		Features:      append([]string(nil), SupportedFeatures...),
+	}
+}
+
+// Agreement is the outcome of a successful negotiation.
+type Agreement struct {
+	Version  Version
+	Features []string // features supported by both sides, sorted
+}
+
+// HasFeature reports whether both sides agreed on feature.
+func (a *Agreement) HasFeature(feature string) bool {
+	i := sort.SearchStrings(a.Features, feature)
+	return i < len(a.Features) && a.Features[i] == feature
+}
+
+// Negotiate selects the highest version offered by both sides that also
+// satisfies both sides' protocol ranges, and intersects their features.
+// It is symmetric: both peers reach the same Agreement from the same offers.
+//
+// If no version is acceptable it fails with CodeProtocolMismatch and reports
+// both offers in Details.
+func Negotiate(local, remote Offer) (*Agreement, error) {
+	localRange, err := ParseRange(local.ProtocolRange)
+	if err != nil {
+		return nil, err
+	}
+	remoteRange, err := ParseRange(remote.ProtocolRange)
+	if err != nil {
+		return nil, mismatch("remote protocol range is invalid", local, remote)
+	}
+
+	remoteVersions := make(map[Version]bool)
+	for _, s := range remote.Versions {
+		// Unparseable remote versions are ignored rather than fatal so that a
+		// peer can advertise formats this SDK does not understand.
+		if v, err := ParseVersion(s); err == nil {
+			remoteVersions[v] = true
+		}
+	}
+
+	var best *Version
+	for _, s := range local.Versions {
+		v, err := ParseVersion(s)
+		if err != nil {
+			return nil, err
+		}
+		if !remoteVersions[v] || !localRange.Contains(v) || !remoteRange.Contains(v) {
+			continue
+		}
+		if best == nil || v.Compare(*best) > 0 {
+			v := v
+			best = &v
+		}
+	}
+	if best == nil {
+		return nil, mismatch("no common protocol version", local, remote)
+	}
+
+	return &Agreement{Version: *best, Features: intersect(local.Features, remote.Features)}, nil
+}
+
+// RequireFeatures fails with CodeProtocolMismatch unless every listed
+// feature was agreed.
+func (a *Agreement) RequireFeatures(features ...string) error {
+	var missing []string
+	for _, f := range features {
+		if !a.HasFeature(f) {
+			missing = append(missing, f)
+		}
+	}
+	if len(missing) > 0 {
+		return errors.New(errors.CodeProtocolMismatch, "required protocol features not agreed",
+			errors.WithDetails(map[string]interface{}{
+				"missing_features": missing,
+				"features":         a.Features,
+			}))
+	}
+	return nil
+}
+
+func intersect(a, b []string) []string {
+	in := make(map[string]bool, len(b))
+	for _, s := range b {
+		in[s] = true
+	}
+	seen := make(map[string]bool)
+	out := []string{}
+	for _, s := range a {
+		if in[s] && !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func mismatch(message string, local, remote Offer) error {
+	return errors.New(errors.CodeProtocolMismatch, message,
+		errors.WithDetails(map[string]interface{}{
+			"local_range":     local.ProtocolRange,
+			"remote_range":    remote.ProtocolRange,
+			"local_versions":  local.Versions,
+			"remote_versions": remote.Versions,
+		}))
+}
@@ -0,0 +1,91 @@
+package protocol
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+)
+
+func TestNegotiate(t *testing.T) {
+	local := Offer{ProtocolRange: "^1.0.0", Versions: []string{"1.0.0", "1.1.0", "1.2.0"}, Features: []string{"ratchet", "groups"}}
+	remote := Offer{ProtocolRange: ">=1.1.0", Versions: []string{"1.1.0", "1.2.0", "2.0.0"}, Features: []string{"groups", "sealed", "ratchet"}}
+
+	a, err := Negotiate(local, remote)
+	if err != nil {
+		t.Fatalf("Negotiate failed: %v", err)
+	}
+	if a.Version.String() != "1.2.0" {
+		t.Errorf("expected 1.2.0, got %s", a.Version)
+	}
+	if !reflect.DeepEqual(a.Features, []string{"groups", "ratchet"}) {
+		t.Errorf("unexpected features %v", a.Features)
+	}
+	if a.HasFeature("sealed") || !a.HasFeature("ratchet") {
+		t.Error("HasFeature mismatch")
+	}
+	if err := a.RequireFeatures("ratchet", "sealed"); err == nil {
+		t.Error("expected missing feature error")
+	}
+
+	// Symmetric.
+	b, err := Negotiate(remote, local)
+	if err != nil || b.Version != a.Version || !reflect.DeepEqual(a.Features, b.Features) {
+		t.Errorf("negotiation is not symmetric: %v %v", b, err)
+	}
+}
+
+func TestNegotiate_RangeExcludesCommonVersion(t *testing.T) {
+	// Both list 1.2.0 but the remote range caps below it.
+	local := Offer{ProtocolRange: "^1.0.0", Versions: []string{"1.0.0", "1.2.0"}}
+	remote := Offer{ProtocolRange: "<1.2.0", Versions: []string{"1.0.0", "1.2.0"}}
+	a, err := Negotiate(local, remote)
+	if err != nil || a.Version.String() != "1.0.0" {
+		t.Errorf("expected 1.0.0, got %v %v", a, err)
+	}
+}
+
+func TestNegotiate_Mismatch(t *testing.T) {
+	local := LocalOffer()
+	remote := Offer{ProtocolRange: "^2.0.0", Versions: []string{"2.0.0"}}
+
+	_, err := Negotiate(local, remote)
+	te, ok := err.(*errors.TalosError)
+	if !ok || te.Code != errors.CodeProtocolMismatch {
+		t.Fatalf("expected protocol mismatch, got %v", err)
+	}
+	if te.Details["local_range"] != ProtocolRange || te.Details["remote_range"] != "^2.0.0" {
+		t.Errorf("details missing ranges: %v", te.Details)
+	}
+}
+
+func TestLocalOfferMatchesManifest(t *testing.T) {
+	b, err := os.ReadFile("../../../talos.json")
+	if err != nil {
+		t.Skipf("talos.json not available: %v", err)
+	}
+	var manifest struct {
+		Compat struct {
+			ProtocolRange string   `json:"protocol_range"`
+			Features      []string `json:"features"`
+		} `json:"talos_compatibility"`
+	}
+	if err := json.Unmarshal(b, &manifest); err != nil {
+		t.Fatalf("parse talos.json: %v", err)
+	}
+	offer := LocalOffer()
+	if offer.ProtocolRange != manifest.Compat.ProtocolRange {
+		t.Errorf("ProtocolRange %q does not match talos.json %q", offer.ProtocolRange, manifest.Compat.ProtocolRange)
+	}
+	if !reflect.DeepEqual(offer.Features, manifest.Compat.Features) {
+		t.Errorf("SupportedFeatures %v does not match talos.json %v", offer.Features, manifest.Compat.Features)
+	}
+	r := MustParseRange(offer.ProtocolRange)
+	for _, v := range offer.Versions {
+		if !r.Contains(MustParseVersion(v)) {
+			t.Errorf("supported version %s outside declared range", v)
+		}
+	}
+}
@@ -0,0 +1,342 @@
+package protocol
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+)
+
+// Version is a semantic version (https://semver.org). Build metadata is
+// accepted when parsing but ignored.
+type Version struct {
+	Major, Minor, Patch uint64
+	Prerelease          string
+}
+
+// ParseVersion parses a full semantic version such as "1.2.3" or "1.0.0-rc.1".
+// A leading "v" is accepted.
+func ParseVersion(s string) (Version, error) {
+	v, parts, err := parsePartial(s)
+	if err != nil {
+		return Version{}, err
+	}
+	if parts != 3 {
+		return Version{}, invalidVersion(s)
+	}
+	return v, nil
+}
+
+// MustParseVersion is like ParseVersion but panics on error.
+func MustParseVersion(s string) Version {
+	v, err := ParseVersion(s)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	return s
+}
+
+// Compare returns -1, 0 or +1 following semver precedence.
+func (v Version) Compare(o Version) int {
+	for _, d := range [3][2]uint64{{v.Major, o.Major}, {v.Minor, o.Minor}, {v.Patch, o.Patch}} {
+		if d[0] != d[1] {
+			if d[0] < d[1] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return comparePrerelease(v.Prerelease, o.Prerelease)
+}
+
+func comparePrerelease(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1 // a release outranks its prereleases
+	case b == "":
+		return -1
+	}
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.ParseUint(as[i], 10, 64)
+		bn, bErr := strconv.ParseUint(bs[i], 10, 64)
+		switch {
+		case aErr == nil && bErr == nil:
+			if an != bn {
+				if an < bn {
+					return -1
+				}
+				return 1
+			}
+		case aErr == nil:
+			return -1 // numeric identifiers sort first
+		case bErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(as[i], bs[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	switch {
+	case len(as) < len(bs):
+		return -1
+	case len(as) > len(bs):
+		return 1
+	}
+	return 0
+}
+
+// parsePartial parses "1", "1.2", "1.2.3" (with optional prerelease on full
+// versions) and reports how many numeric parts were given. "x" and "*"
+// wildcards end the version early.
+func parsePartial(s string) (Version, int, error) {
+	raw := s
+	s = strings.TrimPrefix(s, "v")
+	if i := strings.IndexByte(s, '+'); i >= 0 {
+		s = s[:i]
+	}
+	var v Version
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		v.Prerelease = s[i+1:]
+		s = s[:i]
+		if v.Prerelease == "" {
+			return Version{}, 0, invalidVersion(raw)
+		}
+		for _, id := range strings.Split(v.Prerelease, ".") {
+			if id == "" || strings.Trim(id, "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz-") != "" {
+				return Version{}, 0, invalidVersion(raw)
+			}
+		}
+	}
+	fields := strings.Split(s, ".")
+	if len(fields) > 3 || s == "" {
+		return Version{}, 0, invalidVersion(raw)
+	}
+	nums := [3]*uint64{&v.Major, &v.Minor, &v.Patch}
+	parts := 0
+	for i, f := range fields {
+		if f == "x" || f == "X" || f == "*" {
+			break
+		}
+		if f == "" || (len(f) > 1 && f[0] == '0') {
+			return Version{}, 0, invalidVersion(raw)
+		}
+		n, err := strconv.ParseUint(f, 10, 64)
+		if err != nil {
+			return Version{}, 0, invalidVersion(raw)
+		}
+		*nums[i] = n
+		parts++
+	}
+	if v.Prerelease != "" && parts != 3 {
+		return Version{}, 0, invalidVersion(raw)
+	}
+	return v, parts, nil
+}
+
+func invalidVersion(s string) error {
+	return errors.New(errors.CodeInvalidInput, fmt.Sprintf("invalid semantic version %q", s))
+}
+
+type op int
+
+const (
+	opEQ op = iota
+	opGT
+	opGE
+	opLT
+	opLE
+)
+
+type comparator struct {
+	op op
+	v  Version
+}
+
+func (c comparator) matches(v Version) bool {
+	cmp := v.Compare(c.v)
+	switch c.op {
+	case opGT:
+		return cmp > 0
+	case opGE:
+		return cmp >= 0
+	case opLT:
+		return cmp < 0
+	case opLE:
+		return cmp <= 0
+	}
+	return cmp == 0
+}
+
+// Range is a set of semantic versions in npm-style range syntax, e.g.
+// "^1.0.0", "~1.2", ">=1.0.0 <2.0.0", "1.x || ^2.1.0" or "*".
+type Range struct {
+	raw  string
+	sets [][]comparator // OR of ANDs
+}
+
+// ParseRange parses a version range.
+func ParseRange(s string) (Range, error) {
+	r := Range{raw: strings.TrimSpace(s)}
+	for _, alt := range strings.Split(s, "||") {
+		var set []comparator
+		for _, term := range strings.Fields(alt) {
+			cs, err := parseTerm(term)
+			if err != nil {
+				return Range{}, errors.New(errors.CodeInvalidInput,
+					fmt.Sprintf("invalid version range %q", s), errors.WithCause(err))
+			}
+			set = append(set, cs...)
+		}
+		if set == nil {
+			set = []comparator{} // empty alternative matches everything
+		}
+		r.sets = append(r.sets, set)
+	}
+	return r, nil
+}
+
+// MustParseRange is like ParseRange but panics on error.
+func MustParseRange(s string) Range {
+	r, err := ParseRange(s)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+func (r Range) String() string {
+	return r.raw
+}
+
+// Contains reports whether v is in the range. As in npm, a prerelease only
+// matches if some comparator in the same set names a prerelease of the same
+// major.minor.patch.
+func (r Range) Contains(v Version) bool {
+	for _, set := range r.sets {
+		if setMatches(set, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func setMatches(set []comparator, v Version) bool {
+	for _, c := range set {
+		if !c.matches(v) {
+			return false
+		}
+	}
+	if v.Prerelease == "" {
+		return true
+	}
+	for _, c := range set {
+		if c.v.Prerelease != "" && c.v.Major == v.Major && c.v.Minor == v.Minor && c.v.Patch == v.Patch {
+			return true
+		}
+	}
+	return false
+}
+
+func parseTerm(term string) ([]comparator, error) {
+	switch {
+	case term == "*" || term == "x" || term == "X":
+		return nil, nil
+	case strings.HasPrefix(term, "^"):
+		return caret(term[1:])
+	case strings.HasPrefix(term, "~"):
+		return tilde(term[1:])
+	}
+
+	o, rest := opEQ, term
+	for _, p := range []struct {
+		prefix string
+		op     op
+	}{{">=", opGE}, {"<=", opLE}, {">", opGT}, {"<", opLT}, {"=", opEQ}} {
+		if strings.HasPrefix(term, p.prefix) {
+			o, rest = p.op, term[len(p.prefix):]
+			break
+		}
+	}
+	v, parts, err := parsePartial(rest)
+	if err != nil {
+		return nil, err
+	}
+	if parts == 3 {
+		return []comparator{{o, v}}, nil
+	}
+
+	// Partial versions expand to the range they cover, e.g. "1.2" is
+	// ">=1.2.0 <1.3.0" and "<=1" is "<2.0.0".
+	lo, hi := v, bump(v, parts)
+	switch o {
+	case opEQ:
+		if parts == 0 {
+			return nil, nil
+		}
+		return []comparator{{opGE, lo}, {opLT, hi}}, nil
+	case opGE:
+		return []comparator{{opGE, lo}}, nil
+	case opGT:
+		return []comparator{{opGE, hi}}, nil
+	case opLT:
+		return []comparator{{opLT, lo}}, nil
+	default: // opLE
+		return []comparator{{opLT, hi}}, nil
+	}
+}
+
+// bump returns the smallest version above everything matched by a partial
+// version with the given number of parts.
+func bump(v Version, parts int) Version {
+	switch parts {
+	case 1:
+		return Version{Major: v.Major + 1}
+	case 2:
+		return Version{Major: v.Major, Minor: v.Minor + 1}
+	}
+	return Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch + 1}
+}
+
+// caret allows changes that do not modify the left-most non-zero part.
+func caret(s string) ([]comparator, error) {
+	v, parts, err := parsePartial(s)
+	if err != nil || parts == 0 {
+		return nil, err
+	}
+	var hi Version
+	switch {
+	case v.Major > 0 || parts == 1:
+		hi = Version{Major: v.Major + 1}
+	case v.Minor > 0 || parts == 2:
+		hi = Version{Minor: v.Minor + 1}
+	default:
+		hi = Version{Patch: v.Patch + 1}
+	}
+	return []comparator{{opGE, v}, {opLT, hi}}, nil
+}
+
+// tilde allows patch-level changes (minor-level if only a major is given).
+func tilde(s string) ([]comparator, error) {
+	v, parts, err := parsePartial(s)
+	if err != nil || parts == 0 {
+		return nil, err
+	}
+	hi := Version{Major: v.Major, Minor: v.Minor + 1}
+	if parts == 1 {
+		hi = Version{Major: v.Major + 1}
+	}
+	return []comparator{{opGE, v}, {opLT, hi}}, nil
+}
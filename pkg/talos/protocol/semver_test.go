@@ -0,0 +1,83 @@
+package protocol
+
+import (
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	valid := map[string]Version{
+		"1.2.3":             {Major: 1, Minor: 2, Patch: 3},
+		"v0.0.1":            {Patch: 1},
+		"1.0.0-rc.1":        {Major: 1, Prerelease: "rc.1"},
+		"1.0.0-alpha+build": {Major: 1, Prerelease: "alpha"},
+	}
+	for in, want := range valid {
+		got, err := ParseVersion(in)
+		if err != nil || got != want {
+			t.Errorf("ParseVersion(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+
+	for _, in := range []string{"", "1", "1.2", "1.2.3.4", "01.2.3", "1.2.x", "1.0.0-", "1.0.0-a..b", "a.b.c"} {
+		if _, err := ParseVersion(in); err == nil {
+			t.Errorf("ParseVersion(%q) succeeded, want error", in)
+		}
+	}
+}
+
+func TestVersionCompare(t *testing.T) {
+	// Ordered per semver.org section 11.
+	ordered := []string{
+		"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta",
+		"1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "1.0.1", "1.1.0", "2.0.0",
+	}
+	for i := 0; i < len(ordered)-1; i++ {
+		a, b := MustParseVersion(ordered[i]), MustParseVersion(ordered[i+1])
+		if a.Compare(b) != -1 || b.Compare(a) != 1 || a.Compare(a) != 0 {
+			t.Errorf("expected %s < %s", a, b)
+		}
+	}
+}
+
+func TestRangeContains(t *testing.T) {
+	tests := []struct {
+		rng string
+		in  []string
+		out []string
+	}{
+		{"^1.0.0", []string{"1.0.0", "1.9.9"}, []string{"0.9.9", "2.0.0", "1.1.0-rc.1"}},
+		{"^0.2.3", []string{"0.2.3", "0.2.9"}, []string{"0.3.0", "0.2.2"}},
+		{"^0.0.3", []string{"0.0.3"}, []string{"0.0.4"}},
+		{"~1.2.3", []string{"1.2.3", "1.2.9"}, []string{"1.3.0"}},
+		{"~1", []string{"1.0.0", "1.5.0"}, []string{"2.0.0"}},
+		{">=1.0.0 <1.5.0", []string{"1.0.0", "1.4.9"}, []string{"1.5.0", "0.1.0"}},
+		{"1.x || ^3.1.0", []string{"1.0.0", "3.2.0"}, []string{"2.0.0", "3.0.0"}},
+		{"<=1.2", []string{"1.2.9"}, []string{"1.3.0"}},
+		{">1", []string{"2.0.0"}, []string{"1.9.9"}},
+		{"*", []string{"0.0.1", "9.9.9"}, []string{"1.0.0-rc.1"}},
+		{">=1.0.0-rc.1", []string{"1.0.0-rc.2", "1.0.0", "1.2.0"}, []string{"1.1.0-rc.1"}},
+		{"=1.0.0", []string{"1.0.0"}, []string{"1.0.1"}},
+	}
+	for _, tt := range tests {
+		r, err := ParseRange(tt.rng)
+		if err != nil {
+			t.Fatalf("ParseRange(%q) failed: %v", tt.rng, err)
+		}
+		for _, v := range tt.in {
+			if !r.Contains(MustParseVersion(v)) {
+				t.Errorf("%q should contain %s", tt.rng, v)
+			}
+		}
+		for _, v := range tt.out {
+			if r.Contains(MustParseVersion(v)) {
+				t.Errorf("%q should not contain %s", tt.rng, v)
+			}
+		}
+	}
+
+	for _, bad := range []string{"^a.b", ">=1.0.0.0", "~01"} {
+		if _, err := ParseRange(bad); err == nil {
+			t.Errorf("ParseRange(%q) succeeded, want error", bad)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+)
+
+// DefaultTimeout is the HTTP timeout of the default client.
+const DefaultTimeout = 10 * time.Second
+
+// maxResponseBytes bounds registry responses.
+const maxResponseBytes = 4 * 1024 * 1024
+
+// Doer matches http.Client.Do and mcp.Doer.
+type Doer interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+// Client talks to a registry. Every record it returns has been verified,
+// so a compromised registry can withhold or roll back records but not
+// forge them.
+type Client struct {
+	baseURL string
+	client  Doer
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client.
+func WithHTTPClient(client Doer) Option {
+	return func(c *Client) {
+		c.client = client
+	}
+}
+
+// NewClient creates a client for the registry at baseURL.
+func NewClient(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{Timeout: DefaultTimeout},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Register publishes a record made with SignRecord. A record whose
+// sequence number is not higher than the registered one is rejected with
+// CodeReplayDetected.
+func (c *Client) Register(ctx context.Context, r *Record) error {
+	if err := r.Verify(); err != nil {
+		return err
+	}
+	body, err := json.Marshal(r)
+	if err != nil {
+		return errors.New(errors.CodeInvalidInput, "failed to encode record", errors.WithCause(err))
+	}
+	var out Record
+	return c.do(ctx, http.MethodPut, c.recordURL(r.DID), body, &out)
+}
+
+// Lookup returns the record of did. An unregistered DID yields
+// CodeInvalidInput with reason not_found.
+func (c *Client) Lookup(ctx context.Context, did string) (*Record, error) {
+	var r Record
+	if err := c.do(ctx, http.MethodGet, c.recordURL(did), nil, &r); err != nil {
+		return nil, err
+	}
+	if r.DID != did {
+		return nil, reject(errors.CodeCryptoError, ReasonBadSignature, "registry returned a record for a different DID")
+	}
+	if err := r.Verify(); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// Query filters List. Protocol is a version range, e.g. "^1.2".
+type Query struct {
+	Name     string
+	Protocol string
+}
+
+// List returns the registered records matching q.
+func (c *Client) List(ctx context.Context, q Query) ([]*Record, error) {
+	params := url.Values{}
+	if q.Name != "" {
+		params.Set("name", q.Name)
+	}
+	if q.Protocol != "" {
+		params.Set("protocol", q.Protocol)
+	}
+	endpoint := c.baseURL + agentsPath
+	if len(params) > 0 {
+		endpoint += "?" + params.Encode()
+	}
+	var out struct {
+		Agents []*Record `json:"agents"`
+	}
+	if err := c.do(ctx, http.MethodGet, endpoint, nil, &out); err != nil {
+		return nil, err
+	}
+	for _, r := range out.Agents {
+		if err := r.Verify(); err != nil {
+			return nil, err
+		}
+	}
+	return out.Agents, nil
+}
+
+func (c *Client) recordURL(did string) string {
+	return c.baseURL + agentsPath + "/" + url.PathEscape(did)
+}
+
+func (c *Client) do(ctx context.Context, method, endpoint string, body []byte, out interface{}) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var rd io.Reader
+	if body != nil {
+		rd = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, rd)
+	if err != nil {
+		return errors.New(errors.CodeInvalidInput, "invalid registry URL", errors.WithCause(err))
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return errors.New(errors.CodeTransportError, "registry request failed", errors.WithCause(err))
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return errors.New(errors.CodeTransportError, "failed to read registry response", errors.WithCause(err))
+	}
+	if resp.StatusCode != http.StatusOK {
+		var e errorBody
+		if json.Unmarshal(data, &e) == nil && e.Error.Code != "" {
+			details := map[string]interface{}{"status": resp.StatusCode}
+			if e.Error.Reason != "" {
+				details["reason"] = e.Error.Reason
+			}
+			return errors.New(e.Error.Code, e.Error.Message, errors.WithDetails(details))
+		}
+		return errors.New(errors.CodeTransportError, fmt.Sprintf("registry returned status %d", resp.StatusCode),
+			errors.WithDetails(map[string]interface{}{"status": resp.StatusCode}))
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return errors.New(errors.CodeTransportError, "registry response is not valid JSON", errors.WithCause(err))
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+// Package registry publishes and looks up agent metadata: an agent's DID,
+// name, endpoints and supported protocol versions.
+//
+// Entries are self-certifying: each Record is signed by the key of the DID
+// it describes, so neither the registry nor anyone else can forge or alter
+// one, and carries a sequence number so that an older record cannot replace
+// a newer one. Clients verify every record they receive.
+package registry
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/canonical"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/protocol"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/wallet"
+)
+
+// recordContext is the Ed25519ctx context for record signatures.
+const recordContext = "talos-registry-record-v1"
+
+// Reasons reported in Details["reason"].
+const (
+	ReasonMalformed    = "malformed"
+	ReasonBadSignature = "bad_signature"
+	ReasonStale        = "stale_record"
+	ReasonNotFound     = "not_found"
+)
+
+// Endpoint is a way to reach an agent, e.g. {"mcp", "https://..."}.
+type Endpoint struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+// Record describes an agent. IssuedAt is Unix seconds.
+type Record struct {
+	DID              string     `json:"did"`
+	Name             string     `json:"name"`
+	Endpoints        []Endpoint `json:"endpoints"`
+	ProtocolVersions []string   `json:"protocol_versions"`
+	Sequence         uint64     `json:"seq"`
+	IssuedAt         int64      `json:"iat"`
+	Signature        string     `json:"sig,omitempty"`
+}
+
+// MaxSequence is the largest sequence number; canonical JSON encodes numbers
+// as doubles, which are exact only up to 2^53-1.
+const MaxSequence = 1<<53 - 1
+
+// SignRecord signs r as the agent w. DID is set to the wallet's DID; a zero
+// IssuedAt defaults to now and a zero Sequence to the current time in
+// milliseconds, so that successive registrations supersede each other.
+func SignRecord(w *wallet.Wallet, r Record) (*Record, error) {
+	now := time.Now()
+	r.DID = w.DID()
+	r.Endpoints = append([]Endpoint(nil), r.Endpoints...)
+	r.ProtocolVersions = append([]string(nil), r.ProtocolVersions...)
+	if r.IssuedAt == 0 {
+		r.IssuedAt = now.Unix()
+	}
+	if r.Sequence == 0 {
+		r.Sequence = uint64(now.UnixMilli())
+	}
+	if err := r.validate(); err != nil {
+		return nil, err
+	}
+	msg, err := r.signingBytes()
+	if err != nil {
+		return nil, err
+	}
+	sig, err := w.SignWithOptions(msg, wallet.SignOptions{Variant: wallet.VariantEd25519ctx, Context: recordContext})
+	if err != nil {
+		return nil, err
+	}
+	r.Signature = base64.RawURLEncoding.EncodeToString(sig)
+	return &r, nil
+}
+
+// Verify checks that r is well formed and signed by the key of its DID.
+func (r *Record) Verify() error {
+	if err := r.validate(); err != nil {
+		return err
+	}
+	pub, err := wallet.PublicKeyFromDID(r.DID)
+	if err != nil {
+		return reject(errors.CodeInvalidInput, ReasonMalformed, "record DID is not a valid did:key")
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(r.Signature)
+	if err != nil {
+		return reject(errors.CodeCryptoError, ReasonBadSignature, "record signature is not valid base64url")
+	}
+	msg, err := r.signingBytes()
+	if err != nil {
+		return err
+	}
+	if !wallet.VerifyWithOptions(pub, msg, sig, wallet.SignOptions{Variant: wallet.VariantEd25519ctx, Context: recordContext}) {
+		return reject(errors.CodeCryptoError, ReasonBadSignature, "record signature does not verify")
+	}
+	return nil
+}
+
+// Supports reports whether the agent advertises a protocol version in rng.
+func (r *Record) Supports(rng protocol.Range) bool {
+	for _, s := range r.ProtocolVersions {
+		if v, err := protocol.ParseVersion(s); err == nil && rng.Contains(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// Endpoint returns the URL of the first endpoint of type t, or "".
+func (r *Record) Endpoint(t string) string {
+	for _, e := range r.Endpoints {
+		if e.Type == t {
+			return e.URL
+		}
+	}
+	return ""
+}
+
+func (r *Record) signingBytes() ([]byte, error) {
+	unsigned := *r
+	unsigned.Signature = ""
+	b, err := canonical.Marshal(&unsigned)
+	if err != nil {
+		return nil, errors.New(errors.CodeInvalidInput, "failed to canonicalize record", errors.WithCause(err))
+	}
+	return b, nil
+}
+
+func (r *Record) validate() error {
+	if _, err := wallet.PublicKeyFromDID(r.DID); err != nil {
+		return reject(errors.CodeInvalidInput, ReasonMalformed, "record DID is not a valid did:key")
+	}
+	if r.Sequence == 0 || r.Sequence > MaxSequence {
+		return reject(errors.CodeInvalidInput, ReasonMalformed, "record sequence number is out of range")
+	}
+	if r.Name == "" {
+		return reject(errors.CodeInvalidInput, ReasonMalformed, "record has no name")
+	}
+	for _, e := range r.Endpoints {
+		u, err := url.Parse(e.URL)
+		if e.Type == "" || err != nil || u.Scheme == "" || u.Host == "" {
+			return reject(errors.CodeInvalidInput, ReasonMalformed, fmt.Sprintf("invalid endpoint %q", e.URL))
+		}
+	}
+	for _, v := range r.ProtocolVersions {
+		if _, err := protocol.ParseVersion(v); err != nil {
+			return reject(errors.CodeInvalidInput, ReasonMalformed, fmt.Sprintf("invalid protocol version %q", v))
+		}
+	}
+	return nil
+}
+
+func reject(code errors.TalosErrorCode, reason, message string) error {
+	return errors.New(code, message, errors.WithDetails(map[string]interface{}{"reason": reason}))
+}
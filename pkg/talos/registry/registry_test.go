@@ -0,0 +1,139 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/wallet"
+)
+
+func newWallet(t *testing.T, b byte) *wallet.Wallet {
+	t.Helper()
+	w, err := wallet.FromSeed(bytes.Repeat([]byte{b}, 32), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func expectError(t *testing.T, err error, code errors.TalosErrorCode, reason string) {
+	t.Helper()
+	te, ok := err.(*errors.TalosError)
+	if !ok {
+		t.Fatalf("expected *TalosError, got %v", err)
+	}
+	if te.Code != code {
+		t.Errorf("expected code %s, got %s (%v)", code, te.Code, err)
+	}
+	if got, _ := te.Details["reason"].(string); got != reason {
+		t.Errorf("expected reason %q, got %q", reason, got)
+	}
+}
+
+func record(t *testing.T, w *wallet.Wallet, name string, seq uint64, versions ...string) *Record {
+	t.Helper()
+	r, err := SignRecord(w, Record{
+		Name:             name,
+		Endpoints:        []Endpoint{{Type: "mcp", URL: "https://" + name + ".example/mcp"}},
+		ProtocolVersions: versions,
+		Sequence:         seq,
+	})
+	if err != nil {
+		t.Fatalf("SignRecord failed: %v", err)
+	}
+	return r
+}
+
+func TestRegisterAndLookup(t *testing.T) {
+	srv := httptest.NewServer(NewServer())
+	defer srv.Close()
+	c := NewClient(srv.URL, WithHTTPClient(srv.Client()))
+	ctx := context.Background()
+	alice, bob := newWallet(t, 1), newWallet(t, 2)
+
+	if err := c.Register(ctx, record(t, alice, "alice", 1, "1.0.0", "1.2.0")); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if err := c.Register(ctx, record(t, bob, "bob", 1, "2.0.0")); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	got, err := c.Lookup(ctx, alice.DID())
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	if got.Name != "alice" || got.Endpoint("mcp") != "https://alice.example/mcp" {
+		t.Errorf("unexpected record: %+v", got)
+	}
+	_, err = c.Lookup(ctx, newWallet(t, 3).DID())
+	expectError(t, err, errors.CodeInvalidInput, ReasonNotFound)
+
+	// Updates need a higher sequence number.
+	if err := c.Register(ctx, record(t, alice, "alice", 2, "1.3.0")); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	expectError(t, c.Register(ctx, record(t, alice, "alice-old", 1, "1.0.0")), errors.CodeReplayDetected, ReasonStale)
+
+	all, err := c.List(ctx, Query{})
+	if err != nil || len(all) != 2 {
+		t.Fatalf("expected 2 records, got %d (%v)", len(all), err)
+	}
+	v1, err := c.List(ctx, Query{Protocol: "^1.3"})
+	if err != nil || len(v1) != 1 || v1[0].DID != alice.DID() {
+		t.Errorf("unexpected protocol filter result: %v (%v)", v1, err)
+	}
+	named, err := c.List(ctx, Query{Name: "bob"})
+	if err != nil || len(named) != 1 || named[0].DID != bob.DID() {
+		t.Errorf("unexpected name filter result: %v (%v)", named, err)
+	}
+}
+
+func TestForgery(t *testing.T) {
+	srv := httptest.NewServer(NewServer())
+	defer srv.Close()
+	c := NewClient(srv.URL)
+	ctx := context.Background()
+	alice, mallory := newWallet(t, 1), newWallet(t, 2)
+
+	// Mallory cannot register a record for Alice's DID.
+	forged := record(t, mallory, "alice", 5)
+	forged.DID = alice.DID()
+	expectError(t, forged.Verify(), errors.CodeCryptoError, ReasonBadSignature)
+	body, _ := json.Marshal(forged)
+	req, _ := http.NewRequest(http.MethodPut, srv.URL+agentsPath+"/"+alice.DID(), bytes.NewReader(body))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for forged record, got %d", resp.StatusCode)
+	}
+
+	// Altering a signed record breaks it.
+	r := record(t, alice, "alice", 1)
+	r.Endpoints[0].URL = "https://evil.example/mcp"
+	expectError(t, c.Register(ctx, r), errors.CodeCryptoError, ReasonBadSignature)
+
+	// A malicious registry cannot serve altered records.
+	evil := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r := record(t, alice, "alice", 1)
+		r.Name = "mallory"
+		_ = json.NewEncoder(w).Encode(r)
+	}))
+	defer evil.Close()
+	_, err = NewClient(evil.URL).Lookup(ctx, alice.DID())
+	expectError(t, err, errors.CodeCryptoError, ReasonBadSignature)
+
+	if _, err := SignRecord(alice, Record{Name: "x", ProtocolVersions: []string{"one"}}); err == nil {
+		t.Error("expected error for invalid protocol version")
+	}
+	if _, err := SignRecord(alice, Record{Name: "x", Endpoints: []Endpoint{{Type: "mcp", URL: "not a url"}}}); err == nil {
+		t.Error("expected error for invalid endpoint")
+	}
+}
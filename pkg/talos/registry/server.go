@@ -0,0 +1,158 @@
+package registry
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/protocol"
+)
+
+// agentsPath is the collection path; a record lives at agentsPath + "/" +
+// url.PathEscape(did).
+const agentsPath = "/v1/agents"
+
+// maxRecordBytes bounds request bodies accepted by Server.
+const maxRecordBytes = 64 * 1024
+
+// Server is an in-memory reference registry, e.g. for tests with
+// httptest.NewServer. It serves:
+//
+//	PUT /v1/agents/{did}  register or update a signed record
+//	GET /v1/agents/{did}  look up a record
+//	GET /v1/agents        list records, filtered by ?name= and ?protocol=
+//
+// It only stores records that verify, and only if their sequence number is
+// higher than the stored one.
+type Server struct {
+	mu      sync.RWMutex
+	records map[string]*Record
+}
+
+// NewServer returns an empty registry.
+func NewServer() *Server {
+	return &Server{records: make(map[string]*Record)}
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == agentsPath {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		s.list(w, r)
+		return
+	}
+	if !strings.HasPrefix(r.URL.Path, agentsPath+"/") {
+		http.NotFound(w, r)
+		return
+	}
+	did, err := url.PathUnescape(strings.TrimPrefix(r.URL.EscapedPath(), agentsPath+"/"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, reject(errors.CodeInvalidInput, ReasonMalformed, "invalid DID in path"))
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		s.mu.RLock()
+		rec := s.records[did]
+		s.mu.RUnlock()
+		if rec == nil {
+			writeError(w, http.StatusNotFound, reject(errors.CodeInvalidInput, ReasonNotFound, "agent is not registered"))
+			return
+		}
+		writeJSON(w, http.StatusOK, rec)
+	case http.MethodPut:
+		s.put(w, r, did)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) put(w http.ResponseWriter, r *http.Request, did string) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRecordBytes))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, reject(errors.CodeInvalidInput, ReasonMalformed, "failed to read record"))
+		return
+	}
+	var rec Record
+	if err := json.Unmarshal(body, &rec); err != nil {
+		writeError(w, http.StatusBadRequest, reject(errors.CodeInvalidInput, ReasonMalformed, "record is not valid JSON"))
+		return
+	}
+	if rec.DID != did {
+		writeError(w, http.StatusBadRequest, reject(errors.CodeInvalidInput, ReasonMalformed, "record DID does not match the path"))
+		return
+	}
+	if err := rec.Verify(); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cur := s.records[did]; cur != nil && cur.Sequence >= rec.Sequence {
+		writeError(w, http.StatusConflict, reject(errors.CodeReplayDetected, ReasonStale, "a record with a higher sequence number is registered"))
+		return
+	}
+	s.records[did] = &rec
+	writeJSON(w, http.StatusOK, &rec)
+}
+
+func (s *Server) list(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var rng *protocol.Range
+	if p := q.Get("protocol"); p != "" {
+		parsed, err := protocol.ParseRange(p)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, reject(errors.CodeInvalidInput, ReasonMalformed, "invalid protocol range"))
+			return
+		}
+		rng = &parsed
+	}
+	name := q.Get("name")
+
+	s.mu.RLock()
+	out := make([]*Record, 0, len(s.records))
+	for _, rec := range s.records {
+		if (name == "" || rec.Name == name) && (rng == nil || rec.Supports(*rng)) {
+			out = append(out, rec)
+		}
+	}
+	s.mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].DID < out[j].DID })
+	writeJSON(w, http.StatusOK, map[string]interface{}{"agents": out})
+}
+
+// errorBody is the error response format shared by Server and Client.
+type errorBody struct {
+	Error struct {
+		Code    errors.TalosErrorCode `json:"code"`
+		Message string                `json:"message"`
+		Reason  string                `json:"reason,omitempty"`
+	} `json:"error"`
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	var body errorBody
+	body.Error.Code = errors.CodeInvalidInput
+	body.Error.Message = err.Error()
+	if te, ok := err.(*errors.TalosError); ok {
+		body.Error.Code = te.Code
+		body.Error.Message = te.Message
+		body.Error.Reason, _ = te.Details["reason"].(string)
+	}
+	writeJSON(w, status, body)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
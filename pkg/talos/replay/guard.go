@@ -0,0 +1,136 @@
+package replay
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"strconv"
+	"time"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+)
+
+const (
+	// DefaultWindow is the accepted clock skew either side of now.
+	DefaultWindow = 5 * time.Minute
+
+	// MaxNonceSize bounds nonces so a peer cannot bloat the cache.
+	MaxNonceSize = 128
+)
+
+// Reasons reported in TalosError.Details["reason"].
+const (
+	ReasonDuplicate    = "duplicate"
+	ReasonOutOfWindow  = "out_of_window"
+	ReasonInvalidNonce = "invalid_nonce"
+)
+
+// Stamp is the replay-protection material a sender includes in the signed
+// payload of a request.
+type Stamp struct {
+	Nonce     string `json:"nonce"`
+	Timestamp int64  `json:"ts"` // Unix seconds
+}
+
+// NewStamp returns a stamp with a random 128-bit nonce and the current time.
+func NewStamp() (Stamp, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return Stamp{}, errors.New(errors.CodeCryptoError, "failed to generate nonce", errors.WithCause(err))
+	}
+	return Stamp{Nonce: base64.RawURLEncoding.EncodeToString(b), Timestamp: time.Now().Unix()}, nil
+}
+
+// Guard rejects messages whose nonce has already been seen from the same
+// sender or whose timestamp is outside the skew window.
+type Guard struct {
+	store  NonceStore
+	window time.Duration
+	now    func() time.Time
+}
+
+// Option configures a Guard.
+type Option func(*Guard)
+
+// WithWindow sets the accepted clock skew.
+func WithWindow(d time.Duration) Option {
+	return func(g *Guard) {
+		g.window = d
+	}
+}
+
+// WithStore sets the nonce store, e.g. one shared between processes.
+func WithStore(store NonceStore) Option {
+	return func(g *Guard) {
+		g.store = store
+	}
+}
+
+// WithClock overrides the time source.
+func WithClock(now func() time.Time) Option {
+	return func(g *Guard) {
+		g.now = now
+	}
+}
+
+// NewGuard creates a guard. By default it uses a MemoryStore of
+// DefaultCapacity and DefaultWindow.
+func NewGuard(opts ...Option) *Guard {
+	g := &Guard{window: DefaultWindow, now: time.Now}
+	for _, opt := range opts {
+		opt(g)
+	}
+	if g.store == nil {
+		store := NewMemoryStore(DefaultCapacity)
+		store.now = g.now
+		g.store = store
+	}
+	return g
+}
+
+// Check accepts a message from sender with the given nonce and timestamp
+// exactly once. Nonces are scoped per sender and remembered until the
+// timestamp leaves the window, after which the timestamp check rejects it.
+// Rejections are TalosErrors with CodeReplayDetected.
+func (g *Guard) Check(ctx context.Context, sender, nonce string, timestamp time.Time) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if nonce == "" || len(nonce) > MaxNonceSize {
+		return reject(ReasonInvalidNonce, "nonce is empty or too long", sender)
+	}
+
+	now := g.now()
+	if timestamp.Before(now.Add(-g.window)) || timestamp.After(now.Add(g.window)) {
+		return reject(ReasonOutOfWindow, "message timestamp outside accepted window", sender,
+			"timestamp", timestamp.UTC().Format(time.RFC3339),
+			"window_seconds", int64(g.window/time.Second))
+	}
+
+	fresh, err := g.store.CheckAndStore(ctx, sender+"\x00"+nonce, timestamp.Add(g.window))
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return reject(ReasonDuplicate, "message nonce already seen", sender)
+	}
+	return nil
+}
+
+// CheckStamp is Check for a Stamp.
+func (g *Guard) CheckStamp(ctx context.Context, sender string, s Stamp) error {
+	return g.Check(ctx, sender, s.Nonce, time.Unix(s.Timestamp, 0))
+}
+
+// CheckSequence is Check using a per-sender sequence number as the nonce.
+func (g *Guard) CheckSequence(ctx context.Context, sender string, seq uint64, timestamp time.Time) error {
+	return g.Check(ctx, sender, "seq:"+strconv.FormatUint(seq, 10), timestamp)
+}
+
+func reject(reason, message, sender string, kv ...interface{}) error {
+	details := map[string]interface{}{"reason": reason, "sender": sender}
+	for i := 0; i+1 < len(kv); i += 2 {
+		details[kv[i].(string)] = kv[i+1]
+	}
+	return errors.New(errors.CodeReplayDetected, message, errors.WithDetails(details))
+}
@@ -0,0 +1,136 @@
+package replay
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+)
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func expectReason(t *testing.T, err error, reason string) {
+	t.Helper()
+	te, ok := err.(*errors.TalosError)
+	if !ok || te.Code != errors.CodeReplayDetected || te.Details["reason"] != reason {
+		t.Errorf("expected %s/%s, got %v", errors.CodeReplayDetected, reason, err)
+	}
+}
+
+func TestGuard_Duplicate(t *testing.T) {
+	g := NewGuard()
+	ctx := context.Background()
+	now := time.Now()
+
+	if err := g.Check(ctx, "did:key:a", "n1", now); err != nil {
+		t.Fatalf("first Check failed: %v", err)
+	}
+	expectReason(t, g.Check(ctx, "did:key:a", "n1", now), ReasonDuplicate)
+
+	// Same nonce from another sender is independent.
+	if err := g.Check(ctx, "did:key:b", "n1", now); err != nil {
+		t.Errorf("nonce should be scoped per sender: %v", err)
+	}
+}
+
+func TestGuard_Window(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	g := NewGuard(WithWindow(time.Minute), WithClock(clock.Now))
+	ctx := context.Background()
+
+	expectReason(t, g.Check(ctx, "s", "old", clock.Now().Add(-2*time.Minute)), ReasonOutOfWindow)
+	expectReason(t, g.Check(ctx, "s", "future", clock.Now().Add(2*time.Minute)), ReasonOutOfWindow)
+	expectReason(t, g.Check(ctx, "s", "", clock.Now()), ReasonInvalidNonce)
+
+	ts := clock.Now()
+	if err := g.Check(ctx, "s", "n", ts); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	// Once the timestamp leaves the window the nonce may be forgotten, but
+	// a replay is still rejected by the window check.
+	clock.Advance(2 * time.Minute)
+	expectReason(t, g.Check(ctx, "s", "n", ts), ReasonOutOfWindow)
+}
+
+func TestGuard_Stamp(t *testing.T) {
+	g := NewGuard()
+	s, err := NewStamp()
+	if err != nil {
+		t.Fatalf("NewStamp failed: %v", err)
+	}
+	if err := g.CheckStamp(context.Background(), "s", s); err != nil {
+		t.Fatalf("CheckStamp failed: %v", err)
+	}
+	expectReason(t, g.CheckStamp(context.Background(), "s", s), ReasonDuplicate)
+
+	if err := g.CheckSequence(context.Background(), "s", 7, time.Now()); err != nil {
+		t.Fatalf("CheckSequence failed: %v", err)
+	}
+	expectReason(t, g.CheckSequence(context.Background(), "s", 7, time.Now()), ReasonDuplicate)
+}
+
+func TestMemoryStore_ExpiryAndCapacity(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	m := NewMemoryStore(2)
+	m.now = clock.Now
+	ctx := context.Background()
+
+	for _, k := range []string{"a", "b"} {
+		if ok, err := m.CheckAndStore(ctx, k, clock.Now().Add(time.Second)); !ok || err != nil {
+			t.Fatalf("store %s: %v %v", k, ok, err)
+		}
+	}
+	if _, err := m.CheckAndStore(ctx, "c", clock.Now().Add(time.Second)); err == nil {
+		t.Error("expected error when full")
+	}
+
+	clock.Advance(2 * time.Second)
+	if ok, err := m.CheckAndStore(ctx, "a", clock.Now().Add(time.Second)); !ok || err != nil {
+		t.Errorf("expired key should be accepted again: %v %v", ok, err)
+	}
+	if m.Len() != 1 {
+		t.Errorf("expected expired entries to be swept, got %d", m.Len())
+	}
+}
+
+func TestGuard_Concurrent(t *testing.T) {
+	g := NewGuard()
+	ctx := context.Background()
+	now := time.Now()
+
+	var accepted atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 64; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// Every nonce is submitted by 4 goroutines; exactly one may win.
+			if g.Check(ctx, "s", fmt.Sprintf("n%d", i%16), now) == nil {
+				accepted.Add(1)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if accepted.Load() != 16 {
+		t.Errorf("expected 16 accepted, got %d", accepted.Load())
+	}
+}
@@ -0,0 +1,102 @@
+package replay
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+)
+
+// DefaultCapacity bounds a MemoryStore created with a non-positive capacity.
+const DefaultCapacity = 100000
+
+// NonceStore remembers seen nonces until they expire. Implementations backed
+// by a shared database or cache let several processes share one guard.
+type NonceStore interface {
+	// CheckAndStore atomically records key until expiresAt. It reports false
+	// if key is already recorded and has not expired.
+	CheckAndStore(ctx context.Context, key string, expiresAt time.Time) (bool, error)
+}
+
+// MemoryStore is a bounded, time-expiring in-memory NonceStore. It is safe
+// for concurrent use.
+//
+// When full of unexpired nonces it refuses new ones rather than forgetting
+// old ones, since forgetting would reopen a replay window.
+type MemoryStore struct {
+	mu       sync.Mutex
+	capacity int
+	now      func() time.Time
+	seen     map[string]time.Time
+	expiry   expiryHeap
+}
+
+// NewMemoryStore creates a store holding at most capacity nonces.
+func NewMemoryStore(capacity int) *MemoryStore {
+	if capacity <= 0 {
+		capacity = DefaultCapacity
+	}
+	return &MemoryStore{
+		capacity: capacity,
+		now:      time.Now,
+		seen:     make(map[string]time.Time),
+	}
+}
+
+// CheckAndStore implements NonceStore.
+func (m *MemoryStore) CheckAndStore(_ context.Context, key string, expiresAt time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.expireLocked(now)
+
+	if exp, ok := m.seen[key]; ok && exp.After(now) {
+		return false, nil
+	}
+	if len(m.seen) >= m.capacity {
+		return false, errors.New(errors.CodeDenied, "replay cache is full",
+			errors.WithDetails(map[string]interface{}{"capacity": m.capacity}))
+	}
+	m.seen[key] = expiresAt
+	heap.Push(&m.expiry, expiryItem{key: key, at: expiresAt})
+	return true, nil
+}
+
+// Len returns the number of remembered nonces, including any that have
+// expired but not yet been swept.
+func (m *MemoryStore) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.seen)
+}
+
+func (m *MemoryStore) expireLocked(now time.Time) {
+	for m.expiry.Len() > 0 && !m.expiry[0].at.After(now) {
+		item := heap.Pop(&m.expiry).(expiryItem)
+		// Only drop the map entry if it was not re-stored with a later expiry.
+		if exp, ok := m.seen[item.key]; ok && exp.Equal(item.at) {
+			delete(m.seen, item.key)
+		}
+	}
+}
+
+type expiryItem struct {
+	key string
+	at  time.Time
+}
+
+type expiryHeap []expiryItem
+
+func (h expiryHeap) Len() int           { return len(h) }
+func (h expiryHeap) Less(i, j int) bool { return h[i].at.Before(h[j].at) }
+func (h expiryHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *expiryHeap) Push(x any)        { *h = append(*h, x.(expiryItem)) }
+func (h *expiryHeap) Pop() any {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
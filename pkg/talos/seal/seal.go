@@ -0,0 +1,205 @@
+package seal
+
+import (
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/crypto"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/wallet"
+)
+
+const (
+	version = 1
+
+	modeAnonymous     = 0
+	modeAuthenticated = 1
+
+	keySize       = 32
+	headerSize    = 2 + keySize // version, mode, ephemeral public key
+	signatureSize = 64
+
+	kdfInfo    = "talos-seal-v1"
+	sigContext = "talos-seal-v1-sender"
+)
+
+// Overhead is the size a sealed message adds to an anonymous plaintext.
+const Overhead = headerSize + chacha20poly1305.Overhead
+
+// Message is an opened sealed message.
+type Message struct {
+	Plaintext []byte
+	// Sender is the verified DID of the sender, or empty for anonymous
+	// messages.
+	Sender string
+}
+
+// Authenticated reports whether the sender identity was verified.
+func (m *Message) Authenticated() bool {
+	return m.Sender != ""
+}
+
+// Option configures Seal.
+type Option func(*sealer)
+
+type sealer struct {
+	sender *wallet.Wallet
+}
+
+// WithSender authenticates the message as coming from w. The sender DID and
+// signature travel inside the ciphertext, so only the recipient learns who
+// sent it.
+func WithSender(w *wallet.Wallet) Option {
+	return func(s *sealer) {
+		s.sender = w
+	}
+}
+
+// Seal encrypts plaintext to the X25519 key derived from recipientDID (a
+// did:key) using a fresh ephemeral key. Only the holder of the matching
+// wallet can Open it.
+//
+// Layout: version | mode | ephemeral X25519 key | ChaCha20-Poly1305 ciphertext.
+// The key is HKDF-SHA256 over the shared secret, salted with both public
+// keys; the nonce is zero since every key is used once.
+func Seal(recipientDID string, plaintext []byte, opts ...Option) ([]byte, error) {
+	var s sealer
+	for _, opt := range opts {
+		opt(&s)
+	}
+
+	edPub, err := wallet.PublicKeyFromDID(recipientDID)
+	if err != nil {
+		return nil, err
+	}
+	recipient, err := crypto.X25519PublicKey(edPub)
+	if err != nil {
+		return nil, errors.New(errors.CodeInvalidInput, "recipient key cannot be used for encryption", errors.WithCause(err))
+	}
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, errors.New(errors.CodeCryptoError, "failed to generate ephemeral key", errors.WithCause(err))
+	}
+	shared, err := ephemeral.ECDH(recipient)
+	if err != nil {
+		return nil, errors.New(errors.CodeCryptoError, "key agreement failed", errors.WithCause(err))
+	}
+
+	header := make([]byte, 0, headerSize)
+	header = append(header, version, modeAnonymous)
+	header = append(header, ephemeral.PublicKey().Bytes()...)
+
+	body := plaintext
+	if s.sender != nil {
+		header[1] = modeAuthenticated
+		body = authenticatedBody(s.sender, header, recipientDID, plaintext)
+	}
+
+	aead, err := newAEAD(shared, header[2:], recipient.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	return aead.Seal(header, nonce, body, header), nil
+}
+
+// Open decrypts a sealed message addressed to w. Authenticated messages are
+// only returned if the sender signature verifies.
+func Open(w *wallet.Wallet, sealed []byte) (*Message, error) {
+	if len(sealed) < Overhead {
+		return nil, errors.New(errors.CodeInvalidInput, "sealed message too short")
+	}
+	if sealed[0] != version {
+		return nil, errors.New(errors.CodeProtocolMismatch, "unsupported sealed message version",
+			errors.WithDetails(map[string]interface{}{"version": sealed[0]}))
+	}
+	mode := sealed[1]
+	if mode != modeAnonymous && mode != modeAuthenticated {
+		return nil, errors.New(errors.CodeInvalidInput, "unknown sealed message mode")
+	}
+	header, ciphertext := sealed[:headerSize], sealed[headerSize:]
+
+	shared, err := w.SharedSecret(header[2:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(shared, header[2:], w.X25519PublicKey())
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	body, err := aead.Open(nil, nonce, ciphertext, header)
+	if err != nil {
+		return nil, errors.New(errors.CodeCryptoError, "failed to open sealed message", errors.WithCause(err))
+	}
+
+	if mode == modeAnonymous {
+		return &Message{Plaintext: body}, nil
+	}
+	return openAuthenticated(body, header, w.DID())
+}
+
+// authenticatedBody prefixes the plaintext with the sender DID and a
+// signature binding it to this exact envelope and recipient.
+//
+//	did_len u16 | did | signature | plaintext
+func authenticatedBody(sender *wallet.Wallet, header []byte, recipientDID string, plaintext []byte) []byte {
+	did := sender.DID()
+	sig := sender.Sign(signedTranscript(header, did, recipientDID, plaintext))
+	body := binary.BigEndian.AppendUint16(nil, uint16(len(did)))
+	body = append(body, did...)
+	body = append(body, sig...)
+	return append(body, plaintext...)
+}
+
+func openAuthenticated(body, header []byte, recipientDID string) (*Message, error) {
+	if len(body) < 2 {
+		return nil, errors.New(errors.CodeInvalidInput, "malformed authenticated message")
+	}
+	n := int(binary.BigEndian.Uint16(body))
+	if len(body) < 2+n+signatureSize {
+		return nil, errors.New(errors.CodeInvalidInput, "malformed authenticated message")
+	}
+	did := string(body[2 : 2+n])
+	sig := body[2+n : 2+n+signatureSize]
+	plaintext := body[2+n+signatureSize:]
+
+	pub, err := wallet.PublicKeyFromDID(did)
+	if err != nil {
+		return nil, err
+	}
+	if !wallet.Verify(pub, signedTranscript(header, did, recipientDID, plaintext), sig) {
+		return nil, errors.New(errors.CodeCryptoError, "sender signature is invalid",
+			errors.WithDetails(map[string]interface{}{"sender": did}))
+	}
+	return &Message{Plaintext: plaintext, Sender: did}, nil
+}
+
+func signedTranscript(header []byte, senderDID, recipientDID string, plaintext []byte) []byte {
+	h := sha256.New()
+	for _, part := range [][]byte{[]byte(sigContext), header, []byte(senderDID), []byte(recipientDID), plaintext} {
+		_ = binary.Write(h, binary.BigEndian, uint32(len(part)))
+		h.Write(part)
+	}
+	return h.Sum(nil)
+}
+
+func newAEAD(shared, ephemeralPub, recipientPub []byte) (cipher.AEAD, error) {
+	salt := append(append([]byte(nil), ephemeralPub...), recipientPub...)
+	key := make([]byte, chacha20poly1305.KeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, shared, salt, []byte(kdfInfo)), key); err != nil {
+		return nil, errors.New(errors.CodeCryptoError, "key derivation failed", errors.WithCause(err))
+	}
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		return nil, errors.New(errors.CodeCryptoError, "failed to init cipher", errors.WithCause(err))
+	}
+	return aead, nil
+}
@@ -0,0 +1,99 @@
+package seal
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/wallet"
+)
+
+func expectCode(t *testing.T, err error, code errors.TalosErrorCode) {
+	t.Helper()
+	te, ok := err.(*errors.TalosError)
+	if !ok || te.Code != code {
+		t.Errorf("expected %s, got %v", code, err)
+	}
+}
+
+func TestSealOpen_Anonymous(t *testing.T) {
+	recipient, _ := wallet.Generate("agent")
+	secret := []byte("provisioning token")
+
+	sealed, err := Seal(recipient.DID(), secret)
+	if err != nil {
+		t.Fatalf("Seal failed: %v", err)
+	}
+	if len(sealed) != len(secret)+Overhead {
+		t.Errorf("unexpected length %d", len(sealed))
+	}
+	if bytes.Contains(sealed, secret) {
+		t.Error("ciphertext contains plaintext")
+	}
+
+	msg, err := Open(recipient, sealed)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if !bytes.Equal(msg.Plaintext, secret) || msg.Authenticated() {
+		t.Errorf("unexpected message %+v", msg)
+	}
+
+	// Every seal uses a fresh ephemeral key.
+	again, _ := Seal(recipient.DID(), secret)
+	if bytes.Equal(again, sealed) {
+		t.Error("sealing is deterministic")
+	}
+}
+
+func TestSealOpen_Authenticated(t *testing.T) {
+	sender, _ := wallet.Generate("orchestrator")
+	recipient, _ := wallet.Generate("agent")
+
+	sealed, err := Seal(recipient.DID(), []byte("hi"), WithSender(sender))
+	if err != nil {
+		t.Fatalf("Seal failed: %v", err)
+	}
+	if bytes.Contains(sealed, []byte(sender.DID())) {
+		t.Error("sender DID visible in sealed message")
+	}
+
+	msg, err := Open(recipient, sealed)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if msg.Sender != sender.DID() || string(msg.Plaintext) != "hi" {
+		t.Errorf("unexpected message %+v", msg)
+	}
+}
+
+func TestOpen_Failures(t *testing.T) {
+	recipient, _ := wallet.Generate("agent")
+	other, _ := wallet.Generate("other")
+	sealed, _ := Seal(recipient.DID(), []byte("secret"))
+
+	_, err := Open(other, sealed)
+	expectCode(t, err, errors.CodeCryptoError)
+
+	tampered := append([]byte(nil), sealed...)
+	tampered[len(tampered)-1] ^= 1
+	_, err = Open(recipient, tampered)
+	expectCode(t, err, errors.CodeCryptoError)
+
+	// Flipping the mode byte is caught by the AEAD, not misparsed.
+	flipped := append([]byte(nil), sealed...)
+	flipped[1] = modeAuthenticated
+	_, err = Open(recipient, flipped)
+	expectCode(t, err, errors.CodeCryptoError)
+
+	_, err = Open(recipient, sealed[:10])
+	expectCode(t, err, errors.CodeInvalidInput)
+
+	future := append([]byte(nil), sealed...)
+	future[0] = 9
+	_, err = Open(recipient, future)
+	expectCode(t, err, errors.CodeProtocolMismatch)
+
+	_, err = Seal("did:web:example.com", []byte("x"))
+	expectCode(t, err, errors.CodeInvalidInput)
+}
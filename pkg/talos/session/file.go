@@ -0,0 +1,263 @@
+package session
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/crypto"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+)
+
+const (
+	// KeySize is the size of the at-rest encryption key for FileStore.
+	KeySize = 32
+
+	fileExt   = ".session"
+	fileMagic = "TSS1"
+)
+
+// FileStore is a SessionStore that keeps one encrypted file per peer.
+//
+// Writes are atomic (temp file, fsync, rename, directory fsync) so a crash
+// leaves either the old or the new state on disk, never a torn file. State is
+// sealed with AES-256-GCM bound to the peer DID, so files cannot be read
+// without the key or swapped between peers.
+//
+// Per-peer locks serialize writers within a process, and the version check
+// then guards against forks. Nothing locks the directory across processes:
+// two processes saving the same peer can both pass the check and one write
+// is lost, so only one process may use a directory at a time.
+type FileStore struct {
+	dir   string
+	aead  cipher.AEAD
+	locks peerLocks
+}
+
+// NewFileStore opens (creating if needed) a session directory encrypted with
+// the given 32-byte key.
+func NewFileStore(dir string, key []byte) (*FileStore, error) {
+	if len(key) != KeySize {
+		return nil, errors.New(errors.CodeInvalidInput, fmt.Sprintf("session key must be %d bytes", KeySize))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.New(errors.CodeCryptoError, "failed to init cipher", errors.WithCause(err))
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.New(errors.CodeCryptoError, "failed to init cipher", errors.WithCause(err))
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir, aead: aead}, nil
+}
+
+// Load reads and decrypts the session for peerDID.
+func (f *FileStore) Load(_ context.Context, peerDID string) (*State, error) {
+	unlock := f.locks.lock(peerDID)
+	defer unlock()
+	return f.read(peerDID)
+}
+
+// Save atomically writes state if its version follows the stored one.
+func (f *FileStore) Save(_ context.Context, state *State) error {
+	if err := validateState(state); err != nil {
+		return err
+	}
+	unlock := f.locks.lock(state.PeerDID)
+	defer unlock()
+
+	var stored uint64
+	current, err := f.read(state.PeerDID)
+	switch {
+	case err == nil:
+		stored = current.Version
+	case isCode(err, errors.CodeSessionNotFound):
+	default:
+		return err
+	}
+	if state.Version != stored+1 {
+		return conflict(state.PeerDID, stored, state.Version)
+	}
+	return f.write(state)
+}
+
+// Delete removes the session file for peerDID.
+func (f *FileStore) Delete(_ context.Context, peerDID string) error {
+	unlock := f.locks.lock(peerDID)
+	defer unlock()
+	if err := os.Remove(f.path(peerDID)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return syncDir(f.dir)
+}
+
+// Peers returns the DIDs of all sessions in the directory.
+func (f *FileStore) Peers(_ context.Context) ([]string, error) {
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		return nil, err
+	}
+	var peers []string
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), fileExt) {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(f.dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		s, err := f.open(b, strings.TrimSuffix(e.Name(), fileExt))
+		if err != nil {
+			return nil, err
+		}
+		peers = append(peers, s.PeerDID)
+	}
+	return peers, nil
+}
+
+// fileID is the on-disk name of a peer's session. It doubles as the AEAD
+// associated data, binding each ciphertext to its file.
+func fileID(peerDID string) string {
+	return hex.EncodeToString(crypto.SHA256([]byte(peerDID)))
+}
+
+func (f *FileStore) path(peerDID string) string {
+	return filepath.Join(f.dir, fileID(peerDID)+fileExt)
+}
+
+func (f *FileStore) read(peerDID string) (*State, error) {
+	b, err := os.ReadFile(f.path(peerDID))
+	if os.IsNotExist(err) {
+		return nil, notFound(peerDID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s, err := f.open(b, fileID(peerDID))
+	if err != nil {
+		return nil, err
+	}
+	if s.PeerDID != peerDID {
+		return nil, errors.New(errors.CodeCryptoError, "session file does not belong to peer")
+	}
+	return s, nil
+}
+
+// open decrypts a session file whose name (without extension) is id.
+func (f *FileStore) open(b []byte, id string) (*State, error) {
+	ns := f.aead.NonceSize()
+	if len(b) < len(fileMagic)+ns || string(b[:len(fileMagic)]) != fileMagic {
+		return nil, errors.New(errors.CodeCryptoError, "malformed session file")
+	}
+	b = b[len(fileMagic):]
+	nonce, sealed := b[:ns], b[ns:]
+
+	plain, err := f.aead.Open(nil, nonce, sealed, []byte(id))
+	if err != nil {
+		return nil, errors.New(errors.CodeCryptoError, "failed to decrypt session state", errors.WithCause(err))
+	}
+	var s State
+	if err := json.Unmarshal(plain, &s); err != nil {
+		return nil, errors.New(errors.CodeCryptoError, "corrupt session state", errors.WithCause(err))
+	}
+	return &s, nil
+}
+
+func (f *FileStore) write(state *State) error {
+	plain, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, f.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return errors.New(errors.CodeCryptoError, "failed to generate nonce", errors.WithCause(err))
+	}
+	out := append([]byte(fileMagic), nonce...)
+	out = f.aead.Seal(out, nonce, plain, []byte(fileID(state.PeerDID)))
+	return writeFileAtomic(f.path(state.PeerDID), out)
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName) // no-op after a successful rename
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	// Some platforms do not support fsync on directories; the rename is
+	// still atomic there.
+	_ = d.Sync()
+	return nil
+}
+
+// peerLocks hands out one mutex per peer DID, dropping it when unused.
+type peerLocks struct {
+	mu    sync.Mutex
+	locks map[string]*peerLock
+}
+
+type peerLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+func (p *peerLocks) lock(peerDID string) (unlock func()) {
+	p.mu.Lock()
+	if p.locks == nil {
+		p.locks = make(map[string]*peerLock)
+	}
+	l, ok := p.locks[peerDID]
+	if !ok {
+		l = &peerLock{}
+		p.locks[peerDID] = l
+	}
+	l.refs++
+	p.mu.Unlock()
+
+	l.mu.Lock()
+	return func() {
+		l.mu.Unlock()
+		p.mu.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(p.locks, peerDID)
+		}
+		p.mu.Unlock()
+	}
+}
@@ -0,0 +1,270 @@
+package session
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+)
+
+const (
+	DefaultMaxSessions = 10000
+	DefaultIdleTimeout = 10 * time.Minute
+)
+
+// Metrics is a point-in-time snapshot of SessionManager counters.
+type Metrics struct {
+	Sessions   int    // sessions currently cached
+	Operations uint64 // successful operations
+	Errors     uint64 // operations that returned an error
+	Hits       uint64 // operations served from the cache
+	Misses     uint64 // operations that had to consult the store
+	Evictions  uint64 // sessions dropped for capacity or idleness
+}
+
+// SessionManager runs operations on many peer sessions concurrently.
+//
+// Operations on the same peer are serialized; operations on different peers
+// run in parallel without sharing a lock. Session state is cached in memory,
+// bounded by WithMaxSessions (least recently used first) and dropped after
+// WithIdleTimeout; every change is written through to the SessionStore so an
+// evicted session is simply reloaded on next use. Sessions with operations in
+// flight are never evicted.
+type SessionManager struct {
+	store       SessionStore
+	maxSessions int
+	idleTimeout time.Duration
+	now         func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*entry
+	lru     *list.List // front is most recently used
+
+	operations, errs, hits, misses, evictions atomic.Uint64
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+type entry struct {
+	peerDID  string
+	sem      chan struct{} // held while an operation runs
+	state    *State        // nil until loaded; guarded by sem
+	refs     int           // running or waiting operations; guarded by mu
+	lastUsed time.Time     // guarded by mu
+	elem     *list.Element
+}
+
+// ManagerOption configures a SessionManager.
+type ManagerOption func(*SessionManager)
+
+// WithMaxSessions bounds the number of cached sessions.
+func WithMaxSessions(n int) ManagerOption {
+	return func(m *SessionManager) {
+		m.maxSessions = n
+	}
+}
+
+// WithIdleTimeout sets how long an unused session stays cached. Zero disables
+// idle eviction.
+func WithIdleTimeout(d time.Duration) ManagerOption {
+	return func(m *SessionManager) {
+		m.idleTimeout = d
+	}
+}
+
+// NewSessionManager creates a manager backed by store. A nil store uses a
+// MemoryStore. Call Close to stop the idle-eviction goroutine.
+func NewSessionManager(store SessionStore, opts ...ManagerOption) *SessionManager {
+	if store == nil {
+		store = NewMemoryStore()
+	}
+	m := &SessionManager{
+		store:       store,
+		maxSessions: DefaultMaxSessions,
+		idleTimeout: DefaultIdleTimeout,
+		now:         time.Now,
+		entries:     make(map[string]*entry),
+		lru:         list.New(),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	if m.idleTimeout > 0 {
+		go m.janitor()
+	} else {
+		close(m.done)
+	}
+	return m
+}
+
+// Do runs fn with exclusive access to the session for peerDID and persists
+// the data it returns as the next version. fn receives nil data for a new
+// session. If fn returns an error nothing is saved.
+func (m *SessionManager) Do(ctx context.Context, peerDID string, fn func(data []byte) ([]byte, error)) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if peerDID == "" {
+		return errors.New(errors.CodeInvalidInput, "peer DID is required")
+	}
+
+	e := m.acquire(peerDID)
+	defer m.release(e)
+
+	select {
+	case e.sem <- struct{}{}:
+	case <-ctx.Done():
+		m.errs.Add(1)
+		return ctx.Err()
+	}
+	defer func() { <-e.sem }()
+
+	err := m.run(ctx, e, fn)
+	if err != nil {
+		m.errs.Add(1)
+		return err
+	}
+	m.operations.Add(1)
+	return nil
+}
+
+func (m *SessionManager) run(ctx context.Context, e *entry, fn func([]byte) ([]byte, error)) error {
+	if e.state != nil {
+		m.hits.Add(1)
+	} else {
+		m.misses.Add(1)
+		s, err := m.store.Load(ctx, e.peerDID)
+		switch {
+		case err == nil:
+			e.state = s
+		case isCode(err, errors.CodeSessionNotFound):
+			e.state = &State{PeerDID: e.peerDID}
+		default:
+			return err
+		}
+	}
+
+	next, err := fn(append([]byte(nil), e.state.Data...))
+	if err != nil {
+		return err
+	}
+
+	s := &State{PeerDID: e.peerDID, Version: e.state.Version + 1, Data: next, UpdatedAt: m.now().UTC()}
+	if err := m.store.Save(ctx, s); err != nil {
+		// Another writer got there first; reload on next use.
+		e.state = nil
+		return err
+	}
+	e.state = s
+	return nil
+}
+
+func (m *SessionManager) acquire(peerDID string) *entry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[peerDID]
+	if !ok {
+		e = &entry{peerDID: peerDID, sem: make(chan struct{}, 1)}
+		e.elem = m.lru.PushFront(e)
+		m.entries[peerDID] = e
+	} else {
+		m.lru.MoveToFront(e.elem)
+	}
+	e.refs++
+	return e
+}
+
+func (m *SessionManager) release(e *entry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e.refs--
+	e.lastUsed = m.now()
+	m.evictOverflowLocked()
+}
+
+// evictOverflowLocked drops least recently used idle sessions until the
+// cache is within bounds.
+func (m *SessionManager) evictOverflowLocked() {
+	for el := m.lru.Back(); el != nil && len(m.entries) > m.maxSessions; {
+		prev := el.Prev()
+		if e := el.Value.(*entry); e.refs == 0 {
+			m.removeLocked(e)
+		}
+		el = prev
+	}
+}
+
+func (m *SessionManager) removeLocked(e *entry) {
+	m.lru.Remove(e.elem)
+	delete(m.entries, e.peerDID)
+	m.evictions.Add(1)
+}
+
+// EvictIdle drops cached sessions unused for longer than the idle timeout
+// and returns how many were evicted.
+func (m *SessionManager) EvictIdle() int {
+	if m.idleTimeout <= 0 {
+		return 0
+	}
+	cutoff := m.now().Add(-m.idleTimeout)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	for el := m.lru.Back(); el != nil; {
+		prev := el.Prev()
+		e := el.Value.(*entry)
+		if e.refs == 0 && e.lastUsed.Before(cutoff) {
+			m.removeLocked(e)
+			n++
+		}
+		el = prev
+	}
+	return n
+}
+
+// Metrics returns a snapshot of the manager's counters.
+func (m *SessionManager) Metrics() Metrics {
+	m.mu.Lock()
+	n := len(m.entries)
+	m.mu.Unlock()
+	return Metrics{
+		Sessions:   n,
+		Operations: m.operations.Load(),
+		Errors:     m.errs.Load(),
+		Hits:       m.hits.Load(),
+		Misses:     m.misses.Load(),
+		Evictions:  m.evictions.Load(),
+	}
+}
+
+// Close stops background eviction. It does not wait for running operations.
+func (m *SessionManager) Close() error {
+	m.stopOnce.Do(func() { close(m.stop) })
+	<-m.done
+	return nil
+}
+
+func (m *SessionManager) janitor() {
+	defer close(m.done)
+	interval := m.idleTimeout / 2
+	if interval < time.Millisecond {
+		interval = time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			m.EvictIdle()
+		case <-m.stop:
+			return
+		}
+	}
+}
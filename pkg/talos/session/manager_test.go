@@ -0,0 +1,169 @@
+package session
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func counter(data []byte) ([]byte, error) {
+	var n uint64
+	if len(data) == 8 {
+		n = binary.BigEndian.Uint64(data)
+	}
+	out := make([]byte, 8)
+	binary.BigEndian.PutUint64(out, n+1)
+	return out, nil
+}
+
+func TestSessionManager_Stress(t *testing.T) {
+	store := NewMemoryStore()
+	m := NewSessionManager(store, WithMaxSessions(8))
+	defer m.Close()
+
+	const peers, workers, opsPerWorker = 32, 16, 25
+	ctx := context.Background()
+
+	var inFlight [peers]atomic.Int32
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < opsPerWorker*peers/workers; i++ {
+				p := (w + i) % peers
+				err := m.Do(ctx, fmt.Sprintf("did:key:peer-%d", p), func(data []byte) ([]byte, error) {
+					if inFlight[p].Add(1) != 1 {
+						t.Errorf("peer %d: concurrent operations on one session", p)
+					}
+					defer inFlight[p].Add(-1)
+					return counter(data)
+				})
+				if err != nil {
+					t.Errorf("Do failed: %v", err)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	var total uint64
+	for p := 0; p < peers; p++ {
+		s, err := store.Load(ctx, fmt.Sprintf("did:key:peer-%d", p))
+		if err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+		n := binary.BigEndian.Uint64(s.Data)
+		if n != s.Version {
+			t.Errorf("peer %d: counter %d does not match version %d", p, n, s.Version)
+		}
+		total += n
+	}
+
+	metrics := m.Metrics()
+	if total != metrics.Operations || metrics.Errors != 0 {
+		t.Errorf("metrics %+v do not match total %d", metrics, total)
+	}
+	if metrics.Sessions > 8 {
+		t.Errorf("cache exceeded bound: %d sessions", metrics.Sessions)
+	}
+	if metrics.Evictions == 0 || metrics.Misses < peers {
+		t.Errorf("expected evictions and misses, got %+v", metrics)
+	}
+}
+
+func TestSessionManager_ParallelPeers(t *testing.T) {
+	m := NewSessionManager(nil)
+	defer m.Close()
+
+	// Two peers blocked inside Do must not block each other.
+	ready := make(chan struct{}, 2)
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	for _, peer := range []string{"did:key:a", "did:key:b"} {
+		wg.Add(1)
+		go func(peer string) {
+			defer wg.Done()
+			_ = m.Do(context.Background(), peer, func(data []byte) ([]byte, error) {
+				ready <- struct{}{}
+				<-release
+				return data, nil
+			})
+		}(peer)
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-ready:
+		case <-time.After(2 * time.Second):
+			t.Fatal("sessions for different peers did not run in parallel")
+		}
+	}
+	close(release)
+	wg.Wait()
+}
+
+func TestSessionManager_ContextCancel(t *testing.T) {
+	m := NewSessionManager(nil)
+	defer m.Close()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	go func() {
+		_ = m.Do(context.Background(), "did:key:a", func(data []byte) ([]byte, error) {
+			close(started)
+			<-release
+			return data, nil
+		})
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := m.Do(ctx, "did:key:a", counter)
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+	close(release)
+}
+
+func TestSessionManager_IdleEviction(t *testing.T) {
+	var mu sync.Mutex
+	now := time.Now()
+	clock := func(m *SessionManager) {
+		m.now = func() time.Time {
+			mu.Lock()
+			defer mu.Unlock()
+			return now
+		}
+	}
+	m := NewSessionManager(nil, WithIdleTimeout(time.Minute), clock)
+	defer m.Close()
+
+	ctx := context.Background()
+	_ = m.Do(ctx, "did:key:old", counter)
+	mu.Lock()
+	now = now.Add(2 * time.Minute)
+	mu.Unlock()
+	_ = m.Do(ctx, "did:key:new", counter)
+
+	if n := m.EvictIdle(); n != 1 {
+		t.Errorf("expected 1 eviction, got %d", n)
+	}
+	if got := m.Metrics().Sessions; got != 1 {
+		t.Errorf("expected 1 cached session, got %d", got)
+	}
+
+	// The evicted session reloads from the store with its state intact.
+	var seen []byte
+	_ = m.Do(ctx, "did:key:old", func(data []byte) ([]byte, error) {
+		seen = data
+		return counter(data)
+	})
+	if len(seen) != 8 || binary.BigEndian.Uint64(seen) != 1 {
+		t.Errorf("evicted session lost state: %v", seen)
+	}
+}
@@ -0,0 +1,169 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+)
+
+// maxUpdateRetries bounds how often Update retries after a version conflict.
+const maxUpdateRetries = 8
+
+// State is the persisted state of a secure session with a single peer.
+// Data is the opaque serialized ratchet state; Version increases by one on
+// every successful Save and is used to detect forked (concurrent) writers.
+type State struct {
+	PeerDID   string    `json:"peer_did"`
+	Version   uint64    `json:"version"`
+	Data      []byte    `json:"data"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Clone returns a deep copy of the state.
+func (s *State) Clone() *State {
+	c := *s
+	c.Data = append([]byte(nil), s.Data...)
+	return &c
+}
+
+// SessionStore persists session state keyed by peer DID.
+//
+// Save is a compare-and-swap: state.Version must be exactly one greater than
+// the stored version (1 for a new session), otherwise it fails with
+// CodeSessionConflict and nothing is written.
+type SessionStore interface {
+	Load(ctx context.Context, peerDID string) (*State, error)
+	Save(ctx context.Context, state *State) error
+	Delete(ctx context.Context, peerDID string) error
+	Peers(ctx context.Context) ([]string, error)
+}
+
+// Update loads the session for peerDID, applies fn to its data and saves the
+// result as the next version. A missing session is passed to fn as nil data.
+// Conflicting concurrent writers are retried a bounded number of times.
+func Update(ctx context.Context, store SessionStore, peerDID string, fn func(data []byte) ([]byte, error)) (*State, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	for attempt := 0; ; attempt++ {
+		var version uint64
+		var data []byte
+		current, err := store.Load(ctx, peerDID)
+		switch {
+		case err == nil:
+			version, data = current.Version, current.Data
+		case isCode(err, errors.CodeSessionNotFound):
+		default:
+			return nil, err
+		}
+
+		next, err := fn(data)
+		if err != nil {
+			return nil, err
+		}
+
+		state := &State{PeerDID: peerDID, Version: version + 1, Data: next, UpdatedAt: time.Now().UTC()}
+		err = store.Save(ctx, state)
+		if err == nil {
+			return state, nil
+		}
+		if !isCode(err, errors.CodeSessionConflict) || attempt+1 >= maxUpdateRetries {
+			return nil, err
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// MemoryStore is an in-memory SessionStore. It is safe for concurrent use.
+type MemoryStore struct {
+	mu       sync.RWMutex
+	sessions map[string]*State
+}
+
+// NewMemoryStore creates an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{sessions: make(map[string]*State)}
+}
+
+// Load returns a copy of the stored state for peerDID.
+func (m *MemoryStore) Load(_ context.Context, peerDID string) (*State, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	s, ok := m.sessions[peerDID]
+	if !ok {
+		return nil, notFound(peerDID)
+	}
+	return s.Clone(), nil
+}
+
+// Save stores a copy of state if its version follows the stored one.
+func (m *MemoryStore) Save(_ context.Context, state *State) error {
+	if err := validateState(state); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var stored uint64
+	if s, ok := m.sessions[state.PeerDID]; ok {
+		stored = s.Version
+	}
+	if state.Version != stored+1 {
+		return conflict(state.PeerDID, stored, state.Version)
+	}
+	m.sessions[state.PeerDID] = state.Clone()
+	return nil
+}
+
+// Delete removes the session for peerDID. Deleting a missing session is a no-op.
+func (m *MemoryStore) Delete(_ context.Context, peerDID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, peerDID)
+	return nil
+}
+
+// Peers returns the DIDs of all stored sessions.
+func (m *MemoryStore) Peers(_ context.Context) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	peers := make([]string, 0, len(m.sessions))
+	for did := range m.sessions {
+		peers = append(peers, did)
+	}
+	return peers, nil
+}
+
+func validateState(state *State) error {
+	if state == nil || state.PeerDID == "" {
+		return errors.New(errors.CodeInvalidInput, "session state requires a peer DID")
+	}
+	if state.Version == 0 {
+		return errors.New(errors.CodeInvalidInput, "session state version must be at least 1")
+	}
+	return nil
+}
+
+func notFound(peerDID string) error {
+	return errors.New(errors.CodeSessionNotFound, "no session for peer",
+		errors.WithDetails(map[string]interface{}{"peer_did": peerDID}))
+}
+
+func conflict(peerDID string, stored, got uint64) error {
+	return errors.New(errors.CodeSessionConflict,
+		fmt.Sprintf("session version conflict: stored %d, got %d", stored, got),
+		errors.WithDetails(map[string]interface{}{
+			"peer_did":       peerDID,
+			"stored_version": stored,
+			"version":        got,
+		}))
+}
+
+func isCode(err error, code errors.TalosErrorCode) bool {
+	te, ok := err.(*errors.TalosError)
+	return ok && te.Code == code
+}
@@ -0,0 +1,151 @@
+package session
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, KeySize)
+}
+
+func stores(t *testing.T) map[string]SessionStore {
+	fs, err := NewFileStore(t.TempDir(), testKey(1))
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	return map[string]SessionStore{
+		"memory": NewMemoryStore(),
+		"file":   fs,
+	}
+}
+
+func TestStore_SaveLoad(t *testing.T) {
+	ctx := context.Background()
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			if _, err := store.Load(ctx, "did:key:alice"); !isCode(err, errors.CodeSessionNotFound) {
+				t.Fatalf("expected not found, got %v", err)
+			}
+
+			if err := store.Save(ctx, &State{PeerDID: "did:key:alice", Version: 1, Data: []byte("v1")}); err != nil {
+				t.Fatalf("Save failed: %v", err)
+			}
+			s, err := store.Load(ctx, "did:key:alice")
+			if err != nil {
+				t.Fatalf("Load failed: %v", err)
+			}
+			if s.Version != 1 || string(s.Data) != "v1" {
+				t.Errorf("unexpected state %+v", s)
+			}
+
+			// Stale and skipped versions are rejected.
+			for _, v := range []uint64{1, 3} {
+				err := store.Save(ctx, &State{PeerDID: "did:key:alice", Version: v, Data: []byte("x")})
+				if !isCode(err, errors.CodeSessionConflict) {
+					t.Errorf("version %d: expected conflict, got %v", v, err)
+				}
+			}
+
+			peers, _ := store.Peers(ctx)
+			if len(peers) != 1 || peers[0] != "did:key:alice" {
+				t.Errorf("unexpected peers %v", peers)
+			}
+
+			if err := store.Delete(ctx, "did:key:alice"); err != nil {
+				t.Fatalf("Delete failed: %v", err)
+			}
+			if _, err := store.Load(ctx, "did:key:alice"); !isCode(err, errors.CodeSessionNotFound) {
+				t.Errorf("expected not found after delete, got %v", err)
+			}
+		})
+	}
+}
+
+func TestStore_ConcurrentUpdate(t *testing.T) {
+	ctx := context.Background()
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			const workers = 8
+			var wg sync.WaitGroup
+			var mu sync.Mutex
+			var failures int
+			for i := 0; i < workers; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_, err := Update(ctx, store, "did:key:bob", func(data []byte) ([]byte, error) {
+						return append(data, 'x'), nil
+					})
+					if err != nil {
+						mu.Lock()
+						failures++
+						mu.Unlock()
+					}
+				}()
+			}
+			wg.Wait()
+
+			s, err := store.Load(ctx, "did:key:bob")
+			if err != nil {
+				t.Fatalf("Load failed: %v", err)
+			}
+			// Every successful update is reflected exactly once: no forks.
+			if int(s.Version) != workers-failures || len(s.Data) != int(s.Version) {
+				t.Errorf("forked state: version %d, data %q, failures %d", s.Version, s.Data, failures)
+			}
+		})
+	}
+}
+
+func TestFileStore_Persistence(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	fs, _ := NewFileStore(dir, testKey(1))
+	if _, err := Update(ctx, fs, "did:key:carol", func([]byte) ([]byte, error) { return []byte("secret"), nil }); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	// Plaintext must not hit the disk.
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 file, got %d", len(entries))
+	}
+	raw, _ := os.ReadFile(filepath.Join(dir, entries[0].Name()))
+	if bytes.Contains(raw, []byte("secret")) || bytes.Contains(raw, []byte("did:key:carol")) {
+		t.Error("session file contains plaintext")
+	}
+
+	// A new store over the same directory sees the state.
+	reopened, _ := NewFileStore(dir, testKey(1))
+	s, err := reopened.Load(ctx, "did:key:carol")
+	if err != nil || string(s.Data) != "secret" {
+		t.Fatalf("reopen failed: %v %+v", err, s)
+	}
+
+	// Wrong key fails closed.
+	wrong, _ := NewFileStore(dir, testKey(2))
+	if _, err := wrong.Load(ctx, "did:key:carol"); !isCode(err, errors.CodeCryptoError) {
+		t.Errorf("expected crypto error with wrong key, got %v", err)
+	}
+
+	// A file copied onto another peer's name is rejected.
+	_ = os.WriteFile(reopened.path("did:key:mallory"), raw, 0o600)
+	if _, err := reopened.Load(ctx, "did:key:mallory"); !isCode(err, errors.CodeCryptoError) {
+		t.Errorf("expected crypto error for swapped file, got %v", err)
+	}
+}
+
+func TestNewFileStore_InvalidKey(t *testing.T) {
+	_, err := NewFileStore(t.TempDir(), make([]byte, 16))
+	if !isCode(err, errors.CodeInvalidInput) {
+		t.Errorf("expected invalid input, got %v", err)
+	}
+}
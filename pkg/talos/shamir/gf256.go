@@ -0,0 +1,62 @@
+package shamir
+
+// Arithmetic in GF(2^8) with the AES reduction polynomial x^8+x^4+x^3+x+1.
+// Multiplication is branch-free and table-free so that share values do not
+// influence timing or cache access patterns.
+
+func gfAdd(a, b byte) byte {
+	return a ^ b
+}
+
+func gfMul(a, b byte) byte {
+	var p byte
+	for i := 0; i < 8; i++ {
+		// p ^= a if the low bit of b is set.
+		p ^= a & -(b & 1)
+		// a *= x, reducing by 0x1b if the high bit overflowed.
+		a = (a << 1) ^ (0x1b & -(a >> 7))
+		b >>= 1
+	}
+	return p
+}
+
+// gfInv returns a^-1 as a^254. The inverse of zero is zero.
+func gfInv(a byte) byte {
+	r := a
+	for i := 0; i < 6; i++ {
+		r = gfMul(r, r)
+		r = gfMul(r, a)
+	}
+	return gfMul(r, r)
+}
+
+// evaluate returns the polynomial with the given coefficients (constant term
+// first) at x.
+func evaluate(coeffs []byte, x byte) byte {
+	var y byte
+	for i := len(coeffs) - 1; i >= 0; i-- {
+		y = gfAdd(gfMul(y, x), coeffs[i])
+	}
+	return y
+}
+
+// interpolateAtZero returns f(0) for the unique polynomial of degree
+// len(xs)-1 through the points (xs[i], ys[i]). The xs must be distinct and
+// non-zero.
+func interpolateAtZero(xs, ys []byte) byte {
+	var secret byte
+	for i, xi := range xs {
+		// Lagrange basis at zero: prod_{j!=i} xj / (xj - xi). Subtraction is
+		// XOR in characteristic 2.
+		num, den := byte(1), byte(1)
+		for j, xj := range xs {
+			if i == j {
+				continue
+			}
+			num = gfMul(num, xj)
+			den = gfMul(den, gfAdd(xj, xi))
+		}
+		secret = gfAdd(secret, gfMul(ys[i], gfMul(num, gfInv(den))))
+	}
+	return secret
+}
@@ -0,0 +1,318 @@
+// Package shamir splits secrets such as wallet seeds into threshold shares
+// using Shamir's scheme over GF(256).
+//
+// Every share records the threshold, its index, the identifier of the split
+// it belongs to and an authenticator of the secret, so that Combine can
+// reject shares that are mixed up, corrupted or too few rather than silently
+// returning the wrong secret. The authenticator is keyed by a random key
+// that is split along with the secret, so a single share cannot be used to
+// test guesses of a low-entropy secret.
+package shamir
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/wallet"
+)
+
+const (
+	version = 2
+
+	// MaxShares is the largest number of shares a secret can be split into.
+	MaxShares = 255
+
+	// SetIDSize is the size of the random identifier shared by all shares of
+	// one split.
+	SetIDSize = 8
+	// DigestSize is the size of the secret digest carried by each share.
+	DigestSize = 4
+	// KeySize is the size of the random digest key split with the secret.
+	KeySize = 16
+
+	checksumSize = 4
+	headerSize   = 3 + SetIDSize + DigestSize // version, threshold, index
+	textPrefix   = "talos-share-"
+)
+
+// Reasons reported in Details["reason"] when shares are rejected.
+const (
+	ReasonInsufficient = "insufficient_shares"
+	ReasonInconsistent = "inconsistent_shares"
+	ReasonDuplicate    = "duplicate_index"
+	ReasonChecksum     = "checksum_mismatch"
+	ReasonDigest       = "digest_mismatch"
+	ReasonMalformed    = "malformed_share"
+)
+
+// Share is one piece of a split secret.
+type Share struct {
+	// Threshold is the number of shares needed to recover the secret.
+	Threshold int
+	// Index is the share's x coordinate, 1..255.
+	Index int
+	// SetID identifies the split this share came from.
+	SetID []byte
+	// Digest is a truncated HMAC-SHA256 of SetID and the secret under the
+	// digest key, checked after recovery.
+	Digest []byte
+	// Value holds one y coordinate per secret byte, followed by KeySize
+	// more for the digest key.
+	Value []byte
+}
+
+// Split divides secret into count shares, any threshold of which recover it.
+func Split(secret []byte, threshold, count int) ([]*Share, error) {
+	if len(secret) == 0 {
+		return nil, errors.New(errors.CodeInvalidInput, "secret must not be empty")
+	}
+	if threshold < 2 || count < threshold || count > MaxShares {
+		return nil, errors.New(errors.CodeInvalidInput,
+			fmt.Sprintf("invalid threshold %d-of-%d", threshold, count))
+	}
+
+	// The digest key is shared as trailing secret bytes.
+	setID := make([]byte, SetIDSize)
+	data := make([]byte, len(secret)+KeySize)
+	copy(data, secret)
+	defer wipe(data)
+	coeffs := make([]byte, len(data)*(threshold-1))
+	if _, err := rand.Read(setID); err != nil {
+		return nil, errors.New(errors.CodeCryptoError, "failed to read randomness", errors.WithCause(err))
+	}
+	if _, err := rand.Read(data[len(secret):]); err != nil {
+		return nil, errors.New(errors.CodeCryptoError, "failed to read randomness", errors.WithCause(err))
+	}
+	if _, err := rand.Read(coeffs); err != nil {
+		return nil, errors.New(errors.CodeCryptoError, "failed to read randomness", errors.WithCause(err))
+	}
+	digest := secretDigest(data[len(secret):], setID, secret)
+
+	shares := make([]*Share, count)
+	for i := range shares {
+		shares[i] = &Share{
+			Threshold: threshold,
+			Index:     i + 1,
+			SetID:     setID,
+			Digest:    digest,
+			Value:     make([]byte, len(data)),
+		}
+	}
+
+	poly := make([]byte, threshold)
+	for b, s := range data {
+		poly[0] = s
+		copy(poly[1:], coeffs[b*(threshold-1):])
+		for _, sh := range shares {
+			sh.Value[b] = evaluate(poly, byte(sh.Index))
+		}
+	}
+	wipe(coeffs)
+	wipe(poly)
+	return shares, nil
+}
+
+// Combine recovers the secret from at least Threshold shares of the same
+// split. Extra shares are used only to cross-check consistency.
+func Combine(shares []*Share) ([]byte, error) {
+	if len(shares) == 0 {
+		return nil, reject(ReasonInsufficient, "no shares provided", nil)
+	}
+	first := shares[0]
+	seen := make(map[int]bool, len(shares))
+	for _, s := range shares {
+		if err := s.validate(); err != nil {
+			return nil, err
+		}
+		if s.Threshold != first.Threshold || !bytes.Equal(s.SetID, first.SetID) ||
+			!bytes.Equal(s.Digest, first.Digest) || len(s.Value) != len(first.Value) {
+			return nil, reject(ReasonInconsistent, "shares belong to different splits", nil)
+		}
+		if seen[s.Index] {
+			return nil, reject(ReasonDuplicate, fmt.Sprintf("share %d provided more than once", s.Index),
+				map[string]interface{}{"index": s.Index})
+		}
+		seen[s.Index] = true
+	}
+	if len(shares) < first.Threshold {
+		return nil, reject(ReasonInsufficient,
+			fmt.Sprintf("need %d shares, got %d", first.Threshold, len(shares)),
+			map[string]interface{}{"threshold": first.Threshold, "have": len(shares)})
+	}
+
+	data := interpolate(shares[:first.Threshold])
+	defer wipe(data)
+	n := len(data) - KeySize
+	if subtle.ConstantTimeCompare(secretDigest(data[n:], first.SetID, data[:n]), first.Digest) != 1 {
+		return nil, reject(ReasonDigest, "recovered secret does not match share digest", nil)
+	}
+	// Every additional share must lie on the same polynomial.
+	for _, extra := range shares[first.Threshold:] {
+		check := append([]*Share{extra}, shares[:first.Threshold-1]...)
+		got := interpolate(check)
+		ok := subtle.ConstantTimeCompare(got, data) == 1
+		wipe(got)
+		if !ok {
+			return nil, reject(ReasonInconsistent, "shares do not lie on the same polynomial", nil)
+		}
+	}
+	return append([]byte(nil), data[:n]...), nil
+}
+
+// SplitSeed splits a wallet seed. It is Split with the seed length checked.
+func SplitSeed(seed []byte, threshold, count int) ([]*Share, error) {
+	if len(seed) != ed25519.SeedSize {
+		return nil, errors.New(errors.CodeInvalidInput, fmt.Sprintf("seed must be %d bytes", ed25519.SeedSize))
+	}
+	return Split(seed, threshold, count)
+}
+
+// RecoverWallet combines seed shares and rebuilds the wallet with
+// wallet.FromSeed.
+func RecoverWallet(shares []*Share, name string) (*wallet.Wallet, error) {
+	seed, err := Combine(shares)
+	if err != nil {
+		return nil, err
+	}
+	defer wipe(seed)
+	return wallet.FromSeed(seed, name)
+}
+
+// MarshalBinary encodes the share as
+// version | threshold | index | set id | digest | value | checksum, where the
+// checksum is a truncated SHA-256 of everything before it.
+func (s *Share) MarshalBinary() ([]byte, error) {
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+	b := make([]byte, 0, headerSize+len(s.Value)+checksumSize)
+	b = append(b, version, byte(s.Threshold), byte(s.Index))
+	b = append(b, s.SetID...)
+	b = append(b, s.Digest...)
+	b = append(b, s.Value...)
+	return append(b, checksum(b)...), nil
+}
+
+// UnmarshalBinary decodes a share produced by MarshalBinary, rejecting it if
+// the checksum does not match.
+func (s *Share) UnmarshalBinary(data []byte) error {
+	if len(data) < headerSize+KeySize+1+checksumSize {
+		return reject(ReasonMalformed, "share too short", nil)
+	}
+	body, sum := data[:len(data)-checksumSize], data[len(data)-checksumSize:]
+	if subtle.ConstantTimeCompare(checksum(body), sum) != 1 {
+		return reject(ReasonChecksum, "share checksum mismatch", nil)
+	}
+	if body[0] != version {
+		return reject(ReasonMalformed, fmt.Sprintf("unsupported share version %d", body[0]), nil)
+	}
+	out := Share{
+		Threshold: int(body[1]),
+		Index:     int(body[2]),
+		SetID:     append([]byte(nil), body[3:3+SetIDSize]...),
+		Digest:    append([]byte(nil), body[3+SetIDSize:headerSize]...),
+		Value:     append([]byte(nil), body[headerSize:]...),
+	}
+	if err := out.validate(); err != nil {
+		return err
+	}
+	*s = out
+	return nil
+}
+
+// String returns the share as "talos-share-" followed by base58, suitable
+// for printing or handing to a custodian.
+func (s *Share) String() string {
+	b, err := s.MarshalBinary()
+	if err != nil {
+		return ""
+	}
+	return textPrefix + wallet.EncodeBase58(b)
+}
+
+// ParseShare decodes the output of Share.String.
+func ParseShare(text string) (*Share, error) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, textPrefix) {
+		return nil, reject(ReasonMalformed, "missing share prefix", nil)
+	}
+	raw, err := wallet.DecodeBase58(strings.TrimPrefix(text, textPrefix))
+	if err != nil {
+		return nil, reject(ReasonMalformed, "invalid share encoding", nil)
+	}
+	s := new(Share)
+	if err := s.UnmarshalBinary(raw); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Share) validate() error {
+	switch {
+	case s == nil:
+		return reject(ReasonMalformed, "nil share", nil)
+	case s.Threshold < 2 || s.Threshold > MaxShares:
+		return reject(ReasonMalformed, fmt.Sprintf("invalid threshold %d", s.Threshold), nil)
+	case s.Index < 1 || s.Index > MaxShares:
+		return reject(ReasonMalformed, fmt.Sprintf("invalid index %d", s.Index), nil)
+	case len(s.SetID) != SetIDSize || len(s.Digest) != DigestSize || len(s.Value) <= KeySize:
+		return reject(ReasonMalformed, "share fields have wrong length", nil)
+	}
+	return nil
+}
+
+// interpolate recovers each secret byte from the given shares.
+func interpolate(shares []*Share) []byte {
+	xs := make([]byte, len(shares))
+	ys := make([]byte, len(shares))
+	for i, s := range shares {
+		xs[i] = byte(s.Index)
+	}
+	secret := make([]byte, len(shares[0].Value))
+	for b := range secret {
+		for i, s := range shares {
+			ys[i] = s.Value[b]
+		}
+		secret[b] = interpolateAtZero(xs, ys)
+	}
+	wipe(ys)
+	return secret
+}
+
+func secretDigest(key, setID, secret []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte("talos-shamir-v2"))
+	h.Write(setID)
+	var n [4]byte
+	binary.BigEndian.PutUint32(n[:], uint32(len(secret)))
+	h.Write(n[:])
+	h.Write(secret)
+	return h.Sum(nil)[:DigestSize]
+}
+
+func checksum(b []byte) []byte {
+	sum := sha256.Sum256(b)
+	return sum[:checksumSize]
+}
+
+func reject(reason, message string, details map[string]interface{}) error {
+	if details == nil {
+		details = map[string]interface{}{}
+	}
+	details["reason"] = reason
+	return errors.New(errors.CodeInvalidInput, message, errors.WithDetails(details))
+}
+
+func wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
@@ -0,0 +1,166 @@
+package shamir
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/wallet"
+)
+
+func reason(t *testing.T, err error) string {
+	t.Helper()
+	te, ok := err.(*errors.TalosError)
+	if !ok {
+		t.Fatalf("expected *TalosError, got %v", err)
+	}
+	if te.Code != errors.CodeInvalidInput {
+		t.Errorf("expected %s, got %s", errors.CodeInvalidInput, te.Code)
+	}
+	r, _ := te.Details["reason"].(string)
+	return r
+}
+
+func TestGF256(t *testing.T) {
+	for a := 1; a < 256; a++ {
+		if gfMul(byte(a), gfInv(byte(a))) != 1 {
+			t.Fatalf("%d * inv(%d) != 1", a, a)
+		}
+	}
+	// 0x53 * 0xca = 0x01 is the worked example from FIPS-197.
+	if gfMul(0x53, 0xca) != 0x01 {
+		t.Error("unexpected product for FIPS-197 example")
+	}
+}
+
+func TestSplitCombine(t *testing.T) {
+	secret := make([]byte, 32)
+	rand.Read(secret)
+
+	shares, err := Split(secret, 3, 5)
+	if err != nil {
+		t.Fatalf("Split failed: %v", err)
+	}
+	subsets := [][]int{{0, 1, 2}, {4, 2, 0}, {1, 3, 4}, {0, 1, 2, 3, 4}}
+	for _, subset := range subsets {
+		var picked []*Share
+		for _, i := range subset {
+			picked = append(picked, shares[i])
+		}
+		got, err := Combine(picked)
+		if err != nil {
+			t.Fatalf("%v: Combine failed: %v", subset, err)
+		}
+		if !bytes.Equal(got, secret) {
+			t.Errorf("%v: recovered wrong secret", subset)
+		}
+	}
+
+	if _, err := Split(secret, 1, 3); err == nil {
+		t.Error("expected error for threshold 1")
+	}
+	if _, err := Split(secret, 3, 256); err == nil {
+		t.Error("expected error for more than 255 shares")
+	}
+}
+
+func TestCombine_Errors(t *testing.T) {
+	secret := []byte("correct horse battery staple")
+	a, _ := Split(secret, 2, 3)
+	b, _ := Split(secret, 2, 3)
+
+	corrupt := *a[1]
+	corrupt.Value = append([]byte(nil), a[1].Value...)
+	corrupt.Value[0] ^= 1
+	corruptKey := *a[1]
+	corruptKey.Value = append([]byte(nil), a[1].Value...)
+	corruptKey.Value[len(corruptKey.Value)-1] ^= 1
+
+	tests := []struct {
+		name   string
+		shares []*Share
+		reason string
+	}{
+		{"none", nil, ReasonInsufficient},
+		{"too few", a[:1], ReasonInsufficient},
+		{"duplicate", []*Share{a[0], a[0]}, ReasonDuplicate},
+		{"mixed splits", []*Share{a[0], b[1]}, ReasonInconsistent},
+		{"corrupted value", []*Share{a[0], &corrupt}, ReasonDigest},
+		{"corrupted key", []*Share{a[0], &corruptKey}, ReasonDigest},
+		{"corrupted extra", []*Share{a[0], a[2], &corrupt}, ReasonInconsistent},
+		{"bad index", []*Share{a[0], {Threshold: 2, Index: 0, SetID: a[0].SetID, Digest: a[0].Digest, Value: a[0].Value}}, ReasonMalformed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Combine(tt.shares)
+			if got := reason(t, err); got != tt.reason {
+				t.Errorf("expected reason %q, got %q", tt.reason, got)
+			}
+		})
+	}
+}
+
+func TestDigestIsKeyed(t *testing.T) {
+	// A single share must not let anyone confirm guesses of a low-entropy
+	// secret: the digest key is only recovered with the threshold.
+	secret := []byte("1234")
+	shares, _ := Split(secret, 2, 3)
+	s := shares[0]
+	if len(s.Value) != len(secret)+KeySize {
+		t.Fatalf("expected %d value bytes, got %d", len(secret)+KeySize, len(s.Value))
+	}
+	for _, key := range [][]byte{nil, s.Value[len(secret):]} {
+		if bytes.Equal(secretDigest(key, s.SetID, secret), s.Digest) {
+			t.Error("digest can be recomputed from a single share")
+		}
+	}
+	again, _ := Split(secret, 2, 3)
+	if bytes.Equal(again[0].Value[len(secret):], s.Value[len(secret):]) {
+		t.Error("digest key shares repeat across splits")
+	}
+}
+
+func TestShareEncoding(t *testing.T) {
+	shares, _ := Split([]byte{1, 2, 3}, 2, 2)
+	text := shares[0].String()
+	parsed, err := ParseShare(text)
+	if err != nil {
+		t.Fatalf("ParseShare failed: %v", err)
+	}
+	if parsed.Index != 1 || parsed.Threshold != 2 || !bytes.Equal(parsed.Value, shares[0].Value) {
+		t.Errorf("round trip mismatch: %+v", parsed)
+	}
+
+	raw, _ := shares[1].MarshalBinary()
+	raw[len(raw)-5] ^= 0x80
+	var s Share
+	if got := reason(t, s.UnmarshalBinary(raw)); got != ReasonChecksum {
+		t.Errorf("expected %s, got %s", ReasonChecksum, got)
+	}
+	if _, err := ParseShare("share-abc"); err == nil {
+		t.Error("expected error for missing prefix")
+	}
+}
+
+func TestRecoverWallet(t *testing.T) {
+	seed := make([]byte, 32)
+	rand.Read(seed)
+	original, _ := wallet.FromSeed(seed, "agent")
+
+	shares, err := SplitSeed(seed, 2, 3)
+	if err != nil {
+		t.Fatalf("SplitSeed failed: %v", err)
+	}
+	w, err := RecoverWallet([]*Share{shares[2], shares[0]}, "agent")
+	if err != nil {
+		t.Fatalf("RecoverWallet failed: %v", err)
+	}
+	if w.DID() != original.DID() {
+		t.Errorf("expected %s, got %s", original.DID(), w.DID())
+	}
+
+	if _, err := SplitSeed(seed[:31], 2, 3); err == nil {
+		t.Error("expected error for short seed")
+	}
+}
@@ -0,0 +1,112 @@
+package transparency
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/merkle"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/wallet"
+)
+
+// Log is an in-memory key transparency log, e.g. for tests. It is safe for
+// concurrent use.
+type Log struct {
+	signer *wallet.Wallet
+	now    func() time.Time
+
+	mu      sync.RWMutex
+	tree    *merkle.Tree
+	entries []Entry
+	latest  map[string]uint64 // DID -> index of its newest entry
+}
+
+// LogOption configures a Log.
+type LogOption func(*Log)
+
+// WithClock overrides the time source for entry and head timestamps.
+func WithClock(now func() time.Time) LogOption {
+	return func(l *Log) {
+		l.now = now
+	}
+}
+
+// NewLog creates an empty log whose tree heads are signed by signer; the
+// log's ID is the signer's DID.
+func NewLog(signer *wallet.Wallet, opts ...LogOption) *Log {
+	l := &Log{signer: signer, now: time.Now, tree: merkle.NewTree(), latest: make(map[string]uint64)}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// ID returns the log's ID.
+func (l *Log) ID() string {
+	return l.signer.DID()
+}
+
+// Publish appends a binding of did to pub, superseding earlier ones.
+func (l *Log) Publish(did string, pub ed25519.PublicKey) (*Entry, error) {
+	if len(pub) != ed25519.PublicKeySize {
+		return nil, errors.New(errors.CodeInvalidInput, "public key has the wrong size")
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	e := Entry{DID: did, PublicKey: append([]byte(nil), pub...), Version: 1, Timestamp: l.now().UnixMilli()}
+	if i, ok := l.latest[did]; ok {
+		e.Version = l.entries[i].Version + 1
+	}
+	leaf, err := e.LeafData()
+	if err != nil {
+		return nil, err
+	}
+	l.latest[did] = l.tree.Append(leaf)
+	l.entries = append(l.entries, e)
+	return &e, nil
+}
+
+// Head returns a freshly signed head for the current tree.
+func (l *Log) Head() (*SignedTreeHead, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.headLocked()
+}
+
+func (l *Log) headLocked() (*SignedTreeHead, error) {
+	return signHead(l.signer, SignedTreeHead{
+		TreeSize:  l.tree.Size(),
+		RootHash:  l.tree.Root(),
+		Timestamp: l.now().UnixMilli(),
+	})
+}
+
+// Lookup returns the newest entry for did with its inclusion proof in a
+// fresh tree head. Unknown DIDs yield CodeInvalidInput with reason
+// not_found.
+func (l *Log) Lookup(_ context.Context, did string) (*KeyProof, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	i, ok := l.latest[did]
+	if !ok {
+		return nil, errors.New(errors.CodeInvalidInput, fmt.Sprintf("no key published for %s", did),
+			errors.WithDetails(map[string]interface{}{"reason": ReasonNotFound}))
+	}
+	head, err := l.headLocked()
+	if err != nil {
+		return nil, err
+	}
+	proof, err := l.tree.InclusionProof(i, head.TreeSize)
+	if err != nil {
+		return nil, err
+	}
+	return &KeyProof{Entry: l.entries[i], Proof: proof, Head: head}, nil
+}
+
+// ConsistencyProof implements Prover.
+func (l *Log) ConsistencyProof(_ context.Context, size1, size2 uint64) (*merkle.ConsistencyProof, error) {
+	return l.tree.ConsistencyProof(size1, size2)
+}
@@ -0,0 +1,293 @@
+// Package transparency verifies key transparency logs: append-only Merkle
+// logs (RFC 6962, see package merkle) of DID-to-key bindings whose signed
+// tree heads let clients detect a directory that shows different keys to
+// different parties.
+//
+// A client looking up a key checks that the returned Entry is included in a
+// signed tree head. A Monitor additionally remembers the newest head it has
+// seen and accepts another only with a consistency proof, so heads obtained
+// from peers (gossip) either extend the same history or expose the log as
+// equivocating. Inclusion does not show that an entry is a DID's newest: a
+// log can keep serving a superseded binding, which only an auditor replaying
+// the full log detects.
+package transparency
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"sync"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/canonical"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/merkle"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/wallet"
+)
+
+// headContext is the Ed25519ctx context for tree head signatures.
+const headContext = "talos-kt-tree-head-v1"
+
+// Reasons reported in Details["reason"].
+const (
+	ReasonMalformed    = "malformed"
+	ReasonBadSignature = "bad_signature"
+	ReasonWrongLog     = "wrong_log"
+	ReasonWrongEntry   = "wrong_entry"
+	ReasonEquivocation = "equivocation"
+	ReasonNotFound     = "not_found"
+	ReasonStaleEntry   = "stale_entry"
+)
+
+// Entry binds a DID to an Ed25519 public key. Version counts the bindings
+// published for the DID, starting at 1; Timestamp is Unix milliseconds.
+type Entry struct {
+	DID       string `json:"did"`
+	PublicKey []byte `json:"public_key"`
+	Version   uint64 `json:"version"`
+	Timestamp int64  `json:"ts"`
+}
+
+// LeafData returns the Merkle leaf data of e, its canonical JSON.
+func (e *Entry) LeafData() ([]byte, error) {
+	b, err := canonical.Marshal(e)
+	if err != nil {
+		return nil, errors.New(errors.CodeInvalidInput, "failed to canonicalize entry", errors.WithCause(err))
+	}
+	return b, nil
+}
+
+// SignedTreeHead commits the log identified by LogID (the log's DID) to the
+// tree of TreeSize entries with RootHash. Timestamp is Unix milliseconds.
+type SignedTreeHead struct {
+	LogID     string `json:"log_id"`
+	TreeSize  uint64 `json:"tree_size"`
+	RootHash  []byte `json:"root_hash"`
+	Timestamp int64  `json:"ts"`
+	Signature string `json:"sig,omitempty"`
+}
+
+func signHead(w *wallet.Wallet, h SignedTreeHead) (*SignedTreeHead, error) {
+	h.LogID = w.DID()
+	msg, err := h.signingBytes()
+	if err != nil {
+		return nil, err
+	}
+	sig, err := w.SignWithOptions(msg, wallet.SignOptions{Variant: wallet.VariantEd25519ctx, Context: headContext})
+	if err != nil {
+		return nil, err
+	}
+	h.Signature = base64.RawURLEncoding.EncodeToString(sig)
+	return &h, nil
+}
+
+// Verify checks that h is signed by the log logID.
+func (h *SignedTreeHead) Verify(logID string) error {
+	if h.LogID != logID {
+		return reject(ReasonWrongLog, fmt.Sprintf("tree head is from log %s, not %s", h.LogID, logID))
+	}
+	if len(h.RootHash) != merkle.HashSize {
+		return reject(ReasonMalformed, "tree head root hash has the wrong size")
+	}
+	pub, err := wallet.PublicKeyFromDID(h.LogID)
+	if err != nil {
+		return reject(ReasonMalformed, "tree head log ID is not a valid did:key")
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(h.Signature)
+	if err != nil {
+		return reject(ReasonBadSignature, "tree head signature is not valid base64url")
+	}
+	msg, err := h.signingBytes()
+	if err != nil {
+		return err
+	}
+	if !wallet.VerifyWithOptions(pub, msg, sig, wallet.SignOptions{Variant: wallet.VariantEd25519ctx, Context: headContext}) {
+		return reject(ReasonBadSignature, "tree head signature does not verify")
+	}
+	return nil
+}
+
+func (h *SignedTreeHead) signingBytes() ([]byte, error) {
+	unsigned := *h
+	unsigned.Signature = ""
+	b, err := canonical.Marshal(&unsigned)
+	if err != nil {
+		return nil, errors.New(errors.CodeInvalidInput, "failed to canonicalize tree head", errors.WithCause(err))
+	}
+	return b, nil
+}
+
+// KeyProof is a log's answer to a key lookup: the entry the log presents as
+// the DID's latest, and its inclusion proof in Head.
+type KeyProof struct {
+	Entry Entry                  `json:"entry"`
+	Proof *merkle.InclusionProof `json:"proof"`
+	Head  *SignedTreeHead        `json:"head"`
+}
+
+// VerifyEntry checks that e is included in head. The head's signature is
+// not checked; see SignedTreeHead.Verify.
+func VerifyEntry(head *SignedTreeHead, e *Entry, proof *merkle.InclusionProof) error {
+	if proof == nil || proof.TreeSize != head.TreeSize {
+		return reject(ReasonMalformed, "inclusion proof is not for the tree head's size")
+	}
+	if len(e.PublicKey) != ed25519.PublicKeySize {
+		return reject(ReasonMalformed, "entry public key has the wrong size")
+	}
+	leaf, err := e.LeafData()
+	if err != nil {
+		return err
+	}
+	return proof.Verify(leaf, head.RootHash)
+}
+
+// VerifyConsistency checks that older and newer are heads of the same log
+// and that proof shows older's tree is a prefix of newer's. Signatures are
+// not checked.
+func VerifyConsistency(older, newer *SignedTreeHead, proof *merkle.ConsistencyProof) error {
+	if older.LogID != newer.LogID {
+		return reject(ReasonWrongLog, "tree heads are from different logs")
+	}
+	if proof == nil || proof.FirstSize != older.TreeSize || proof.SecondSize != newer.TreeSize {
+		return reject(ReasonMalformed, "consistency proof is not for the tree heads' sizes")
+	}
+	return proof.Verify(older.RootHash, newer.RootHash)
+}
+
+// Prover supplies consistency proofs, e.g. a log or a client for its API.
+type Prover interface {
+	ConsistencyProof(ctx context.Context, size1, size2 uint64) (*merkle.ConsistencyProof, error)
+}
+
+// Monitor tracks the newest verified tree head of one log. It is safe for
+// concurrent use.
+type Monitor struct {
+	logID  string
+	prover Prover
+
+	mu       sync.Mutex
+	latest   *SignedTreeHead
+	versions map[string]uint64
+}
+
+// NewMonitor creates a monitor for the log logID that fetches consistency
+// proofs from prover. The first head observed is trusted; pass a known head
+// to Observe first to pin one.
+func NewMonitor(logID string, prover Prover) *Monitor {
+	return &Monitor{logID: logID, prover: prover, versions: make(map[string]uint64)}
+}
+
+// Latest returns the newest head observed, e.g. to gossip to peers, or nil.
+func (m *Monitor) Latest() *SignedTreeHead {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.latest
+}
+
+// Observe verifies head, whether from the log itself or from a peer, and
+// checks it against the newest head seen: a larger head must extend it, a
+// smaller one must be a prefix of it, and one of equal size must have the
+// same root. Conflicting heads are evidence that the log equivocates; they
+// are reported as CodeCryptoError with reason equivocation and both heads
+// in Details["heads"]. Errors from the prover are returned as they are.
+func (m *Monitor) Observe(ctx context.Context, head *SignedTreeHead) error {
+	if err := head.Verify(m.logID); err != nil {
+		return err
+	}
+	for {
+		m.mu.Lock()
+		latest := m.latest
+		if latest == nil {
+			m.latest = head
+			m.mu.Unlock()
+			return nil
+		}
+		m.mu.Unlock()
+
+		if err := m.check(ctx, latest, head); err != nil {
+			return err
+		}
+
+		// Another head may have been installed while the proof was
+		// fetched; head must then be checked against that one too, or
+		// concurrent forks would each pass against the same old head.
+		m.mu.Lock()
+		if m.latest != latest {
+			m.mu.Unlock()
+			continue
+		}
+		if head.TreeSize > latest.TreeSize {
+			m.latest = head
+		}
+		m.mu.Unlock()
+		return nil
+	}
+}
+
+// check verifies that head and latest are heads of the same history.
+func (m *Monitor) check(ctx context.Context, latest, head *SignedTreeHead) error {
+	older, newer := latest, head
+	if head.TreeSize < latest.TreeSize {
+		older, newer = head, latest
+	}
+	if older.TreeSize == newer.TreeSize {
+		if !bytes.Equal(older.RootHash, newer.RootHash) {
+			return equivocation(older, newer, nil)
+		}
+		return nil
+	}
+	proof, err := m.prover.ConsistencyProof(ctx, older.TreeSize, newer.TreeSize)
+	if err != nil {
+		return err
+	}
+	if err := VerifyConsistency(older, newer, proof); err != nil {
+		return equivocation(older, newer, err)
+	}
+	return nil
+}
+
+// VerifyKey checks a lookup answer for did: the head is observed as by
+// Observe and the entry must be for did and included in it. An entry whose
+// Version is below one already verified for did is rejected with reason
+// stale_entry. Beyond that, freshness is not verified: an entry this monitor
+// has not seen superseded is accepted even if the log holds a newer one.
+func (m *Monitor) VerifyKey(ctx context.Context, did string, p *KeyProof) error {
+	if p == nil || p.Head == nil {
+		return reject(ReasonMalformed, "key proof has no tree head")
+	}
+	if p.Entry.DID != did {
+		return reject(ReasonWrongEntry, "key proof is for a different DID")
+	}
+	if err := m.Observe(ctx, p.Head); err != nil {
+		return err
+	}
+	if err := VerifyEntry(p.Head, &p.Entry, p.Proof); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if p.Entry.Version < m.versions[did] {
+		return reject(ReasonStaleEntry, fmt.Sprintf("key proof has version %d, but version %d was already verified",
+			p.Entry.Version, m.versions[did]))
+	}
+	m.versions[did] = p.Entry.Version
+	return nil
+}
+
+func equivocation(a, b *SignedTreeHead, cause error) error {
+	opts := []errors.Option{errors.WithDetails(map[string]interface{}{
+		"reason": ReasonEquivocation,
+		"heads":  []*SignedTreeHead{a, b},
+	})}
+	if cause != nil {
+		opts = append(opts, errors.WithCause(cause))
+	}
+	return errors.New(errors.CodeCryptoError, "log presented inconsistent tree heads", opts...)
+}
+
+func reject(reason, message string) error {
+	return errors.New(errors.CodeCryptoError, message,
+		errors.WithDetails(map[string]interface{}{"reason": reason}))
+}
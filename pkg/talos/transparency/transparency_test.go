@@ -0,0 +1,221 @@
+package transparency
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/merkle"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/wallet"
+)
+
+func newWallet(t *testing.T, b byte) *wallet.Wallet {
+	t.Helper()
+	w, err := wallet.FromSeed(bytes.Repeat([]byte{b}, 32), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func expectReason(t *testing.T, err error, reason string) {
+	t.Helper()
+	te, ok := err.(*errors.TalosError)
+	if !ok {
+		t.Fatalf("expected *TalosError, got %v", err)
+	}
+	if got, _ := te.Details["reason"].(string); got != reason {
+		t.Errorf("expected reason %q, got %q (%v)", reason, got, err)
+	}
+}
+
+func key(b byte) ed25519.PublicKey {
+	return ed25519.NewKeyFromSeed(bytes.Repeat([]byte{b}, 32)).Public().(ed25519.PublicKey)
+}
+
+func TestLookupAndVerify(t *testing.T) {
+	ctx := context.Background()
+	log := NewLog(newWallet(t, 1))
+	for i := byte(0); i < 5; i++ {
+		if _, err := log.Publish("did:web:agent"+string('a'+rune(i))+".example", key(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := log.Publish("did:web:agentb.example", key(9)); err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewMonitor(log.ID(), log)
+	p, err := log.Lookup(ctx, "did:web:agentb.example")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	if err := m.VerifyKey(ctx, "did:web:agentb.example", p); err != nil {
+		t.Fatalf("VerifyKey failed: %v", err)
+	}
+	if !bytes.Equal(p.Entry.PublicKey, key(9)) || p.Entry.Version != 2 {
+		t.Errorf("expected the rotated key, got %+v", p.Entry)
+	}
+
+	// Swapping the key, or the DID, fails.
+	swapped := *p
+	swapped.Entry.PublicKey = key(7)
+	expectReason(t, m.VerifyKey(ctx, "did:web:agentb.example", &swapped), "root_mismatch")
+	expectReason(t, m.VerifyKey(ctx, "did:web:agentc.example", p), ReasonWrongEntry)
+
+	// Heads must be signed by the log.
+	forged := *p.Head
+	forged.TreeSize++
+	expectReason(t, forged.Verify(log.ID()), ReasonBadSignature)
+	expectReason(t, p.Head.Verify(newWallet(t, 2).DID()), ReasonWrongLog)
+
+	_, err = log.Lookup(ctx, "did:web:unknown.example")
+	expectReason(t, err, ReasonNotFound)
+
+	// The monitor follows the log as it grows.
+	old := m.Latest()
+	log.Publish("did:web:agentz.example", key(3))
+	head, _ := log.Head()
+	if err := m.Observe(ctx, head); err != nil {
+		t.Fatalf("Observe failed: %v", err)
+	}
+	if m.Latest() != head {
+		t.Error("monitor did not advance")
+	}
+	// An older head gossiped by a peer is still consistent.
+	if err := m.Observe(ctx, old); err != nil {
+		t.Errorf("Observe of older head failed: %v", err)
+	}
+	if m.Latest() != head {
+		t.Error("monitor moved backwards")
+	}
+}
+
+func TestVerifyKey_Stale(t *testing.T) {
+	ctx := context.Background()
+	log := NewLog(newWallet(t, 1))
+	log.Publish("did:web:a.example", key(1))
+	old, _ := log.Lookup(ctx, "did:web:a.example")
+	log.Publish("did:web:a.example", key(2))
+	cur, _ := log.Lookup(ctx, "did:web:a.example")
+
+	m := NewMonitor(log.ID(), log)
+	if err := m.VerifyKey(ctx, "did:web:a.example", cur); err != nil {
+		t.Fatalf("VerifyKey failed: %v", err)
+	}
+	// The superseded binding is still included in a consistent head, but
+	// the monitor has already seen a newer version.
+	expectReason(t, m.VerifyKey(ctx, "did:web:a.example", old), ReasonStaleEntry)
+	if err := m.VerifyKey(ctx, "did:web:a.example", cur); err != nil {
+		t.Errorf("VerifyKey of the same version failed: %v", err)
+	}
+}
+
+func TestEquivocation(t *testing.T) {
+	ctx := context.Background()
+	signer := newWallet(t, 1)
+	honest, fork := NewLog(signer), NewLog(signer)
+	for _, l := range []*Log{honest, fork} {
+		l.Publish("did:web:a.example", key(1))
+		l.Publish("did:web:b.example", key(2))
+	}
+	// The fork shows a different key for a to some clients.
+	honest.Publish("did:web:a.example", key(3))
+	fork.Publish("did:web:a.example", key(4))
+
+	m := NewMonitor(signer.DID(), honest)
+	h1, _ := honest.Head()
+	if err := m.Observe(ctx, h1); err != nil {
+		t.Fatal(err)
+	}
+
+	// A peer gossips the forked head of the same size.
+	f1, _ := fork.Head()
+	err := m.Observe(ctx, f1)
+	expectReason(t, err, ReasonEquivocation)
+	if heads, _ := err.(*errors.TalosError).Details["heads"].([]*SignedTreeHead); len(heads) != 2 {
+		t.Errorf("expected both heads as evidence, got %v", heads)
+	}
+
+	// A larger forked head cannot be proven consistent either.
+	honest.Publish("did:web:c.example", key(5))
+	fork.Publish("did:web:c.example", key(5))
+	f2, _ := fork.Head()
+	expectReason(t, m.Observe(ctx, f2), ReasonEquivocation)
+
+	// Nor can a forked lookup answer.
+	p, _ := fork.Lookup(ctx, "did:web:a.example")
+	expectReason(t, m.VerifyKey(ctx, "did:web:a.example", p), ReasonEquivocation)
+}
+
+// forkProver serves consistency proofs ending at size 2 from one branch and
+// all others from another, holding the first two requests until both have
+// arrived.
+type forkProver struct {
+	two, three *Log
+	mu         sync.Mutex
+	calls      int
+	both       chan struct{}
+}
+
+func (p *forkProver) ConsistencyProof(ctx context.Context, size1, size2 uint64) (*merkle.ConsistencyProof, error) {
+	p.mu.Lock()
+	p.calls++
+	if p.calls == 2 {
+		close(p.both)
+	}
+	first := p.calls <= 2
+	p.mu.Unlock()
+	if first {
+		<-p.both
+	}
+	if size2 == 2 {
+		return p.two.ConsistencyProof(ctx, size1, size2)
+	}
+	return p.three.ConsistencyProof(ctx, size1, size2)
+}
+
+func TestEquivocation_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	signer := newWallet(t, 1)
+	clock := WithClock(func() time.Time { return time.Unix(1700000000, 0) })
+	b, c := NewLog(signer, clock), NewLog(signer, clock)
+	for _, l := range []*Log{b, c} {
+		l.Publish("did:web:a.example", key(1))
+	}
+	a, _ := b.Head()
+	b.Publish("did:web:b.example", key(2))
+	c.Publish("did:web:b.example", key(3))
+	c.Publish("did:web:c.example", key(4))
+	hb, _ := b.Head()
+	hc, _ := c.Head()
+
+	// Both forks extend a; observed at the same time, one must still be
+	// caught against the other.
+	m := NewMonitor(signer.DID(), &forkProver{two: b, three: c, both: make(chan struct{})})
+	if err := m.Observe(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+	errs := make([]error, 2)
+	var wg sync.WaitGroup
+	for i, h := range []*SignedTreeHead{hb, hc} {
+		wg.Add(1)
+		go func(i int, h *SignedTreeHead) {
+			defer wg.Done()
+			errs[i] = m.Observe(ctx, h)
+		}(i, h)
+	}
+	wg.Wait()
+	if errs[0] == nil && errs[1] == nil {
+		t.Fatal("concurrent forked heads were both accepted")
+	}
+	for _, err := range errs {
+		if err != nil {
+			expectReason(t, err, ReasonEquivocation)
+		}
+	}
+}
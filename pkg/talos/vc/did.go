@@ -0,0 +1,139 @@
+package vc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/wallet"
+)
+
+// Verification relationships a proof purpose refers to.
+const (
+	PurposeAssertion      = "assertionMethod"
+	PurposeAuthentication = "authentication"
+)
+
+// DIDDocument is the subset of a DID document used to verify proofs.
+type DIDDocument struct {
+	Context            []string             `json:"@context,omitempty"`
+	ID                 string               `json:"id"`
+	VerificationMethod []VerificationMethod `json:"verificationMethod"`
+	Authentication     []string             `json:"authentication,omitempty"`
+	AssertionMethod    []string             `json:"assertionMethod,omitempty"`
+}
+
+// VerificationMethod is a public key in Multikey form.
+type VerificationMethod struct {
+	ID                 string `json:"id"`
+	Type               string `json:"type"`
+	Controller         string `json:"controller"`
+	PublicKeyMultibase string `json:"publicKeyMultibase"`
+}
+
+// PublicKey decodes an Ed25519 Multikey.
+func (m *VerificationMethod) PublicKey() ([]byte, error) {
+	if m.Type != "Multikey" {
+		return nil, reject(ReasonUnknownMethod, fmt.Sprintf("unsupported verification method type %q", m.Type))
+	}
+	pub, err := wallet.PublicKeyFromDID("did:key:" + m.PublicKeyMultibase)
+	if err != nil {
+		return nil, reject(ReasonUnknownMethod, "verification method is not an Ed25519 Multikey")
+	}
+	return pub, nil
+}
+
+// Method returns the verification method with the given ID if it is
+// authorized for purpose.
+func (d *DIDDocument) Method(id, purpose string) (*VerificationMethod, error) {
+	var refs []string
+	switch purpose {
+	case PurposeAssertion:
+		refs = d.AssertionMethod
+	case PurposeAuthentication:
+		refs = d.Authentication
+	default:
+		return nil, reject(ReasonUnknownMethod, fmt.Sprintf("unsupported proof purpose %q", purpose))
+	}
+	authorized := false
+	for _, r := range refs {
+		if r == id || strings.HasPrefix(r, "#") && d.ID+r == id {
+			authorized = true
+			break
+		}
+	}
+	if !authorized {
+		return nil, reject(ReasonUnknownMethod, fmt.Sprintf("%s is not authorized for %s", id, purpose))
+	}
+	for i := range d.VerificationMethod {
+		m := &d.VerificationMethod[i]
+		if m.ID == id || strings.HasPrefix(m.ID, "#") && d.ID+m.ID == id {
+			return m, nil
+		}
+	}
+	return nil, reject(ReasonUnknownMethod, fmt.Sprintf("DID document has no verification method %s", id))
+}
+
+// Resolver resolves a DID to its document.
+type Resolver interface {
+	Resolve(ctx context.Context, did string) (*DIDDocument, error)
+}
+
+// ResolverFunc adapts a function to a Resolver.
+type ResolverFunc func(ctx context.Context, did string) (*DIDDocument, error)
+
+// Resolve implements Resolver.
+func (f ResolverFunc) Resolve(ctx context.Context, did string) (*DIDDocument, error) {
+	return f(ctx, did)
+}
+
+// KeyResolver resolves did:key identifiers locally.
+type KeyResolver struct{}
+
+// Resolve implements Resolver.
+func (KeyResolver) Resolve(_ context.Context, did string) (*DIDDocument, error) {
+	if _, err := wallet.PublicKeyFromDID(did); err != nil {
+		return nil, errors.New(errors.CodeInvalidInput, fmt.Sprintf("cannot resolve %q", did),
+			errors.WithCause(err), errors.WithDetails(map[string]interface{}{"reason": ReasonUnresolvable}))
+	}
+	return KeyDocument(did), nil
+}
+
+// KeyDocument returns the DID document of a valid did:key: a single
+// Multikey usable for assertion and authentication.
+func KeyDocument(did string) *DIDDocument {
+	id := KeyMethodID(did)
+	return &DIDDocument{
+		Context: []string{"https://www.w3.org/ns/did/v1", "https://w3id.org/security/multikey/v1"},
+		ID:      did,
+		VerificationMethod: []VerificationMethod{{
+			ID:                 id,
+			Type:               "Multikey",
+			Controller:         did,
+			PublicKeyMultibase: strings.TrimPrefix(did, "did:key:"),
+		}},
+		Authentication:  []string{id},
+		AssertionMethod: []string{id},
+	}
+}
+
+// KeyMethodID returns the verification method ID of a did:key.
+func KeyMethodID(did string) string {
+	return did + "#" + strings.TrimPrefix(did, "did:key:")
+}
+
+// MethodResolver dispatches on the DID method ("key", "web", ...).
+type MethodResolver map[string]Resolver
+
+// Resolve implements Resolver.
+func (m MethodResolver) Resolve(ctx context.Context, did string) (*DIDDocument, error) {
+	parts := strings.SplitN(did, ":", 3)
+	if len(parts) == 3 && parts[0] == "did" {
+		if r, ok := m[parts[1]]; ok {
+			return r.Resolve(ctx, did)
+		}
+	}
+	return nil, errors.New(errors.CodeInvalidInput, fmt.Sprintf("no resolver for %q", did),
+		errors.WithDetails(map[string]interface{}{"reason": ReasonUnresolvable}))
+}
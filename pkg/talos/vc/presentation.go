@@ -0,0 +1,92 @@
+package vc
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/wallet"
+)
+
+// Presentation is a verifiable presentation: credentials bundled and signed
+// by their holder. Credentials are kept as received so that their proofs
+// stay verifiable.
+type Presentation struct {
+	Context              Contexts          `json:"@context"`
+	ID                   string            `json:"id,omitempty"`
+	Type                 Types             `json:"type"`
+	Holder               string            `json:"holder"`
+	VerifiableCredential []json.RawMessage `json:"verifiableCredential,omitempty"`
+	Proof                *Proof            `json:"proof,omitempty"`
+
+	// Credentials holds the verified credentials after VerifyPresentation.
+	Credentials []*Credential `json:"-"`
+}
+
+// Present bundles JSON-encoded credentials into a presentation signed by
+// holder for authentication. The verifier's challenge binds the
+// presentation to one exchange and domain to one audience; both are
+// recommended.
+func Present(holder *wallet.Wallet, challenge, domain string, credentials ...json.RawMessage) (*Presentation, error) {
+	p := Presentation{
+		Context:              Contexts{ContextV2},
+		Type:                 Types{TypePresentation},
+		Holder:               holder.DID(),
+		VerifiableCredential: credentials,
+	}
+	proof, err := sign(holder, &p, PurposeAuthentication, challenge, domain, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	p.Proof = proof
+	return &p, nil
+}
+
+// VerifyPresentation verifies a JSON-encoded presentation: the holder's
+// authentication proof, which must carry the expected challenge and domain,
+// and every embedded credential. Errors from a credential carry its index in
+// Details["credential"].
+func (v *Verifier) VerifyPresentation(ctx context.Context, data []byte, challenge, domain string) (*Presentation, error) {
+	doc, err := decodeDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	if !hasType(doc, TypePresentation) {
+		return nil, malformed("document is not a VerifiablePresentation")
+	}
+	holder, _ := doc["holder"].(string)
+	if holder == "" {
+		return nil, malformed("presentation has no holder")
+	}
+	proof, err := v.verifyProof(ctx, doc, PurposeAuthentication, holder)
+	if err != nil {
+		return nil, err
+	}
+	if got, _ := proof["challenge"].(string); got != challenge {
+		return nil, reject(ReasonWrongChallenge, "presentation challenge does not match")
+	}
+	if got, _ := proof["domain"].(string); got != domain {
+		return nil, reject(ReasonWrongDomain, "presentation domain does not match")
+	}
+
+	var p Presentation
+	if err := remarshal(doc, &p); err != nil {
+		return nil, err
+	}
+	for i, raw := range p.VerifiableCredential {
+		cdoc, err := decodeDocument(raw)
+		if err != nil {
+			return nil, err
+		}
+		c, err := v.verifyCredential(ctx, cdoc)
+		if err != nil {
+			if te, ok := err.(*errors.TalosError); ok && te.Details != nil {
+				te.Details["credential"] = i
+			}
+			return nil, err
+		}
+		p.Credentials = append(p.Credentials, c)
+	}
+	return &p, nil
+}
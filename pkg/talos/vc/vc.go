@@ -0,0 +1,430 @@
+// Package vc issues and verifies W3C Verifiable Credentials and
+// Presentations (VC Data Model 2.0) secured with Data Integrity proofs using
+// the eddsa-jcs-2022 cryptosuite: documents are canonicalized with RFC 8785
+// (canonical.MarshalJCS), hashed with SHA-256 and signed with Ed25519.
+//
+// Signers are wallet.Wallets identified by their did:key; verifiers resolve
+// verification methods through a Resolver, which defaults to resolving
+// did:key locally.
+package vc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/canonical"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/wallet"
+)
+
+// Well-known values.
+const (
+	ContextV2                = "https://www.w3.org/ns/credentials/v2"
+	TypeCredential           = "VerifiableCredential"
+	TypePresentation         = "VerifiablePresentation"
+	ProofType                = "DataIntegrityProof"
+	Cryptosuite              = "eddsa-jcs-2022"
+	multibaseBase58BTCPrefix = "z"
+)
+
+// Reasons reported in Details["reason"].
+const (
+	ReasonMalformed       = "malformed"
+	ReasonBadProof        = "bad_proof"
+	ReasonUnknownMethod   = "unknown_method"
+	ReasonUnresolvable    = "unresolvable"
+	ReasonUntrustedIssuer = "untrusted_issuer"
+	ReasonWrongController = "wrong_controller"
+	ReasonExpired         = "expired"
+	ReasonNotYetValid     = "not_yet_valid"
+	ReasonWrongChallenge  = "wrong_challenge"
+	ReasonWrongDomain     = "wrong_domain"
+)
+
+// Contexts is a JSON-LD @context: URL strings or context objects. A single
+// string is accepted when decoding.
+type Contexts []interface{}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (c *Contexts) UnmarshalJSON(data []byte) error {
+	var one string
+	if json.Unmarshal(data, &one) == nil {
+		*c = Contexts{one}
+		return nil
+	}
+	var many []interface{}
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*c = many
+	return nil
+}
+
+// Types is a document's type. A single string is accepted when decoding.
+type Types []string
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (t *Types) UnmarshalJSON(data []byte) error {
+	var one string
+	if json.Unmarshal(data, &one) == nil {
+		*t = Types{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*t = many
+	return nil
+}
+
+// Credential is a verifiable credential. Times are RFC 3339 strings.
+type Credential struct {
+	Context           Contexts               `json:"@context"`
+	ID                string                 `json:"id,omitempty"`
+	Type              Types                  `json:"type"`
+	Issuer            string                 `json:"issuer"`
+	ValidFrom         string                 `json:"validFrom,omitempty"`
+	ValidUntil        string                 `json:"validUntil,omitempty"`
+	CredentialSubject map[string]interface{} `json:"credentialSubject"`
+	Proof             *Proof                 `json:"proof,omitempty"`
+}
+
+// Proof is a Data Integrity proof.
+type Proof struct {
+	Type               string `json:"type"`
+	Cryptosuite        string `json:"cryptosuite"`
+	Created            string `json:"created,omitempty"`
+	VerificationMethod string `json:"verificationMethod"`
+	ProofPurpose       string `json:"proofPurpose"`
+	Challenge          string `json:"challenge,omitempty"`
+	Domain             string `json:"domain,omitempty"`
+	ProofValue         string `json:"proofValue"`
+}
+
+// Issue signs c as issuer. Context, Type and ValidFrom default to the VC 2.0
+// context, VerifiableCredential and now; Issuer, if set, must be the
+// wallet's DID.
+func Issue(issuer *wallet.Wallet, c Credential) (*Credential, error) {
+	if c.Issuer == "" {
+		c.Issuer = issuer.DID()
+	}
+	if c.Issuer != issuer.DID() {
+		return nil, malformed("credential issuer does not match the signing wallet")
+	}
+	if len(c.CredentialSubject) == 0 {
+		return nil, malformed("credential has no subject")
+	}
+	if len(c.Context) == 0 {
+		c.Context = Contexts{ContextV2}
+	}
+	if !containsString(c.Type, TypeCredential) {
+		c.Type = append([]string{TypeCredential}, c.Type...)
+	}
+	now := time.Now().UTC()
+	if c.ValidFrom == "" {
+		c.ValidFrom = now.Format(time.RFC3339)
+	}
+	c.Proof = nil
+
+	proof, err := sign(issuer, &c, PurposeAssertion, "", "", now)
+	if err != nil {
+		return nil, err
+	}
+	c.Proof = proof
+	return &c, nil
+}
+
+// Verifier verifies credentials and presentations.
+type Verifier struct {
+	resolver Resolver
+	trusted  map[string]bool
+	now      func() time.Time
+}
+
+// Option configures a Verifier.
+type Option func(*Verifier)
+
+// WithResolver sets the DID resolver. The default resolves did:key only.
+func WithResolver(r Resolver) Option {
+	return func(v *Verifier) {
+		v.resolver = r
+	}
+}
+
+// WithTrustedIssuers restricts accepted credentials to those issued by
+// dids. Without it any issuer whose proof verifies is accepted.
+func WithTrustedIssuers(dids ...string) Option {
+	return func(v *Verifier) {
+		if v.trusted == nil {
+			v.trusted = make(map[string]bool)
+		}
+		for _, d := range dids {
+			v.trusted[d] = true
+		}
+	}
+}
+
+// WithClock overrides the time source for validity checks.
+func WithClock(now func() time.Time) Option {
+	return func(v *Verifier) {
+		v.now = now
+	}
+}
+
+// NewVerifier creates a verifier.
+func NewVerifier(opts ...Option) *Verifier {
+	v := &Verifier{resolver: KeyResolver{}, now: time.Now}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+// VerifyCredential verifies a JSON-encoded credential: its proof, that it
+// was made by the issuer with an assertion method, the issuer's trust and
+// the validity period. The proof covers the document as received, so
+// properties this package does not model are still protected.
+func (v *Verifier) VerifyCredential(ctx context.Context, data []byte) (*Credential, error) {
+	doc, err := decodeDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	return v.verifyCredential(ctx, doc)
+}
+
+func (v *Verifier) verifyCredential(ctx context.Context, doc map[string]interface{}) (*Credential, error) {
+	if !hasType(doc, TypeCredential) {
+		return nil, malformed("document is not a VerifiableCredential")
+	}
+	// VC 2.0 allows the issuer to be an object with an id.
+	issuer, _ := doc["issuer"].(string)
+	if obj, ok := doc["issuer"].(map[string]interface{}); ok {
+		issuer, _ = obj["id"].(string)
+	}
+	if issuer == "" {
+		return nil, malformed("credential has no issuer")
+	}
+	if _, err := v.verifyProof(ctx, doc, PurposeAssertion, issuer); err != nil {
+		return nil, err
+	}
+	if v.trusted != nil && !v.trusted[issuer] {
+		return nil, reject(ReasonUntrustedIssuer, fmt.Sprintf("issuer %s is not trusted", issuer))
+	}
+
+	var c Credential
+	typed := make(map[string]interface{}, len(doc))
+	for k, val := range doc {
+		typed[k] = val
+	}
+	typed["issuer"] = issuer
+	if err := remarshal(typed, &c); err != nil {
+		return nil, err
+	}
+	if err := v.checkValidity(c.ValidFrom, c.ValidUntil); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (v *Verifier) checkValidity(from, until string) error {
+	now := v.now()
+	if from != "" {
+		t, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return malformed("validFrom is not an RFC 3339 time")
+		}
+		if now.Before(t) {
+			return reject(ReasonNotYetValid, "credential is not yet valid")
+		}
+	}
+	if until != "" {
+		t, err := time.Parse(time.RFC3339, until)
+		if err != nil {
+			return malformed("validUntil is not an RFC 3339 time")
+		}
+		if now.After(t) {
+			return reject(ReasonExpired, "credential has expired")
+		}
+	}
+	return nil
+}
+
+// sign creates an eddsa-jcs-2022 proof over doc, which must not contain a
+// proof.
+func sign(w *wallet.Wallet, doc interface{}, purpose, challenge, domain string, created time.Time) (*Proof, error) {
+	unsecured, err := toMap(doc)
+	if err != nil {
+		return nil, err
+	}
+	p := &Proof{
+		Type:               ProofType,
+		Cryptosuite:        Cryptosuite,
+		Created:            created.UTC().Format(time.RFC3339),
+		VerificationMethod: KeyMethodID(w.DID()),
+		ProofPurpose:       purpose,
+		Challenge:          challenge,
+		Domain:             domain,
+	}
+	options, err := toMap(p)
+	if err != nil {
+		return nil, err
+	}
+	delete(options, "proofValue")
+	hash, err := hashData(unsecured, options)
+	if err != nil {
+		return nil, err
+	}
+	p.ProofValue = multibaseBase58BTCPrefix + wallet.EncodeBase58(w.Sign(hash))
+	return p, nil
+}
+
+// verifyProof checks doc's proof against the key of its verification
+// method, which must be authorized for purpose and controlled by
+// controller. It returns the proof.
+func (v *Verifier) verifyProof(ctx context.Context, doc map[string]interface{}, purpose, controller string) (map[string]interface{}, error) {
+	proof, ok := doc["proof"].(map[string]interface{})
+	if !ok {
+		return nil, reject(ReasonBadProof, "document has no single proof")
+	}
+	if proof["type"] != ProofType || proof["cryptosuite"] != Cryptosuite {
+		return nil, reject(ReasonBadProof, "unsupported proof type or cryptosuite")
+	}
+	if proof["proofPurpose"] != purpose {
+		return nil, reject(ReasonBadProof, fmt.Sprintf("proof purpose is not %s", purpose))
+	}
+	method, _ := proof["verificationMethod"].(string)
+	if did, _, _ := strings.Cut(method, "#"); did != controller {
+		return nil, reject(ReasonWrongController, fmt.Sprintf("proof is not made by %s", controller))
+	}
+	value, _ := proof["proofValue"].(string)
+	if !strings.HasPrefix(value, multibaseBase58BTCPrefix) {
+		return nil, reject(ReasonBadProof, "proof value is not base58btc multibase")
+	}
+	sig, err := wallet.DecodeBase58(value[1:])
+	if err != nil {
+		return nil, reject(ReasonBadProof, "proof value is not base58btc multibase")
+	}
+
+	didDoc, err := v.resolver.Resolve(ctx, controller)
+	if err != nil {
+		return nil, err
+	}
+	vm, err := didDoc.Method(method, purpose)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := vm.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+
+	unsecured := make(map[string]interface{}, len(doc))
+	for k, val := range doc {
+		if k != "proof" {
+			unsecured[k] = val
+		}
+	}
+	options := make(map[string]interface{}, len(proof))
+	for k, val := range proof {
+		if k != "proofValue" {
+			options[k] = val
+		}
+	}
+	hash, err := hashData(unsecured, options)
+	if err != nil {
+		return nil, err
+	}
+	if !wallet.Verify(pub, hash, sig) {
+		return nil, reject(ReasonBadProof, "proof does not verify")
+	}
+	return proof, nil
+}
+
+// hashData implements the eddsa-jcs-2022 hashing step: SHA-256 of the
+// canonical proof configuration, which takes the document's @context,
+// followed by SHA-256 of the canonical unsecured document.
+func hashData(unsecured, options map[string]interface{}) ([]byte, error) {
+	if ctx, ok := unsecured["@context"]; ok {
+		options["@context"] = ctx
+	}
+	config, err := canonical.MarshalJCS(options)
+	if err != nil {
+		return nil, malformedCause("failed to canonicalize proof configuration", err)
+	}
+	document, err := canonical.MarshalJCS(unsecured)
+	if err != nil {
+		return nil, malformedCause("failed to canonicalize document", err)
+	}
+	configHash, docHash := sha256.Sum256(config), sha256.Sum256(document)
+	return append(configHash[:], docHash[:]...), nil
+}
+
+func decodeDocument(data []byte) (map[string]interface{}, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil || doc == nil {
+		return nil, malformed("document is not a JSON object")
+	}
+	return doc, nil
+}
+
+func toMap(v interface{}) (map[string]interface{}, error) {
+	var m map[string]interface{}
+	if err := remarshal(v, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func remarshal(in, out interface{}) error {
+	b, err := json.Marshal(in)
+	if err != nil {
+		return malformedCause("failed to encode document", err)
+	}
+	if err := json.Unmarshal(b, out); err != nil {
+		return malformedCause("document does not match the data model", err)
+	}
+	return nil
+}
+
+func hasType(doc map[string]interface{}, want string) bool {
+	switch t := doc["type"].(type) {
+	case string:
+		return t == want
+	case []interface{}:
+		for _, e := range t {
+			if e == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func containsString(set []string, v string) bool {
+	for _, s := range set {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+func reject(reason, message string) error {
+	return errors.New(errors.CodeCryptoError, message,
+		errors.WithDetails(map[string]interface{}{"reason": reason}))
+}
+
+func malformed(message string) error {
+	return errors.New(errors.CodeInvalidInput, message,
+		errors.WithDetails(map[string]interface{}{"reason": ReasonMalformed}))
+}
+
+func malformedCause(message string, cause error) error {
+	return errors.New(errors.CodeInvalidInput, message, errors.WithCause(cause),
+		errors.WithDetails(map[string]interface{}{"reason": ReasonMalformed}))
+}
@@ -0,0 +1,263 @@
+package vc
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/wallet"
+)
+
+func newWallet(t *testing.T, b byte) *wallet.Wallet {
+	t.Helper()
+	w, err := wallet.FromSeed(bytes.Repeat([]byte{b}, 32), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func expectReason(t *testing.T, err error, reason string) {
+	t.Helper()
+	te, ok := err.(*errors.TalosError)
+	if !ok {
+		t.Fatalf("expected *TalosError, got %v", err)
+	}
+	if got, _ := te.Details["reason"].(string); got != reason {
+		t.Errorf("expected reason %q, got %q (%v)", reason, got, err)
+	}
+}
+
+func issue(t *testing.T, issuer, agent *wallet.Wallet) []byte {
+	t.Helper()
+	c, err := Issue(issuer, Credential{
+		Type:       []string{"ProductionAccessCredential"},
+		ValidUntil: time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+		CredentialSubject: map[string]interface{}{
+			"id":        agent.DID(),
+			"certified": "production-data",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+	b, _ := json.Marshal(c)
+	return b
+}
+
+func TestIssueAndVerify(t *testing.T) {
+	issuer, agent := newWallet(t, 1), newWallet(t, 2)
+	data := issue(t, issuer, agent)
+	ctx := context.Background()
+
+	c, err := NewVerifier(WithTrustedIssuers(issuer.DID())).VerifyCredential(ctx, data)
+	if err != nil {
+		t.Fatalf("VerifyCredential failed: %v", err)
+	}
+	if c.Issuer != issuer.DID() || c.CredentialSubject["certified"] != "production-data" {
+		t.Errorf("unexpected credential: %+v", c)
+	}
+	if c.Proof.Cryptosuite != Cryptosuite || c.Proof.VerificationMethod != KeyMethodID(issuer.DID()) {
+		t.Errorf("unexpected proof: %+v", c.Proof)
+	}
+
+	// Tampering with any property, even one the package does not model,
+	// breaks the proof.
+	var doc map[string]interface{}
+	json.Unmarshal(data, &doc)
+	doc["credentialSubject"].(map[string]interface{})["certified"] = "everything"
+	tampered, _ := json.Marshal(doc)
+	_, err = NewVerifier().VerifyCredential(ctx, tampered)
+	expectReason(t, err, ReasonBadProof)
+
+	json.Unmarshal(data, &doc)
+	doc["evidence"] = "added later"
+	tampered, _ = json.Marshal(doc)
+	_, err = NewVerifier().VerifyCredential(ctx, tampered)
+	expectReason(t, err, ReasonBadProof)
+
+	// Reformatting does not matter.
+	var indented bytes.Buffer
+	json.Indent(&indented, data, "", "  ")
+	if _, err := NewVerifier().VerifyCredential(ctx, indented.Bytes()); err != nil {
+		t.Errorf("reformatted credential failed: %v", err)
+	}
+
+	// Trust, validity and controller checks.
+	_, err = NewVerifier(WithTrustedIssuers(agent.DID())).VerifyCredential(ctx, data)
+	expectReason(t, err, ReasonUntrustedIssuer)
+	_, err = NewVerifier(WithClock(func() time.Time { return time.Now().Add(2 * time.Hour) })).VerifyCredential(ctx, data)
+	expectReason(t, err, ReasonExpired)
+	_, err = NewVerifier(WithClock(func() time.Time { return time.Now().Add(-time.Hour) })).VerifyCredential(ctx, data)
+	expectReason(t, err, ReasonNotYetValid)
+
+	json.Unmarshal(data, &doc)
+	doc["issuer"] = agent.DID()
+	forged, _ := json.Marshal(doc)
+	_, err = NewVerifier().VerifyCredential(ctx, forged)
+	expectReason(t, err, ReasonWrongController)
+
+	if _, err := Issue(issuer, Credential{Issuer: agent.DID(), CredentialSubject: map[string]interface{}{"id": "x"}}); err == nil {
+		t.Error("expected error when issuing in someone else's name")
+	}
+}
+
+// The eddsa-jcs-2022 test vector from the W3C Data Integrity EdDSA
+// Cryptosuites v1.0 specification.
+const (
+	vectorSecretKey = "z3u2en7t5LR2WtQH5PfFqMqwVHBeXouLzo6haApm8XHqvjxq"
+	vectorDID       = "did:key:z6MkrJVnaZkeFzdQyMZu1cgjg7k1pZZ6pvBQ7XJPt4swbTQ2"
+	vectorDocHash   = "59b7cb6251b8991add1ce0bc83107e3db9dbbab5bd2c28f687db1a03abc92f19"
+	vectorConfHash  = "66ab154f5c2890a140cb8388a22a160454f80575f6eae09e5a097cabe539a1db"
+	vectorProof     = "z2HnFSSPPBzR36zdDgK8PbEHeXbR56YF24jwMpt3R1eHXQzJDMWS93FCzpvJpwTWd3GAVFuUfjoJdcnTMuVor51aX"
+	vectorDocument  = `{
+  "@context": ["https://www.w3.org/ns/credentials/v2", "https://www.w3.org/ns/credentials/examples/v2"],
+  "id": "urn:uuid:58172aac-d8ba-11ed-83dd-0b3aef56cc33",
+  "type": ["VerifiableCredential", "AlumniCredential"],
+  "name": "Alumni Credential",
+  "description": "A minimum viable example of an Alumni Credential.",
+  "issuer": "https://vc.example/issuers/5678",
+  "validFrom": "2023-01-01T00:00:00Z",
+  "credentialSubject": {"id": "did:example:abcdefgh", "alumniOf": "The School of Examples"}
+}`
+)
+
+func TestSpecVector(t *testing.T) {
+	// The secret key is multibase with the ed25519-priv multicodec prefix.
+	raw, err := wallet.DecodeBase58(strings.TrimPrefix(vectorSecretKey, "z"))
+	if err != nil || len(raw) != 34 || raw[0] != 0x80 || raw[1] != 0x26 {
+		t.Fatalf("bad vector secret key: %x (%v)", raw, err)
+	}
+	w, err := wallet.FromSeed(raw[2:], "")
+	if err != nil || w.DID() != vectorDID {
+		t.Fatalf("expected %s, got %s (%v)", vectorDID, w.DID(), err)
+	}
+
+	doc, err := decodeDocument([]byte(vectorDocument))
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof, err := sign(w, doc, PurposeAssertion, "", "", time.Date(2023, 2, 24, 23, 36, 38, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("sign failed: %v", err)
+	}
+	if proof.ProofValue != vectorProof {
+		t.Errorf("expected proof value %s, got %s", vectorProof, proof.ProofValue)
+	}
+	options, _ := toMap(proof)
+	delete(options, "proofValue")
+	hash, _ := hashData(doc, options)
+	if got := hex.EncodeToString(hash); got != vectorConfHash+vectorDocHash {
+		t.Errorf("unexpected hash data %s", got)
+	}
+
+	// The issuer is not a DID, so check the proof against the signing key
+	// directly.
+	doc["proof"], _ = toMap(proof)
+	if _, err := NewVerifier().verifyProof(context.Background(), doc, PurposeAssertion, vectorDID); err != nil {
+		t.Errorf("vector proof does not verify: %v", err)
+	}
+}
+
+func TestPermissiveDecoding(t *testing.T) {
+	// VC 2.0 allows a single string type and context objects.
+	issuer := newWallet(t, 1)
+	doc := map[string]interface{}{
+		"@context":          []interface{}{ContextV2, map[string]interface{}{"@vocab": "https://example.org/vocab#"}},
+		"type":              TypeCredential,
+		"issuer":            issuer.DID(),
+		"credentialSubject": map[string]interface{}{"id": "did:example:abc"},
+	}
+	proof, err := sign(issuer, doc, PurposeAssertion, "", "", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc["proof"] = proof
+	data, _ := json.Marshal(doc)
+
+	c, err := NewVerifier().VerifyCredential(context.Background(), data)
+	if err != nil {
+		t.Fatalf("VerifyCredential failed: %v", err)
+	}
+	if len(c.Type) != 1 || c.Type[0] != TypeCredential || len(c.Context) != 2 {
+		t.Errorf("unexpected credential: %+v", c)
+	}
+
+	var single Credential
+	if err := json.Unmarshal([]byte(`{"@context":"`+ContextV2+`","type":"VerifiableCredential"}`), &single); err != nil ||
+		len(single.Context) != 1 || len(single.Type) != 1 {
+		t.Errorf("unexpected decoding %+v (%v)", single, err)
+	}
+}
+
+func TestResolver(t *testing.T) {
+	issuer, agent := newWallet(t, 1), newWallet(t, 2)
+	data := issue(t, issuer, agent)
+	ctx := context.Background()
+
+	// A did:key document whose key was rotated away no longer verifies.
+	rotated := ResolverFunc(func(ctx context.Context, did string) (*DIDDocument, error) {
+		doc := KeyDocument(did)
+		doc.AssertionMethod = nil
+		return doc, nil
+	})
+	_, err := NewVerifier(WithResolver(rotated)).VerifyCredential(ctx, data)
+	expectReason(t, err, ReasonUnknownMethod)
+
+	// Methods without a resolver cannot be resolved.
+	r := MethodResolver{"key": KeyResolver{}}
+	if _, err := r.Resolve(ctx, issuer.DID()); err != nil {
+		t.Errorf("Resolve failed: %v", err)
+	}
+	_, err = r.Resolve(ctx, "did:web:example.com")
+	expectReason(t, err, ReasonUnresolvable)
+	_, err = KeyResolver{}.Resolve(ctx, "did:key:zbad")
+	expectReason(t, err, ReasonUnresolvable)
+}
+
+func TestPresentation(t *testing.T) {
+	issuer, agent, other := newWallet(t, 1), newWallet(t, 2), newWallet(t, 3)
+	cred := issue(t, issuer, agent)
+	ctx := context.Background()
+	v := NewVerifier(WithTrustedIssuers(issuer.DID()))
+
+	p, err := Present(agent, "nonce-123", "gateway.example", cred)
+	if err != nil {
+		t.Fatalf("Present failed: %v", err)
+	}
+	data, _ := json.Marshal(p)
+	got, err := v.VerifyPresentation(ctx, data, "nonce-123", "gateway.example")
+	if err != nil {
+		t.Fatalf("VerifyPresentation failed: %v", err)
+	}
+	if got.Holder != agent.DID() || len(got.Credentials) != 1 || got.Credentials[0].Issuer != issuer.DID() {
+		t.Errorf("unexpected presentation: %+v", got)
+	}
+
+	_, err = v.VerifyPresentation(ctx, data, "other-nonce", "gateway.example")
+	expectReason(t, err, ReasonWrongChallenge)
+	_, err = v.VerifyPresentation(ctx, data, "nonce-123", "evil.example")
+	expectReason(t, err, ReasonWrongDomain)
+
+	// Someone else cannot present as the holder.
+	forged, _ := Present(other, "nonce-123", "gateway.example", cred)
+	forged.Holder = agent.DID()
+	data, _ = json.Marshal(forged)
+	_, err = v.VerifyPresentation(ctx, data, "nonce-123", "gateway.example")
+	expectReason(t, err, ReasonWrongController)
+
+	// A bad embedded credential fails the presentation.
+	bad := []byte(strings.Replace(string(cred), "production-data", "all-data", 1))
+	p, _ = Present(agent, "n", "d", cred, bad)
+	data, _ = json.Marshal(p)
+	_, err = v.VerifyPresentation(ctx, data, "n", "d")
+	expectReason(t, err, ReasonBadProof)
+	if te := err.(*errors.TalosError); te.Details["credential"] != 1 {
+		t.Errorf("expected failing credential index 1, got %v", te.Details["credential"])
+	}
+}
@@ -1,13 +1,20 @@
 package wallet
 
 import (
+	"crypto/ecdh"
 	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
 	"encoding/hex"
 	"fmt"
+	"io"
 	"math/big"
+	"strings"
 
 	"github.com/talosprotocol/talos-sdk-go/pkg/talos/crypto"
 	"github.com/talosprotocol/talos-sdk-go/pkg/talos/errors"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/multihash"
 )
 
 const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
@@ -41,6 +48,52 @@ func EncodeBase58(input []byte) string {
 	return string(result)
 }
 
+// DecodeBase58 decodes a base58 (Bitcoin style) string.
+func DecodeBase58(input string) ([]byte, error) {
+	x := new(big.Int)
+	base := big.NewInt(58)
+	for i := 0; i < len(input); i++ {
+		d := strings.IndexByte(base58Alphabet, input[i])
+		if d < 0 {
+			return nil, errors.New(errors.CodeInvalidInput, fmt.Sprintf("invalid base58 character %q", input[i]))
+		}
+		x.Mul(x, base)
+		x.Add(x, big.NewInt(int64(d)))
+	}
+
+	// Leading '1's encode leading zero bytes.
+	zeros := 0
+	for zeros < len(input) && input[zeros] == base58Alphabet[0] {
+		zeros++
+	}
+	return append(make([]byte, zeros), x.Bytes()...), nil
+}
+
+// didKeyPrefix is the did:key multibase/multicodec prefix for Ed25519 keys.
+var didKeyPrefix = []byte{0xed, 0x01}
+
+// PublicKeyFromDID extracts the Ed25519 public key from a did:key identifier.
+func PublicKeyFromDID(did string) ([]byte, error) {
+	const scheme = "did:key:z"
+	if !strings.HasPrefix(did, scheme) {
+		return nil, errors.New(errors.CodeInvalidInput, "not a base58 did:key identifier")
+	}
+	raw, err := DecodeBase58(did[len(scheme):])
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) != len(didKeyPrefix)+ed25519.PublicKeySize || raw[0] != didKeyPrefix[0] || raw[1] != didKeyPrefix[1] {
+		return nil, errors.New(errors.CodeInvalidInput, "did:key is not an Ed25519 key")
+	}
+	return raw[len(didKeyPrefix):], nil
+}
+
+// DIDFromPublicKey returns the did:key identifier for an Ed25519 public key.
+func DIDFromPublicKey(publicKey []byte) string {
+	input := append(append([]byte(nil), didKeyPrefix...), publicKey...)
+	return "did:key:z" + EncodeBase58(input)
+}
+
 // Wallet represents a Talos identity.
 type Wallet struct {
 	privateKey ed25519.PrivateKey
@@ -48,10 +101,30 @@ type Wallet struct {
 	name       string
 }
 
+// GenerateOption configures Generate.
+type GenerateOption func(*generateConfig)
+
+type generateConfig struct {
+	entropy io.Reader
+}
+
+// WithEntropy draws the key from r instead of crypto/rand. It exists for
+// reproducible tests (see crypto/cryptotest); production code should not
+// use it.
+func WithEntropy(r io.Reader) GenerateOption {
+	return func(c *generateConfig) {
+		c.entropy = r
+	}
+}
+
 // Generate creates a new wallet securely.
 // Name is optional.
-func Generate(name string) (*Wallet, error) {
-	pub, priv, err := crypto.GenerateKey()
+func Generate(name string, opts ...GenerateOption) (*Wallet, error) {
+	var cfg generateConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	pub, priv, err := crypto.GenerateKeyFrom(cfg.entropy)
 	if err != nil {
 		return nil, errors.New(errors.CodeCryptoError, "failed to generate key", errors.WithCause(err))
 	}
@@ -89,12 +162,39 @@ func (w *Wallet) Address() string {
 	return hex.EncodeToString(hash)
 }
 
+// AddressWith returns the hex multihash of the public key under the given
+// algorithm. For multihash.SHA2_256 it is "1220" followed by Address().
+func (w *Wallet) AddressWith(code multihash.Code) (string, error) {
+	mh, err := multihash.Sum(code, w.PublicKey())
+	if err != nil {
+		return "", err
+	}
+	return mh.String(), nil
+}
+
+// MatchesAddress reports whether address identifies publicKey. It accepts
+// both the legacy SHA-256 hex form returned by Address and the multihash
+// form returned by AddressWith.
+func MatchesAddress(publicKey []byte, address string) bool {
+	if len(address) == 2*sha256.Size {
+		legacy, err := hex.DecodeString(address)
+		if err != nil {
+			return false
+		}
+		return subtle.ConstantTimeCompare(legacy, crypto.SHA256(publicKey)) == 1
+	}
+	mh, err := multihash.Parse(address)
+	if err != nil {
+		return false
+	}
+	ok, err := mh.Verify(publicKey)
+	return err == nil && ok
+}
+
 // DID returns the did:key identifier.
 // Format: did:key:z + base58(0xed01 + pubkey)
 func (w *Wallet) DID() string {
-	prefix := []byte{0xed, 0x01}
-	input := append(prefix, w.PublicKey()...)
-	return "did:key:z" + EncodeBase58(input)
+	return DIDFromPublicKey(w.PublicKey())
 }
 
 // Sign signs a message.
@@ -102,6 +202,104 @@ func (w *Wallet) Sign(message []byte) []byte {
 	return crypto.Sign(w.privateKey, message)
 }
 
+// Variant selects an RFC 8032 Ed25519 signature scheme.
+type Variant string
+
+const (
+	// VariantEd25519 is pure Ed25519, as used by Sign.
+	VariantEd25519 Variant = "Ed25519"
+	// VariantEd25519ctx is Ed25519 with a mandatory domain-separation context.
+	VariantEd25519ctx Variant = "Ed25519ctx"
+	// VariantEd25519ph signs a SHA-512 pre-hash of the message, so large
+	// messages can be streamed.
+	VariantEd25519ph Variant = "Ed25519ph"
+)
+
+// SignOptions selects the variant and context for SignWithOptions.
+type SignOptions struct {
+	Variant Variant
+	Context string
+}
+
+func (o SignOptions) validate() error {
+	if len(o.Context) > crypto.MaxContextSize {
+		return errors.New(errors.CodeInvalidInput, fmt.Sprintf("context must be at most %d bytes", crypto.MaxContextSize))
+	}
+	switch o.Variant {
+	case "", VariantEd25519:
+		if o.Context != "" {
+			return errors.New(errors.CodeInvalidInput, "pure Ed25519 does not take a context; use Ed25519ctx")
+		}
+	case VariantEd25519ctx:
+		if o.Context == "" {
+			return errors.New(errors.CodeInvalidInput, "Ed25519ctx requires a non-empty context")
+		}
+	case VariantEd25519ph:
+	default:
+		return errors.New(errors.CodeInvalidInput, fmt.Sprintf("unknown signature variant %q", o.Variant))
+	}
+	return nil
+}
+
+// SignWithOptions signs a message with the chosen variant. For Ed25519ph the
+// message is the full message, hashed internally; use SignStream to avoid
+// buffering it.
+func (w *Wallet) SignWithOptions(message []byte, opts SignOptions) ([]byte, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	var sig []byte
+	var err error
+	switch opts.Variant {
+	case VariantEd25519ctx:
+		sig, err = crypto.SignCtx(w.privateKey, message, opts.Context)
+	case VariantEd25519ph:
+		digest := sha512.Sum512(message)
+		sig, err = crypto.SignPrehashed(w.privateKey, digest[:], opts.Context)
+	default:
+		sig = crypto.Sign(w.privateKey, message)
+	}
+	if err != nil {
+		return nil, errors.New(errors.CodeCryptoError, "failed to sign", errors.WithCause(err))
+	}
+	return sig, nil
+}
+
+// SignStream signs the message read from r with Ed25519ph.
+func (w *Wallet) SignStream(r io.Reader, context string) ([]byte, error) {
+	if len(context) > crypto.MaxContextSize {
+		return nil, errors.New(errors.CodeInvalidInput, fmt.Sprintf("context must be at most %d bytes", crypto.MaxContextSize))
+	}
+	sig, err := crypto.SignPH(w.privateKey, r, context)
+	if err != nil {
+		return nil, errors.New(errors.CodeCryptoError, "failed to sign stream", errors.WithCause(err))
+	}
+	return sig, nil
+}
+
+// VerifyWithOptions verifies a signature made with SignWithOptions.
+func VerifyWithOptions(publicKey, message, signature []byte, opts SignOptions) bool {
+	if len(publicKey) != ed25519.PublicKeySize || opts.validate() != nil {
+		return false
+	}
+	switch opts.Variant {
+	case VariantEd25519ctx:
+		return crypto.VerifyCtx(publicKey, message, signature, opts.Context)
+	case VariantEd25519ph:
+		digest := sha512.Sum512(message)
+		return crypto.VerifyPrehashed(publicKey, digest[:], signature, opts.Context)
+	}
+	return crypto.Verify(publicKey, message, signature)
+}
+
+// VerifyStream verifies an Ed25519ph signature over the message read from r.
+func VerifyStream(publicKey []byte, r io.Reader, signature []byte, context string) (bool, error) {
+	if len(publicKey) != ed25519.PublicKeySize {
+		return false, nil
+	}
+	return crypto.VerifyPH(publicKey, r, signature, context)
+}
+
 // Verify verifies a signature.
 func Verify(publicKey, message, signature []byte) bool {
 	if len(publicKey) != ed25519.PublicKeySize {
@@ -111,6 +309,54 @@ func Verify(publicKey, message, signature []byte) bool {
 	return crypto.Verify(ed25519.PublicKey(publicKey), message, signature)
 }
 
+// SignedMessage is a (public key, message, signature) tuple for VerifyBatch.
+type SignedMessage struct {
+	PublicKey []byte
+	Message   []byte
+	Signature []byte
+}
+
+// VerifyBatch verifies many signatures at once and returns the indices of
+// the invalid ones (nil if all are valid). It is about 1.6 times as fast as
+// calling Verify in a loop. Every signature is checked with the cofactored
+// equation, so it may accept signatures crafted with small-order components
+// that Verify rejects; see crypto.VerifyBatch.
+func VerifyBatch(batch []SignedMessage) []int {
+	entries := make([]crypto.BatchEntry, len(batch))
+	for i, m := range batch {
+		entries[i] = crypto.BatchEntry{PublicKey: ed25519.PublicKey(m.PublicKey), Message: m.Message, Signature: m.Signature}
+	}
+	return crypto.VerifyBatch(entries)
+}
+
+// X25519PublicKey returns the X25519 form of the wallet's public key.
+func (w *Wallet) X25519PublicKey() []byte {
+	pub, err := crypto.X25519PublicKey(w.publicKey)
+	if err != nil {
+		// Unreachable: the key was derived locally and is always valid.
+		panic(err)
+	}
+	return pub.Bytes()
+}
+
+// SharedSecret performs X25519 between the wallet's key and a peer X25519
+// public key. It fails on low-order peer keys.
+func (w *Wallet) SharedSecret(peerX25519 []byte) ([]byte, error) {
+	priv, err := crypto.X25519PrivateKey(w.privateKey)
+	if err != nil {
+		return nil, errors.New(errors.CodeCryptoError, "failed to derive X25519 key", errors.WithCause(err))
+	}
+	peer, err := ecdh.X25519().NewPublicKey(peerX25519)
+	if err != nil {
+		return nil, errors.New(errors.CodeInvalidInput, "invalid X25519 public key", errors.WithCause(err))
+	}
+	secret, err := priv.ECDH(peer)
+	if err != nil {
+		return nil, errors.New(errors.CodeCryptoError, "X25519 key agreement failed", errors.WithCause(err))
+	}
+	return secret, nil
+}
+
 // Name returns the wallet name.
 func (w *Wallet) Name() string {
 	return w.name
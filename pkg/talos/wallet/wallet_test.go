@@ -1,7 +1,11 @@
 package wallet
 
 import (
+	"bytes"
 	"testing"
+
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/crypto/cryptotest"
+	"github.com/talosprotocol/talos-sdk-go/pkg/talos/multihash"
 )
 
 func TestGenerate(t *testing.T) {
@@ -77,3 +81,178 @@ func TestEncodeBase58(t *testing.T) {
 		t.Error("encoded string is empty")
 	}
 }
+
+func TestDecodeBase58(t *testing.T) {
+	for _, input := range [][]byte{[]byte("hello world"), {0, 0, 1, 2}, {}} {
+		decoded, err := DecodeBase58(EncodeBase58(input))
+		if err != nil {
+			t.Fatalf("DecodeBase58 failed: %v", err)
+		}
+		if string(decoded) != string(input) {
+			t.Errorf("round trip mismatch: got %x, want %x", decoded, input)
+		}
+	}
+
+	if _, err := DecodeBase58("0OIl"); err == nil {
+		t.Error("expected error for invalid characters")
+	}
+}
+
+func TestPublicKeyFromDID(t *testing.T) {
+	w, _ := Generate("did")
+	pub, err := PublicKeyFromDID(w.DID())
+	if err != nil {
+		t.Fatalf("PublicKeyFromDID failed: %v", err)
+	}
+	if string(pub) != string(w.PublicKey()) {
+		t.Error("public key mismatch")
+	}
+
+	for _, bad := range []string{"did:web:example.com", "did:key:z", "did:key:z" + EncodeBase58([]byte{0x12, 0x00, 1})} {
+		if _, err := PublicKeyFromDID(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestSharedSecret(t *testing.T) {
+	alice, _ := Generate("alice")
+	bob, _ := Generate("bob")
+
+	ab, err := alice.SharedSecret(bob.X25519PublicKey())
+	if err != nil {
+		t.Fatalf("SharedSecret failed: %v", err)
+	}
+	ba, err := bob.SharedSecret(alice.X25519PublicKey())
+	if err != nil {
+		t.Fatalf("SharedSecret failed: %v", err)
+	}
+	if string(ab) != string(ba) {
+		t.Error("shared secrets differ")
+	}
+
+	if _, err := alice.SharedSecret(make([]byte, 32)); err == nil {
+		t.Error("expected error for low-order point")
+	}
+}
+
+func TestVerifyBatch(t *testing.T) {
+	var batch []SignedMessage
+	for i := 0; i < 10; i++ {
+		w, _ := Generate("")
+		msg := []byte{byte(i)}
+		batch = append(batch, SignedMessage{PublicKey: w.PublicKey(), Message: msg, Signature: w.Sign(msg)})
+	}
+	if invalid := VerifyBatch(batch); invalid != nil {
+		t.Fatalf("unexpected invalid indices %v", invalid)
+	}
+
+	batch[4].Message = []byte("other")
+	batch[7].PublicKey = batch[7].PublicKey[:5]
+	invalid := VerifyBatch(batch)
+	if len(invalid) != 2 || invalid[0] != 4 || invalid[1] != 7 {
+		t.Errorf("expected [4 7], got %v", invalid)
+	}
+}
+
+func TestSignWithOptions(t *testing.T) {
+	w, _ := Generate("variants")
+	msg := []byte("release manifest")
+
+	for _, opts := range []SignOptions{
+		{},
+		{Variant: VariantEd25519},
+		{Variant: VariantEd25519ctx, Context: "talos/audit"},
+		{Variant: VariantEd25519ph},
+		{Variant: VariantEd25519ph, Context: "talos/artifact"},
+	} {
+		sig, err := w.SignWithOptions(msg, opts)
+		if err != nil {
+			t.Fatalf("%+v: SignWithOptions failed: %v", opts, err)
+		}
+		if !VerifyWithOptions(w.PublicKey(), msg, sig, opts) {
+			t.Errorf("%+v: VerifyWithOptions failed", opts)
+		}
+		other := opts
+		other.Context += "x"
+		if other.Variant == VariantEd25519 || other.Variant == "" {
+			other = SignOptions{Variant: VariantEd25519ctx, Context: "x"}
+		}
+		if VerifyWithOptions(w.PublicKey(), msg, sig, other) {
+			t.Errorf("%+v: signature verified with %+v", opts, other)
+		}
+	}
+
+	for _, bad := range []SignOptions{
+		{Variant: VariantEd25519ctx},
+		{Variant: VariantEd25519, Context: "x"},
+		{Variant: "Ed448"},
+		{Variant: VariantEd25519ph, Context: string(make([]byte, 256))},
+	} {
+		if _, err := w.SignWithOptions(msg, bad); err == nil {
+			t.Errorf("%+v: expected error", bad)
+		}
+	}
+}
+
+func TestSignStream(t *testing.T) {
+	w, _ := Generate("stream")
+	data := bytes.Repeat([]byte{7}, 100000)
+
+	sig, err := w.SignStream(bytes.NewReader(data), "ctx")
+	if err != nil {
+		t.Fatalf("SignStream failed: %v", err)
+	}
+	// Streaming and buffered Ed25519ph agree.
+	if !VerifyWithOptions(w.PublicKey(), data, sig, SignOptions{Variant: VariantEd25519ph, Context: "ctx"}) {
+		t.Error("buffered verification failed")
+	}
+	ok, err := VerifyStream(w.PublicKey(), bytes.NewReader(data), sig, "ctx")
+	if err != nil || !ok {
+		t.Errorf("VerifyStream failed: %v", err)
+	}
+}
+
+func TestAddressWith(t *testing.T) {
+	w, _ := FromSeed(make([]byte, 32), "Test")
+
+	mh, err := w.AddressWith(multihash.SHA2_256)
+	if err != nil {
+		t.Fatalf("AddressWith failed: %v", err)
+	}
+	if mh != "1220"+w.Address() {
+		t.Errorf("sha2-256 multihash address should extend legacy address, got %s", mh)
+	}
+	sha3, err := w.AddressWith(multihash.SHA3_256)
+	if err != nil {
+		t.Fatalf("AddressWith failed: %v", err)
+	}
+
+	other, _ := Generate("Other")
+	for _, addr := range []string{w.Address(), mh, sha3} {
+		if !MatchesAddress(w.PublicKey(), addr) {
+			t.Errorf("address %s does not match its key", addr)
+		}
+		if MatchesAddress(other.PublicKey(), addr) {
+			t.Errorf("address %s matches another key", addr)
+		}
+	}
+	if MatchesAddress(w.PublicKey(), "not-an-address") {
+		t.Error("garbage address matched")
+	}
+}
+
+func TestGenerateWithEntropy(t *testing.T) {
+	a, err := Generate("a", WithEntropy(cryptotest.NewReader([]byte("seed"))))
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	b, _ := Generate("b", WithEntropy(cryptotest.NewReader([]byte("seed"))))
+	if a.DID() != b.DID() {
+		t.Error("same entropy produced different keys")
+	}
+	c, _ := Generate("c")
+	if a.DID() == c.DID() {
+		t.Error("default entropy reproduced a seeded key")
+	}
+}